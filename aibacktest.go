@@ -0,0 +1,469 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// ================================
+// BACKTEST SETUP AI (backtest --ai)
+// ================================
+
+// AIBacktestReport is what backtest --ai --out writes.
+type AIBacktestReport struct {
+	Symbol     string        `json:"symbol"`
+	Timeframe  string        `json:"timeframe"`
+	From       time.Time     `json:"from"`
+	To         time.Time     `json:"to"`
+	PromptID   string        `json:"prompt_id"`
+	Every      int           `json:"every"`
+	Expire     int           `json:"expire"`
+	Horizon    int           `json:"horizon"`
+	Scorecards []AIScorecard `json:"scorecards"`
+}
+
+// AIScorecard grades one model over every point it was asked about. Hit
+// rates are over filled setups; Brier is the multi-class score of the
+// scenario probabilities, 0 is perfect and a flat 1/3 each scores 0.67.
+type AIScorecard struct {
+	AI          string           `json:"ai"`
+	Asked       int              `json:"asked"`
+	Errors      int              `json:"errors"`
+	Setups      int              `json:"setups"`
+	Filled      int              `json:"filled"`
+	Unfilled    map[string]int   `json:"unfilled"` // expired, missed, invalidated
+	TP1Rate     float64          `json:"tp1_rate_pct"`
+	TP2Rate     float64          `json:"tp2_rate_pct"`
+	TP3Rate     float64          `json:"tp3_rate_pct"`
+	SLRate      float64          `json:"sl_rate_pct"`
+	WinRate     float64          `json:"win_rate_pct"`
+	AvgR        float64          `json:"avg_r"`
+	StatedRR    float64          `json:"stated_rr"`
+	TotalR      float64          `json:"total_r"`
+	Scored      int              `json:"scenarios_scored"`
+	Brier       float64          `json:"brier"`
+	Calibration []CalibrationBin `json:"calibration"`
+	Usage       LLMUsage         `json:"usage"`
+	Calls       []AICall         `json:"calls"`
+}
+
+// AICall is one question to one model and what the market did after it.
+type AICall struct {
+	Time      time.Time      `json:"time"`
+	Price     float64        `json:"price"`
+	Error     string         `json:"error,omitempty"`
+	Setup     *TradeSetup    `json:"setup,omitempty"`
+	Scenarios []PlanScenario `json:"scenarios,omitempty"`
+	Outcome   string         `json:"outcome,omitempty"`  // hasil setup: tp1..3, sl, breakeven, horizon, expired, missed, invalidated
+	Scenario  string         `json:"scenario,omitempty"` // skenario yang terjadi: bullish, neutral, bearish
+	Trade     *BacktestTrade `json:"trade,omitempty"`
+}
+
+// CalibrationBin compares the stated probability with how often scenarios
+// in that probability range actually played out.
+type CalibrationBin struct {
+	From     float64 `json:"from_pct"`
+	To       float64 `json:"to_pct"`
+	Count    int     `json:"count"`
+	Stated   float64 `json:"stated_pct"`
+	Observed float64 `json:"observed_pct"`
+}
+
+const calibrationBins = 5
+
+// runAIBacktest walks candles every opts.Every bars. Each model gets the
+// prompt built from the last opts.Lookback candles up to that bar only;
+// its setup and scenarios are then scored on the next opts.Horizon bars.
+// Answers go through the usual AI cache, so a second run with the same
+// data, or one with --ai-replay, costs nothing.
+func runAIBacktest(candles []Candle, opts backtestOptions) error {
+	iv := mustInterval(opts.TF)
+	var points []int
+	for i := opts.Lookback - 1; i+opts.Horizon < len(candles); i += opts.Every {
+		points = append(points, i)
+	}
+	if len(points) == 0 {
+		return fmt.Errorf("%d candle tidak cukup untuk --lookback %d + --horizon %d, tambah --candles atau --from", len(candles), opts.Lookback, opts.Horizon)
+	}
+	models := opts.models()
+	fmt.Printf("🧮 %d titik x %d model = %d pertanyaan AI (cache/fixture dipakai kalau ada)\n\n", len(points), len(models), len(points)*len(models))
+
+	report := AIBacktestReport{
+		Symbol: opts.Symbol, Timeframe: opts.TF, PromptID: opts.prompt.ID(),
+		From: candles[points[0]].Time, To: candles[points[len(points)-1]].Time,
+		Every: opts.Every, Expire: opts.Expire, Horizon: opts.Horizon,
+	}
+	cards := make([]*AIScorecard, len(models))
+	for i, name := range models {
+		cards[i] = &AIScorecard{AI: name, Unfilled: map[string]int{}}
+	}
+
+	// Ctrl-C berhenti di titik berikutnya, scorecard tetap dicetak
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for n, i := range points {
+		window := candles[i+1-opts.Lookback : i+1]
+		future := candles[i+1 : i+1+opts.Horizon]
+		series := buildSeries(window, iv)
+		levels := detectSupportResistance(window, opts.detectors.SR)
+		patterns := detectPatterns(window, opts.detectors.Patterns) // tanpa kalibrasi: dihitung dari history yang sama
+		price := window[len(window)-1].Close.InexactFloat64()
+
+		members := make([]EnsembleMember, len(models))
+		var wg sync.WaitGroup
+		for k, name := range models {
+			wg.Add(1)
+			go func(k int, name string) {
+				defer wg.Done()
+				o := opts.cliOptions
+				o.AI, o.LLM.Name, o.Models = name, name, nil
+				members[k] = newEnsembleMember(name, callSelectedAI(ctx, o, window, series, opts.Symbol, opts.TF, levels, patterns, nil))
+			}(k, name)
+		}
+		wg.Wait()
+		if ctx.Err() != nil {
+			fmt.Println("\n⛔ Dibatalkan, scorecard dari titik yang sudah selesai:")
+			break
+		}
+
+		var line []string
+		for k, m := range members {
+			call := opts.scoreCall(m, window[len(window)-1].Time, price, future)
+			cards[k].add(call, m.Usage)
+			line = append(line, m.AI+": "+describeCall(call))
+		}
+		fmt.Printf("⏪ [%d/%d] %s %.4f | %s\n", n+1, len(points), window[len(window)-1].Time.Format("2006-01-02 15:04"), price, strings.Join(line, " | "))
+	}
+
+	for _, c := range cards {
+		c.finish()
+		report.Scorecards = append(report.Scorecards, *c)
+	}
+	printAIScorecards(report)
+
+	if opts.Out != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode backtest AI: %w", err)
+		}
+		if err := os.WriteFile(opts.Out, data, 0o644); err != nil {
+			return fmt.Errorf("tulis backtest AI %s: %w", opts.Out, err)
+		}
+		fmt.Printf("✅ Report disimpan → %s\n", opts.Out)
+	}
+	return nil
+}
+
+// scoreCall simulates the member's setup on future and works out which
+// scenario played out.
+func (o backtestOptions) scoreCall(m EnsembleMember, at time.Time, price float64, future []Candle) AICall {
+	call := AICall{Time: at, Price: price, Error: m.Error}
+	if m.Plan == nil {
+		return call
+	}
+	setup := m.Plan.Setup
+	call.Setup, call.Scenarios = &setup, m.Plan.Scenarios
+	call.Scenario = scenarioOutcome(m.Plan.Scenarios, price, future)
+	if setup.Direction == "long" || setup.Direction == "short" {
+		call.Trade, call.Outcome = o.simulateSetup(setup, price, future)
+	}
+	return call
+}
+
+// simulateSetup places the entry at the middle of the zone and walks
+// future bar by bar (see fillSetup and fillBar). The order is cancelled when it has
+// not filled after o.Expire bars. Position size is one unit of planned
+// risk, so trade PnL is in R.
+func (o backtestOptions) simulateSetup(s TradeSetup, price float64, future []Candle) (*BacktestTrade, string) {
+	sign := 1.0
+	if s.Direction == "short" {
+		sign = -1
+	}
+	risk := sign * (s.EntryMid() - s.SL)
+	if risk <= 0 {
+		return nil, "invalidated"
+	}
+	breakout := isBreakout(s, price)
+
+	for j, c := range future {
+		if j >= o.Expire {
+			return nil, "expired"
+		}
+		entry, cancel := o.fillSetup(s, breakout, c)
+		if cancel != "" {
+			return nil, cancel
+		}
+		if entry == 0 {
+			continue
+		}
+
+		targets := s.Targets()
+		t := &BacktestTrade{Side: s.Direction, EntryTime: c.Time, Entry: entry, SL: s.SL, Targets: targets, Qty: 1 / risk, risk: 1}
+		pos := o.fill(t, evenSizes(len(targets)))
+		o.fillBar(pos, c)
+		for _, bar := range future[j+1:] {
+			if pos.qty <= 0 {
+				break
+			}
+			o.manage(pos, bar)
+		}
+		if pos.qty <= 0 {
+			return t, t.Reason
+		}
+		last := future[len(future)-1]
+		o.exit(pos, last.Time, last.Close.InexactFloat64()*(1-sign*o.Slippage/100), pos.qty, "horizon")
+		return t, t.Reason
+	}
+	return nil, "expired"
+}
+
+// isBreakout reports whether the entry of s lies beyond price in the trade
+// direction, so it needs a stop order instead of a limit.
+func isBreakout(s TradeSetup, price float64) bool {
+	if s.Direction == "short" {
+		return s.EntryMid() < price
+	}
+	return s.EntryMid() > price
+}
+
+// fillSetup checks one bar against the entry order of s: a limit at the
+// middle of the zone, or a stop when breakout. It returns the fill price,
+// 0 while the order keeps waiting, or why it is cancelled: for limits,
+// price opening beyond the SL first (invalidated) or TP1 trading before
+// the entry (missed). A bar that reaches both TP1 and the limit counts as
+// missed unless it opened through the limit, the order of the two inside
+// the bar is unknown.
+func (o backtestOptions) fillSetup(s TradeSetup, breakout bool, c Candle) (float64, string) {
+	sign := 1.0
+	if s.Direction == "short" {
+		sign = -1
+	}
+	mid := s.EntryMid()
+	open, high, low := c.Open.InexactFloat64(), c.High.InexactFloat64(), c.Low.InexactFloat64()
+	adverse, favorable := low, high
+	if sign < 0 {
+		adverse, favorable = high, low
+	}
+	if !breakout && sign*(open-s.SL) <= 0 {
+		return 0, "invalidated"
+	}
+
+	switch {
+	case breakout && sign*(favorable-mid) >= 0:
+		entry := mid
+		if sign*(open-mid) > 0 {
+			entry = open
+		}
+		return entry * (1 + sign*o.Slippage/100), ""
+	case !breakout && sign*(open-mid) <= 0:
+		return open, "" // buka di balik limit, terisi duluan
+	case !breakout && sign*(favorable-s.TP1) >= 0:
+		return 0, "missed"
+	case !breakout && sign*(adverse-mid) <= 0:
+		return mid, ""
+	}
+	return 0, ""
+}
+
+// scenarioOutcome is "bullish" when price reaches the bullish target
+// before the bearish one within future, "bearish" the other way round and
+// "neutral" when neither is reached. Scenarios are grouped by name; an
+// answer without usable targets, or a bar that hits both, is not scored.
+func scenarioOutcome(scenarios []PlanScenario, price float64, future []Candle) string {
+	var up, down float64
+	for _, sc := range scenarios {
+		switch scenarioClass(sc.Name) {
+		case "bullish":
+			up = sc.Target
+		case "bearish":
+			down = sc.Target
+		}
+	}
+	if up <= price || down <= 0 || down >= price {
+		return ""
+	}
+	for _, c := range future {
+		hitUp, hitDown := c.High.InexactFloat64() >= up, c.Low.InexactFloat64() <= down
+		switch {
+		case hitUp && hitDown:
+			return ""
+		case hitUp:
+			return "bullish"
+		case hitDown:
+			return "bearish"
+		}
+	}
+	return "neutral"
+}
+
+func scenarioClass(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "bull"):
+		return "bullish"
+	case strings.Contains(name, "bear"):
+		return "bearish"
+	}
+	return "neutral"
+}
+
+// scenarioProbabilities sums the stated probabilities per class, 0-1.
+func scenarioProbabilities(scenarios []PlanScenario) map[string]float64 {
+	p := map[string]float64{"bullish": 0, "neutral": 0, "bearish": 0}
+	for _, sc := range scenarios {
+		p[scenarioClass(sc.Name)] += sc.Probability / 100
+	}
+	return p
+}
+
+func describeCall(c AICall) string {
+	switch {
+	case c.Error != "":
+		return "error"
+	case c.Setup == nil || c.Setup.Direction == "none":
+		return "none"
+	case c.Trade == nil:
+		return c.Setup.Direction + " " + c.Outcome
+	}
+	return fmt.Sprintf("%s → %s (%+.2fR)", c.Setup.Direction, c.Outcome, c.Trade.R)
+}
+
+// binState accumulates one calibration bin.
+type binState struct {
+	n                int
+	stated, observed float64
+}
+
+func (c *AIScorecard) add(call AICall, usage LLMUsage) {
+	c.Asked++
+	c.Usage.Add(usage)
+	c.Calls = append(c.Calls, call)
+	if call.Error != "" {
+		c.Errors++
+	}
+}
+
+// finish derives the rates, R and calibration from c.Calls.
+func (c *AIScorecard) finish() {
+	var tp [3]int
+	var sl, wins int
+	var statedRR float64
+	bins := make([]binState, calibrationBins)
+	var brier float64
+	for _, call := range c.Calls {
+		if call.Setup != nil && (call.Setup.Direction == "long" || call.Setup.Direction == "short") {
+			c.Setups++
+			statedRR += call.Setup.RR
+			if call.Trade == nil {
+				c.Unfilled[call.Outcome]++
+			} else {
+				c.Filled++
+				c.TotalR += call.Trade.R
+				if call.Trade.R > 0 {
+					wins++
+				}
+				for _, f := range call.Trade.Exits {
+					switch f.Reason {
+					case "tp1", "tp2", "tp3":
+						tp[f.Reason[2]-'1']++
+					case "sl":
+						sl++
+					}
+				}
+			}
+		}
+
+		if call.Scenario == "" {
+			continue
+		}
+		c.Scored++
+		for class, p := range scenarioProbabilities(call.Scenarios) {
+			hit := 0.0
+			if class == call.Scenario {
+				hit = 1
+			}
+			brier += (p - hit) * (p - hit)
+			b := min(int(p*calibrationBins), calibrationBins-1)
+			bins[max(b, 0)].n++
+			bins[max(b, 0)].stated += p
+			bins[max(b, 0)].observed += hit
+		}
+	}
+
+	if c.Setups > 0 {
+		c.StatedRR = statedRR / float64(c.Setups)
+	}
+	if c.Filled > 0 {
+		n := float64(c.Filled)
+		c.TP1Rate, c.TP2Rate, c.TP3Rate = float64(tp[0])/n*100, float64(tp[1])/n*100, float64(tp[2])/n*100
+		c.SLRate = float64(sl) / n * 100
+		c.WinRate = float64(wins) / n * 100
+		c.AvgR = c.TotalR / n
+	}
+	if c.Scored > 0 {
+		c.Brier = brier / float64(c.Scored)
+	}
+	for i, b := range bins {
+		bin := CalibrationBin{From: float64(i) * 100 / calibrationBins, To: float64(i+1) * 100 / calibrationBins, Count: b.n}
+		if b.n > 0 {
+			bin.Stated = b.stated / float64(b.n) * 100
+			bin.Observed = b.observed / float64(b.n) * 100
+		}
+		c.Calibration = append(c.Calibration, bin)
+	}
+}
+
+func printAIScorecards(r AIBacktestReport) {
+	fmt.Printf("\n📊 SCORECARD AI %s %s (%s → %s, tiap %d candle, horizon %d, prompt %s)\n\n",
+		r.Symbol, r.Timeframe, r.From.Format("2006-01-02"), r.To.Format("2006-01-02"), r.Every, r.Horizon, r.PromptID)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "AI\tTANYA\tERROR\tSETUP\tTERISI\tTP1%\tTP2%\tTP3%\tSL%\tWIN%\tAVG R\tRR JANJI\tTOTAL R\tBRIER\t")
+	for _, c := range r.Scorecards {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%.0f\t%.0f\t%.0f\t%.0f\t%.0f\t%+.2f\t%.2f\t%+.2f\t%.3f\t\n",
+			c.AI, c.Asked, c.Errors, c.Setups, c.Filled, c.TP1Rate, c.TP2Rate, c.TP3Rate, c.SLRate, c.WinRate, c.AvgR, c.StatedRR, c.TotalR, c.Brier)
+	}
+	tw.Flush()
+
+	for _, c := range r.Scorecards {
+		if len(c.Unfilled) > 0 {
+			var parts []string
+			for _, k := range []string{"expired", "missed", "invalidated"} {
+				if c.Unfilled[k] > 0 {
+					parts = append(parts, fmt.Sprintf("%s %d", k, c.Unfilled[k]))
+				}
+			}
+			fmt.Printf("\n%s: setup tidak terisi → %s", c.AI, strings.Join(parts, ", "))
+		}
+	}
+	fmt.Println()
+
+	fmt.Println("\n🎯 KALIBRASI PROBABILITAS SKENARIO (dinyatakan vs kejadian):")
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := "AI\tDINILAI\t"
+	for _, b := range r.Scorecards[0].Calibration {
+		header += fmt.Sprintf("%.0f-%.0f%%\t", b.From, b.To)
+	}
+	fmt.Fprintln(tw, header)
+	for _, c := range r.Scorecards {
+		row := fmt.Sprintf("%s\t%d\t", c.AI, c.Scored)
+		for _, b := range c.Calibration {
+			if b.Count == 0 {
+				row += "-\t"
+				continue
+			}
+			row += fmt.Sprintf("%.0f→%.0f (%d)\t", b.Stated, b.Observed, b.Count)
+		}
+		fmt.Fprintln(tw, row)
+	}
+	tw.Flush()
+	fmt.Println("\nBrier 0 = sempurna, 0.667 = asal tebak 1/3 tiap skenario. Kolom kalibrasi: rata-rata dinyatakan → terjadi (jumlah).")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var barStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// bars turns {open, high, low, close} rows into hourly candles.
+func bars(rows ...[4]float64) []Candle {
+	d := decimal.NewFromFloat
+	candles := make([]Candle, len(rows))
+	for i, r := range rows {
+		open := barStart.Add(time.Duration(i) * time.Hour)
+		candles[i] = Candle{Time: open, CloseTime: open.Add(time.Hour - time.Millisecond), Open: d(r[0]), High: d(r[1]), Low: d(r[2]), Close: d(r[3]), Volume: d(1)}
+	}
+	return candles
+}
+
+func TestSimulateSetup(t *testing.T) {
+	long := TradeSetup{Direction: "long", EntryLow: 99, EntryHigh: 101, SL: 98, TP1: 102, TP2: 104}
+	short := TradeSetup{Direction: "short", EntryLow: 99, EntryHigh: 101, SL: 102, TP1: 98, TP2: 96}
+	o := backtestOptions{Expire: 3}
+
+	tests := []struct {
+		name    string
+		setup   TradeSetup
+		price   float64
+		future  []Candle
+		outcome string
+		r       float64
+		exitBar int // bar exit terakhir
+	}{
+		{
+			name: "limit terisi lalu tp1 dan tp2", setup: long, price: 102,
+			future:  bars([4]float64{101.5, 101.8, 99.8, 100.5}, [4]float64{100.5, 102.5, 100.2, 102}, [4]float64{102, 104.2, 101.5, 104}),
+			outcome: "tp2", r: 1.5, exitBar: 2,
+		},
+		{
+			name: "tp1 dan limit di bar yang sama = missed", setup: long, price: 102,
+			future:  bars([4]float64{101.5, 102.3, 99.5, 100}, [4]float64{100, 104, 99.5, 104}),
+			outcome: "missed",
+		},
+		{
+			name: "buka di balik limit: terisi di open, tp di bar itu tidak diambil", setup: long, price: 102,
+			future:  bars([4]float64{99.5, 102.5, 99.2, 101.5}, [4]float64{101.5, 101.9, 101, 101.5}, [4]float64{101.5, 101.9, 97.5, 98}),
+			outcome: "sl", r: -0.75, exitBar: 2,
+		},
+		{
+			name: "sl di bar fill", setup: long, price: 102,
+			future:  bars([4]float64{101, 101.2, 97.5, 98}),
+			outcome: "sl", r: -1, exitBar: 0,
+		},
+		{
+			name: "breakout stop terisi lalu sl di bar yang sama", setup: long, price: 95,
+			future:  bars([4]float64{95, 100.5, 94, 97}),
+			outcome: "sl", r: -1, exitBar: 0,
+		},
+		{
+			name: "short limit", setup: short, price: 98,
+			future:  bars([4]float64{98.5, 100.2, 98.2, 99.5}, [4]float64{99.5, 99.8, 97.9, 98}, [4]float64{98, 98.5, 95.5, 96}),
+			outcome: "tp2", r: 1.5, exitBar: 2,
+		},
+		{
+			name: "buka di balik sl = invalidated", setup: long, price: 102,
+			future:  bars([4]float64{97, 98, 96, 97}),
+			outcome: "invalidated",
+		},
+		{
+			name: "tidak pernah terisi = expired", setup: long, price: 101.8,
+			future:  bars([4]float64{101.8, 101.9, 101.2, 101.5}, [4]float64{101.5, 101.9, 101.1, 101.5}, [4]float64{101.5, 101.9, 101.3, 101.5}, [4]float64{101.5, 101.5, 99, 100}),
+			outcome: "expired",
+		},
+		{
+			name: "horizon habis", setup: long, price: 102,
+			future:  bars([4]float64{101, 101.5, 99.8, 100.5}, [4]float64{100.5, 101, 100, 101}),
+			outcome: "horizon", r: 0.5, exitBar: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trade, outcome := o.simulateSetup(tt.setup, tt.price, tt.future)
+			if outcome != tt.outcome {
+				t.Fatalf("outcome = %q, mau %q", outcome, tt.outcome)
+			}
+			if trade == nil {
+				return
+			}
+			if tt.r != 0 && math.Abs(trade.R-tt.r) > 1e-9 {
+				t.Errorf("R = %.4f, mau %.4f", trade.R, tt.r)
+			}
+			if want := tt.future[tt.exitBar].Time; !trade.ExitTime.Equal(want) {
+				t.Errorf("exit di %s, mau %s", trade.ExitTime.Format("15:04"), want.Format("15:04"))
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ================================
+// HISTORICAL BACKFILL
+// ================================
+
+// pagedProvider is implemented by REST providers that cap the number of
+// candles per request. Providers without it are queried in one call.
+type pagedProvider interface {
+	PageLimit() int
+	PageDelay() time.Duration
+}
+
+// backfillCandles pages through klines from start to end (zero end means
+// now). Each request asks for a window that fits exactly in one page, so it
+// works the same whether the venue returns the oldest or newest rows of a
+// range. Candles repeated on page boundaries are dropped.
+func backfillCandles(provider CandleProvider, symbol, interval string, start, end time.Time) ([]Candle, error) {
+	iv, err := ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("range kosong: %s sampai %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+
+	pager, ok := provider.(pagedProvider)
+	if !ok {
+		return provider.Candles(symbol, interval, start, end, 0)
+	}
+	page := pager.PageLimit()
+
+	var candles []Candle
+	seen := make(map[int64]bool)
+	for cursor := start; !cursor.After(end); {
+		// [cursor, cursor+page*interval) memuat tepat page open time, aligned atau tidak
+		pageEnd := cursor.Add(iv.Duration()*time.Duration(page) - time.Millisecond)
+		if pageEnd.After(end) {
+			pageEnd = end
+		}
+
+		batch, err := provider.Candles(symbol, interval, cursor, pageEnd, page)
+		if err != nil {
+			return candles, fmt.Errorf("backfill %s %s dari %s: %w", symbol, interval, cursor.Format("2006-01-02 15:04"), err)
+		}
+		for _, c := range batch {
+			key := c.Time.UnixMilli()
+			if seen[key] || c.Time.Before(start) || c.Time.After(end) {
+				continue
+			}
+			seen[key] = true
+			candles = append(candles, c)
+		}
+
+		cursor = pageEnd.Add(time.Millisecond)
+		if d := pager.PageDelay(); d > 0 && !cursor.After(end) {
+			time.Sleep(d)
+		}
+	}
+
+	sortCandles(candles)
+	return candles, nil
+}
+
+// fetchLatest returns the newest n candles, paging when n is larger than
+// what the provider serves in a single request.
+func fetchLatest(provider CandleProvider, symbol, interval string, n int) ([]Candle, error) {
+	pager, ok := provider.(pagedProvider)
+	if !ok || n <= pager.PageLimit() {
+		return provider.Candles(symbol, interval, time.Time{}, time.Time{}, n)
+	}
+
+	iv, err := ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	candles, err := backfillCandles(provider, symbol, interval, now.Add(-iv.Duration()*time.Duration(n)), now)
+	if err != nil {
+		return nil, err
+	}
+	if len(candles) > n {
+		candles = candles[len(candles)-n:]
+	}
+	return candles, nil
+}
+
+// loadWindow returns the last n candles, or the n candles ending at to when
+// it is set. A candle that opens before to but closes after it was not
+// finished at that point and is left out, on every timeframe.
+func loadWindow(provider CandleProvider, symbol, interval string, n int, to time.Time) ([]Candle, error) {
+	if to.IsZero() {
+		return fetchLatest(provider, symbol, interval, n)
+	}
+	iv, err := ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	candles, err := backfillCandles(provider, symbol, interval, to.Add(-iv.Duration()*time.Duration(n)), to)
+	return closedOnly(candles, to), err
+}
+
+// parseDateFlag parses --from/--to values: "2006-01-02", "2006-01-02 15:04"
+// or RFC3339. Dates without a zone are UTC.
+func parseDateFlag(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("tanggal %q tidak valid, pakai format 2006-01-02 atau RFC3339", s)
+}
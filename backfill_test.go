@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// pageProvider serves pages of at most limit candles the way some venues
+// do: newest first and overlapping the previous page by one candle.
+type pageProvider struct {
+	candles []Candle
+	limit   int
+	calls   []timeRange
+	failAt  int // request ke-n gagal, 0 = tidak pernah
+}
+
+func (p *pageProvider) Name() string             { return "pages" }
+func (p *pageProvider) PageLimit() int           { return p.limit }
+func (p *pageProvider) PageDelay() time.Duration { return 0 }
+
+func (p *pageProvider) Candles(symbol, interval string, start, end time.Time, limit int) ([]Candle, error) {
+	p.calls = append(p.calls, timeRange{From: start, To: end})
+	if len(p.calls) == p.failAt {
+		return nil, &MarketError{Kind: ErrNetwork, Provider: p.Name()}
+	}
+	var page []Candle
+	for _, c := range p.candles {
+		// Satu candle sebelum start ikut terkirim
+		if !c.Time.Before(start.Add(-time.Hour)) && !c.Time.After(end) {
+			page = append([]Candle{c}, page...)
+		}
+	}
+	return page, nil
+}
+
+func TestBackfillPages(t *testing.T) {
+	all := waveCandles(23, barStart.Add(22*time.Hour))
+	start, end := all[0].Time, all[len(all)-1].Time
+
+	tests := []struct {
+		name  string
+		venue []Candle
+		end   time.Time
+		want  []Candle
+		calls int
+	}{
+		{name: "halaman penuh lalu halaman pendek", venue: all, end: end, want: all, calls: 5},
+		{name: "halaman kosong sebelum listing", venue: all[7:], end: end, want: all[7:], calls: 5},
+		{name: "halaman kosong setelah data terakhir", venue: all, end: end.Add(10 * time.Hour), want: all, calls: 7},
+		{name: "venue kosong", venue: nil, end: end, want: nil, calls: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &pageProvider{candles: tt.venue, limit: 5}
+			got, err := backfillCandles(p, "SOLUSDT", "1h", start, tt.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(p.calls) != tt.calls {
+				t.Errorf("request = %d, mau %d", len(p.calls), tt.calls)
+			}
+			// Tiap window pas satu halaman dan menyambung tanpa celah
+			for i, c := range p.calls {
+				if c.To.Sub(c.From) >= 5*time.Hour || i > 0 && !c.From.Equal(p.calls[i-1].To.Add(time.Millisecond)) {
+					t.Errorf("window %d: %s - %s", i, c.From.Format("15:04:05.000"), c.To.Format("15:04:05.000"))
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("dapat %d candle, mau %d (candle di batas halaman harus sekali saja)", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !got[i].Time.Equal(tt.want[i].Time) {
+					t.Fatalf("candle %d open %s, mau %s (urut dan tanpa duplikat)", i, got[i].Time.Format("15:04"), tt.want[i].Time.Format("15:04"))
+				}
+			}
+		})
+	}
+}
+
+func TestBackfillKeepsPagesBeforeError(t *testing.T) {
+	all := waveCandles(23, barStart.Add(22*time.Hour))
+	p := &pageProvider{candles: all, limit: 5, failAt: 3}
+	got, err := backfillCandles(p, "SOLUSDT", "1h", all[0].Time, all[len(all)-1].Time)
+	if !errors.Is(err, ErrNetwork) {
+		t.Fatalf("err %v, mau ErrNetwork", err)
+	}
+	if len(got) != 10 || len(p.calls) != 3 {
+		t.Errorf("dapat %d candle dari %d request, mau 10 dari 3", len(got), len(p.calls))
+	}
+}
+
+func TestBackfillRejectsEmptyRange(t *testing.T) {
+	p := &pageProvider{limit: 5}
+	if _, err := backfillCandles(p, "SOLUSDT", "1h", barStart, barStart); err == nil || len(p.calls) != 0 {
+		t.Errorf("range kosong: err %v setelah %d request", err, len(p.calls))
+	}
+}
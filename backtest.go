@@ -0,0 +1,812 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/components"
+	"github.com/go-echarts/go-echarts/v2/opts"
+)
+
+// ================================
+// BACKTEST
+// ================================
+
+const defaultBacktestLong = "macd-bull-cross+trend-up"
+
+// backtestOptions are the entry rules and the fill model. Entry rules use
+// the scan criteria, so anything scan can rank on can be backtested.
+type backtestOptions struct {
+	cliOptions
+	Long, Short []rankTerm
+	MinScore    float64 // 0 = semua kriteria harus match
+	Near        float64
+	Lookback    int // candle per window analisa, menjaga tiap bar tetap murah
+	SLATR       float64
+	SLLevel     bool      // SL di balik S/R terdekat kalau ada, bukan ATR
+	TPs         []float64 // kelipatan R
+	TPSizes     []float64 // porsi posisi per TP, total 1
+	Breakeven   bool
+	TrailATR    float64 // setelah TP1, SL mengikuti N ATR di belakang harga terbaik
+	MaxBars     int
+	Fee         float64 // persen notional per fill
+	Slippage    float64 // persen, selalu merugikan, hanya untuk fill market
+	Capital     float64
+	Risk        float64 // persen equity yang dipertaruhkan per trade
+	Equity      string
+	Start       time.Time // bar sebelum ini hanya jadi history sinyal (walk-forward)
+
+	// Mode --ai: setup dari LLM, bukan rule
+	Every   int // tanya AI tiap N candle
+	Expire  int // entry yang belum terisi setelah N candle dibatalkan
+	Horizon int // candle yang disimulasikan setelah tiap pertanyaan
+
+	detectors detectorConfig
+}
+
+// BacktestReport is what --out writes.
+type BacktestReport struct {
+	Symbol    string          `json:"symbol"`
+	Timeframe string          `json:"timeframe"`
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	Candles   int             `json:"candles"`
+	Rules     map[string]any  `json:"rules"`
+	Metrics   BacktestMetrics `json:"metrics"`
+	Trades    []BacktestTrade `json:"trades"`
+	Equity    []EquityPoint   `json:"equity_curve"`
+}
+
+type BacktestTrade struct {
+	Side      string         `json:"side"`
+	Signal    []string       `json:"signal"`
+	EntryTime time.Time      `json:"entry_time"`
+	ExitTime  time.Time      `json:"exit_time"`
+	Entry     float64        `json:"entry"`
+	SL        float64        `json:"sl"`
+	Targets   []float64      `json:"targets"`
+	Qty       float64        `json:"qty"`
+	Exits     []BacktestFill `json:"exits"`
+	Fees      float64        `json:"fees"`
+	PnL       float64        `json:"pnl"`
+	R         float64        `json:"r"`
+	Bars      int            `json:"bars"`
+	Reason    string         `json:"exit_reason"`
+
+	risk float64 // quote yang dipertaruhkan saat entry
+}
+
+type BacktestFill struct {
+	Time   time.Time `json:"time"`
+	Price  float64   `json:"price"`
+	Qty    float64   `json:"qty"`
+	Reason string    `json:"reason"`
+}
+
+// EquityPoint is the marked-to-market equity at a candle close.
+type EquityPoint struct {
+	Time     time.Time `json:"time"`
+	Equity   float64   `json:"equity"`
+	Drawdown float64   `json:"drawdown_pct"`
+}
+
+// BacktestMetrics summarizes the trades and the equity curve. ProfitFactor
+// is 0 when there is no losing trade to divide by.
+type BacktestMetrics struct {
+	Trades       int     `json:"trades"`
+	Wins         int     `json:"wins"`
+	WinRate      float64 `json:"win_rate_pct"`
+	Expectancy   float64 `json:"expectancy"`
+	ExpectancyR  float64 `json:"expectancy_r"`
+	ProfitFactor float64 `json:"profit_factor"`
+	MaxDrawdown  float64 `json:"max_drawdown_pct"`
+	Sharpe       float64 `json:"sharpe"`
+	Return       float64 `json:"return_pct"`
+	FinalEquity  float64 `json:"final_equity"`
+	Fees         float64 `json:"fees"`
+	AvgBars      float64 `json:"avg_bars"`
+	Exposure     float64 `json:"exposure_pct"`
+}
+
+// btSignal is an entry decided at a candle close, filled at the next open.
+type btSignal struct {
+	Side    string   `json:"side"`
+	Matched []string `json:"matched"`
+	ATR     float64  `json:"atr"`
+	Stop    float64  `json:"stop,omitempty"` // SL dari level, 0 = pakai ATR
+}
+
+type btPosition struct {
+	trade    *BacktestTrade
+	sign     float64
+	qty      float64 // sisa posisi
+	stop     float64
+	sizes    []float64 // porsi posisi per target
+	next     int       // target berikutnya
+	atr      float64   // ATR saat entry, jarak trailing stop
+	best     float64   // harga terbaik sejak entry
+	trailing bool      // stop sudah digeser trailing
+}
+
+func parseBacktestFlags(args []string) (backtestOptions, error) {
+	var opts backtestOptions
+	fs := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	fs.StringVar(&opts.Symbol, "symbol", "", "coin atau pair, contoh: SOL, BTC, ETHUSDT")
+	fs.StringVar(&opts.Out, "out", "", "tulis report JSON (metrics, trade, equity) ke file ini")
+	finish := dataFlags(fs, &opts.cliOptions)
+	finishRules := ruleFlags(fs, &opts, defaultBacktestLong)
+	fs.StringVar(&opts.Equity, "equity", "", "tulis equity curve CSV ke file ini")
+	fs.StringVar(&opts.AI, "ai", "", "backtest setup AI ("+strings.Join(llmNames(), " / ")+", pisah koma untuk banding model) alih-alih --long/--short")
+	aiFlags(fs, &opts.cliOptions)
+	fs.IntVar(&opts.Every, "every", 24, "mode --ai: tanya AI tiap N candle")
+	fs.IntVar(&opts.Expire, "expire", 6, "mode --ai: batalkan entry yang belum kena setelah N candle")
+	fs.IntVar(&opts.Horizon, "horizon", 48, "mode --ai: candle setelah tiap pertanyaan untuk simulasi trade dan skenario")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of backtest:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\n%s", criteriaHelp())
+	}
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("argumen tidak dikenal: %s", strings.Join(fs.Args(), " "))
+	}
+	if err := finish(); err != nil {
+		return opts, err
+	}
+	if strings.TrimSpace(opts.Symbol) == "" {
+		return opts, errors.New("--symbol wajib diisi")
+	}
+	opts.Symbol = normalizeSymbol(opts.Symbol)
+	if err := finishRules(); err != nil {
+		return opts, err
+	}
+
+	if opts.AI == "" {
+		var skipped bool
+		if opts.detectors, skipped = opts.params.ruleConfig(opts.Symbol, opts.TF, opts.Long, opts.Short); skipped {
+			fmt.Printf("⚙️  Parameter tersimpan %s %s di-tune untuk rule lain, pakai default\n", opts.Symbol, opts.TF)
+		}
+		return opts, nil
+	}
+	opts.detectors = opts.params.config(opts.Symbol, opts.TF)
+	switch {
+	case opts.Every <= 0 || opts.Expire <= 0 || opts.Horizon <= 0:
+		return opts, errors.New("--every, --expire dan --horizon harus lebih dari 0")
+	case opts.Expire > opts.Horizon:
+		return opts, errors.New("--expire tidak boleh lebih panjang dari --horizon")
+	}
+	if err := validateAI(&opts.cliOptions); err != nil {
+		return opts, err
+	}
+	if opts.Format != "json" {
+		return opts, errors.New("backtest AI butuh --format json")
+	}
+	opts.LLM.Stream = false // banyak pertanyaan, cukup progress per titik
+	return opts, checkAIKeys(opts.cliOptions)
+}
+
+// ruleFlags registers the entry rules, exits, costs and sizing shared by
+// backtest and optimize. The returned func validates them after Parse.
+func ruleFlags(fs *flag.FlagSet, opts *backtestOptions, defaultLong string) func() error {
+	long := fs.String("long", defaultLong, "kriteria entry long, format sama dengan scan --rank")
+	short := fs.String("short", "", "kriteria entry short (kosong = tanpa short)")
+	fs.Float64Var(&opts.MinScore, "min-score", 0, "score minimal untuk entry; 0 = semua kriteria harus match")
+	fs.Float64Var(&opts.Near, "near", 2, "jarak maksimal (%) ke level untuk near-support/near-resistance")
+	fs.IntVar(&opts.Lookback, "lookback", 300, "jumlah candle yang dianalisa di tiap bar")
+	fs.Float64Var(&opts.SLATR, "sl-atr", 1.5, "jarak stop loss dalam ATR")
+	fs.BoolVar(&opts.SLLevel, "sl-level", false, "taruh SL di balik support/resistance terdekat (fallback --sl-atr)")
+	tps := fs.String("tp", "1.5,3", "take profit dalam kelipatan R, pisah koma")
+	sizes := fs.String("tp-size", "", "porsi posisi (%) per TP, mis. 50,50 (default: rata)")
+	fs.BoolVar(&opts.Breakeven, "breakeven", false, "geser SL ke entry setelah TP pertama kena")
+	fs.Float64Var(&opts.TrailATR, "trail-atr", 0, "setelah TP pertama, SL mengikuti N ATR di belakang harga terbaik (0 = mati)")
+	fs.IntVar(&opts.MaxBars, "max-bars", 0, "tutup posisi setelah N candle, 0 = tanpa batas")
+	fs.Float64Var(&opts.Fee, "fee", 0.1, "fee (%) per fill")
+	fs.Float64Var(&opts.Slippage, "slippage", 0.05, "slippage (%) untuk entry, SL dan exit market")
+	fs.Float64Var(&opts.Capital, "capital", 10000, "modal awal")
+	fs.Float64Var(&opts.Risk, "risk", 1, "risiko (%) equity per trade")
+
+	return func() error {
+		var err error
+		if strings.TrimSpace(*long) != "" {
+			if opts.Long, err = parseRank(*long); err != nil {
+				return err
+			}
+		}
+		if strings.TrimSpace(*short) != "" {
+			if opts.Short, err = parseRank(*short); err != nil {
+				return err
+			}
+		}
+		if len(opts.Long) == 0 && len(opts.Short) == 0 {
+			return errors.New("isi --long dan/atau --short")
+		}
+		if opts.TPs, err = parseFloatList(*tps); err != nil || len(opts.TPs) == 0 {
+			return fmt.Errorf("--tp %q tidak valid", *tps)
+		}
+		for i, r := range opts.TPs {
+			if r <= 0 || (i > 0 && r <= opts.TPs[i-1]) {
+				return fmt.Errorf("--tp harus positif dan naik, dapat %q", *tps)
+			}
+		}
+		if opts.TPSizes, err = tpSizes(*sizes, len(opts.TPs)); err != nil {
+			return err
+		}
+		switch {
+		case opts.Lookback < 50:
+			return errors.New("--lookback minimal 50")
+		case opts.SLATR <= 0:
+			return errors.New("--sl-atr harus lebih dari 0")
+		case opts.TrailATR < 0:
+			return errors.New("--trail-atr tidak boleh negatif")
+		case opts.Near <= 0:
+			return errors.New("--near harus lebih dari 0")
+		case opts.Fee < 0 || opts.Slippage < 0:
+			return errors.New("--fee dan --slippage tidak boleh negatif")
+		case opts.Capital <= 0:
+			return errors.New("--capital harus lebih dari 0")
+		case opts.Risk <= 0 || opts.Risk > 100:
+			return errors.New("--risk harus di antara 0 dan 100")
+		}
+		return nil
+	}
+}
+
+func parseFloatList(s string) ([]float64, error) {
+	var out []float64
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// tpSizes turns --tp-size percentages into fractions; empty splits evenly.
+func tpSizes(s string, n int) ([]float64, error) {
+	if strings.TrimSpace(s) == "" {
+		return evenSizes(n), nil
+	}
+	sizes, err := parseFloatList(s)
+	if err != nil || len(sizes) != n {
+		return nil, fmt.Errorf("--tp-size %q harus %d angka, satu per TP", s, n)
+	}
+	total := 0.0
+	for _, v := range sizes {
+		if v <= 0 {
+			return nil, fmt.Errorf("--tp-size %q harus positif", s)
+		}
+		total += v
+	}
+	if math.Abs(total-100) > 0.01 {
+		return nil, fmt.Errorf("--tp-size %q harus berjumlah 100, dapat %.2f", s, total)
+	}
+	for i := range sizes {
+		sizes[i] /= 100
+	}
+	return sizes, nil
+}
+
+func evenSizes(n int) []float64 {
+	sizes := make([]float64, n)
+	for i := range sizes {
+		sizes[i] = 1 / float64(n)
+	}
+	return sizes
+}
+
+func runBacktest(args []string) error {
+	opts, err := parseBacktestFlags(args)
+	if err != nil {
+		return err
+	}
+	provider, err := openProvider(opts.cliOptions)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n🔥 Mengambil %s %s dari %s...\n\n", opts.Symbol, opts.TF, provider.Name())
+	var candles []Candle
+	if opts.From.IsZero() {
+		candles, err = fetchLatest(provider, opts.Symbol, opts.TF, opts.Candles)
+	} else {
+		candles, err = backfillCandles(provider, opts.Symbol, opts.TF, opts.From, opts.To)
+	}
+	if errors.Is(err, ErrInvalidSymbol) {
+		return fmt.Errorf("symbol %s tidak ada di %s: %w", opts.Symbol, provider.Name(), err)
+	}
+	if err != nil {
+		return err
+	}
+	// Candle yang masih jalan belum punya close final
+	candles = closedOnly(candles, time.Now())
+	if len(candles) < 60 {
+		return fmt.Errorf("cuma %d candle %s %s, backtest butuh minimal 60", len(candles), opts.Symbol, opts.TF)
+	}
+
+	if opts.AI != "" {
+		return runAIBacktest(candles, opts)
+	}
+	fmt.Printf("⏪ Replay %d candle (%s → %s)...\n", len(candles), candles[0].Time.Format("2006-01-02"), candles[len(candles)-1].Time.Format("2006-01-02 15:04"))
+	report := backtest(candles, opts, opts.signal)
+	printBacktest(report, opts)
+
+	path, err := writeEquityChart(report, fmt.Sprintf("%s_%s_backtest.html", report.Symbol, report.Timeframe))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Equity curve disimpan → %s\n", path)
+	if opts.Equity != "" {
+		if err := writeEquityCSV(opts.Equity, report.Equity); err != nil {
+			return fmt.Errorf("tulis equity %s: %w", opts.Equity, err)
+		}
+		fmt.Printf("✅ Equity CSV disimpan → %s\n", opts.Equity)
+	}
+	if opts.Out != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode backtest: %w", err)
+		}
+		if err := os.WriteFile(opts.Out, data, 0o644); err != nil {
+			return fmt.Errorf("tulis backtest %s: %w", opts.Out, err)
+		}
+		fmt.Printf("✅ Report disimpan → %s\n", opts.Out)
+	}
+	return nil
+}
+
+// signal evaluates the rules on window, the candles up to and including
+// the bar that just closed. Long wins when both sides qualify.
+func (o backtestOptions) signal(window []Candle) *btSignal {
+	r := &scanResult{Symbol: o.Symbol, candles: window, series: buildSeries(window, mustInterval(o.TF))}
+	r.evaluate(o.TF, o.detectors)
+	return o.decide(r)
+}
+
+// decide applies the entry rules to a window that is already evaluated.
+func (o backtestOptions) decide(r *scanResult) *btSignal {
+	scan := scanOptions{Near: o.Near}
+
+	for _, side := range []struct {
+		name  string
+		terms []rankTerm
+	}{{"long", o.Long}, {"short", o.Short}} {
+		if len(side.terms) == 0 {
+			continue
+		}
+		score, matched := r.score(side.terms, scan)
+		if (o.MinScore > 0 && score < o.MinScore) || (o.MinScore <= 0 && len(matched) < len(side.terms)) {
+			continue
+		}
+		sig := &btSignal{Side: side.name, Matched: matched, ATR: r.Summary.ATRValue}
+		if o.SLLevel {
+			buffer := 0.25 * sig.ATR
+			if side.name == "long" && r.Support != nil {
+				sig.Stop = r.Support.Price - buffer
+			}
+			if side.name == "short" && r.Resistance != nil {
+				sig.Stop = r.Resistance.Price + buffer
+			}
+		}
+		if sig.ATR > 0 {
+			return sig
+		}
+	}
+	return nil
+}
+
+// backtest replays candles one bar at a time. At each close signal only
+// sees candles up to that bar; an entry fills at the next bar's open. When
+// one bar touches both the stop and a target the stop is assumed to come
+// first, so results err on the pessimistic side. Trading starts at
+// opts.Start when set; the candles before it only feed the first windows.
+func backtest(candles []Candle, opts backtestOptions, signal func(window []Candle) *btSignal) *BacktestReport {
+	// Indikator butuh ~30 candle sebelum sinyal pertama berarti
+	first := min(30, len(candles)-1)
+	for first < len(candles)-1 && candles[first].Time.Before(opts.Start) {
+		first++
+	}
+	report := &BacktestReport{
+		Symbol: opts.Symbol, Timeframe: opts.TF, Candles: len(candles),
+		From: candles[0].Time, To: candles[len(candles)-1].Time,
+		Rules: map[string]any{
+			"long": termNames(opts.Long), "short": termNames(opts.Short), "min_score": opts.MinScore,
+			"sl_atr": opts.SLATR, "sl_level": opts.SLLevel, "tp_r": opts.TPs, "tp_size": opts.TPSizes,
+			"breakeven": opts.Breakeven, "trail_atr": opts.TrailATR, "max_bars": opts.MaxBars, "fee_pct": opts.Fee, "slippage_pct": opts.Slippage,
+			"capital": opts.Capital, "risk_pct": opts.Risk, "detectors": opts.detectors,
+		},
+	}
+
+	equity, peak := opts.Capital, opts.Capital
+	var pos *btPosition
+	var pending *btSignal
+	inMarket := 0
+	for i := first; i < len(candles); i++ {
+		c := candles[i]
+		if pending != nil {
+			pos = opts.open(pending, c, equity)
+			if pos != nil {
+				equity += pos.trade.PnL // fee entry
+			}
+			pending = nil
+		}
+
+		if pos != nil {
+			inMarket++
+			equity += opts.manage(pos, c)
+			if pos.qty <= 0 {
+				report.Trades = append(report.Trades, *pos.trade)
+				pos = nil
+			}
+		}
+		if pos != nil && i == len(candles)-1 {
+			equity += opts.exit(pos, c.Time, c.Close.InexactFloat64(), pos.qty, "end")
+			report.Trades = append(report.Trades, *pos.trade)
+			pos = nil
+		}
+
+		mark := equity
+		if pos != nil {
+			mark += pos.sign * (c.Close.InexactFloat64() - pos.trade.Entry) * pos.qty
+		}
+		peak = math.Max(peak, mark)
+		report.Equity = append(report.Equity, EquityPoint{Time: c.Time, Equity: mark, Drawdown: (peak - mark) / peak * 100})
+
+		if pos == nil && i < len(candles)-1 {
+			pending = signal(candles[max(0, i+1-opts.Lookback) : i+1])
+		}
+	}
+
+	report.Metrics = backtestMetrics(report.Trades, report.Equity, opts.Capital, inMarket, mustInterval(opts.TF))
+	return report
+}
+
+// open fills sig at c's open and sizes the position so hitting the stop
+// loses opts.Risk percent of equity, without leverage.
+func (o backtestOptions) open(sig *btSignal, c Candle, equity float64) *btPosition {
+	sign := 1.0
+	if sig.Side == "short" {
+		sign = -1
+	}
+	entry := c.Open.InexactFloat64() * (1 + sign*o.Slippage/100)
+	stop := entry - sign*o.SLATR*sig.ATR
+	if sig.Stop > 0 && sign*(entry-sig.Stop) > 0 {
+		stop = sig.Stop
+	}
+	dist := sign * (entry - stop)
+	qty := o.size(entry, dist, equity)
+	if qty == 0 {
+		return nil
+	}
+
+	t := &BacktestTrade{Side: sig.Side, Signal: sig.Matched, EntryTime: c.Time, Entry: entry, SL: stop, Qty: qty, risk: qty * dist}
+	for _, r := range o.TPs {
+		t.Targets = append(t.Targets, entry+sign*r*dist)
+	}
+	pos := o.fill(t, o.TPSizes)
+	pos.atr = sig.ATR
+	return pos
+}
+
+// size is the quantity that loses o.Risk percent of equity over dist, but
+// never more than equity buys. Zero means the trade cannot be sized.
+func (o backtestOptions) size(entry, dist, equity float64) float64 {
+	qty := math.Min(equity*o.Risk/100/dist, equity/entry)
+	if qty <= 0 || math.IsNaN(qty) || math.IsInf(qty, 0) {
+		return 0
+	}
+	return qty
+}
+
+// fill opens the position for t and books the entry fee on it.
+func (o backtestOptions) fill(t *BacktestTrade, sizes []float64) *btPosition {
+	sign := 1.0
+	if t.Side == "short" {
+		sign = -1
+	}
+	fee := t.Entry * t.Qty * o.Fee / 100
+	t.Fees, t.PnL = fee, -fee
+	return &btPosition{trade: t, sign: sign, qty: t.Qty, stop: t.SL, sizes: sizes, best: t.Entry}
+}
+
+// manage walks one bar for an open position and returns the realized PnL.
+// A trailing stop moves at the end of the bar, so it only counts from the
+// next one.
+func (o backtestOptions) manage(pos *btPosition, c Candle) float64 {
+	t := pos.trade
+	t.Bars++
+	open, high, low, close := c.Open.InexactFloat64(), c.High.InexactFloat64(), c.Low.InexactFloat64(), c.Close.InexactFloat64()
+	adverse, favorable := low, high
+	if pos.sign < 0 {
+		adverse, favorable = high, low
+	}
+	slip := 1 - pos.sign*o.Slippage/100
+
+	reason := "sl"
+	switch {
+	case pos.trailing:
+		reason = "trail"
+	case pos.stop == t.Entry:
+		reason = "breakeven"
+	}
+	if pos.sign*(open-pos.stop) <= 0 {
+		return o.exit(pos, c.Time, open*slip, pos.qty, reason) // gap melewati SL
+	}
+	if pos.sign*(adverse-pos.stop) <= 0 {
+		return o.exit(pos, c.Time, pos.stop*slip, pos.qty, reason)
+	}
+
+	pnl := 0.0
+	for pos.next < len(t.Targets) && pos.sign*(favorable-t.Targets[pos.next]) >= 0 {
+		price := t.Targets[pos.next]
+		if pos.sign*(open-price) > 0 {
+			price = open // gap melewati TP, limit terisi di open
+		}
+		qty := t.Qty * pos.sizes[pos.next]
+		if pos.next == len(t.Targets)-1 {
+			qty = pos.qty
+		}
+		pos.next++
+		pnl += o.exit(pos, c.Time, price, math.Min(qty, pos.qty), fmt.Sprintf("tp%d", pos.next))
+		if o.Breakeven && pos.next == 1 {
+			pos.stop = t.Entry
+		}
+	}
+	if pos.sign*(favorable-pos.best) > 0 {
+		pos.best = favorable
+	}
+	if o.TrailATR > 0 && pos.atr > 0 && pos.next > 0 && pos.qty > 0 {
+		if stop := pos.best - pos.sign*o.TrailATR*pos.atr; pos.sign*(stop-pos.stop) > 0 {
+			pos.stop, pos.trailing = stop, true
+		}
+	}
+	if pos.qty > 0 && o.MaxBars > 0 && t.Bars >= o.MaxBars {
+		pnl += o.exit(pos, c.Time, close*slip, pos.qty, "time")
+	}
+	return pnl
+}
+
+// fillBar walks the bar a resting order filled in. Where price went inside
+// the bar before and after the fill is unknown, so only the stop counts
+// (pessimistic); targets and the trailing stop start with the next bar.
+// Market fills at the open do not need this, manage covers the whole bar.
+func (o backtestOptions) fillBar(pos *btPosition, c Candle) float64 {
+	t := pos.trade
+	t.Bars++
+	adverse := c.Low.InexactFloat64()
+	if pos.sign < 0 {
+		adverse = c.High.InexactFloat64()
+	}
+	slip := 1 - pos.sign*o.Slippage/100
+	if pos.sign*(adverse-pos.stop) <= 0 {
+		return o.exit(pos, c.Time, pos.stop*slip, pos.qty, "sl")
+	}
+	if o.MaxBars > 0 && t.Bars >= o.MaxBars {
+		return o.exit(pos, c.Time, c.Close.InexactFloat64()*slip, pos.qty, "time")
+	}
+	return 0
+}
+
+// exit closes qty at price and returns the PnL net of the exit fee.
+func (o backtestOptions) exit(pos *btPosition, at time.Time, price, qty float64, reason string) float64 {
+	t := pos.trade
+	fee := price * qty * o.Fee / 100
+	pnl := pos.sign*(price-t.Entry)*qty - fee
+	pos.qty -= qty
+	if pos.qty < t.Qty*1e-9 {
+		pos.qty = 0
+	}
+	t.Exits = append(t.Exits, BacktestFill{Time: at, Price: price, Qty: qty, Reason: reason})
+	t.Fees += fee
+	t.PnL += pnl
+	t.ExitTime, t.Reason = at, reason
+	if t.risk > 0 {
+		t.R = t.PnL / t.risk
+	}
+	return pnl
+}
+
+// backtestMetrics computes the summary. Sharpe uses per-bar returns of the
+// marked equity, annualized for a market that trades around the clock.
+func backtestMetrics(trades []BacktestTrade, equity []EquityPoint, capital float64, inMarket int, iv Interval) BacktestMetrics {
+	m := BacktestMetrics{Trades: len(trades), FinalEquity: capital}
+	if len(equity) > 0 {
+		m.FinalEquity = equity[len(equity)-1].Equity
+		m.Exposure = float64(inMarket) / float64(len(equity)) * 100
+	}
+	m.Return = (m.FinalEquity - capital) / capital * 100
+
+	var grossWin, grossLoss, sumR, bars float64
+	for _, t := range trades {
+		if t.PnL > 0 {
+			m.Wins++
+			grossWin += t.PnL
+		} else {
+			grossLoss -= t.PnL
+		}
+		m.Fees += t.Fees
+		sumR += t.R
+		bars += float64(t.Bars)
+	}
+	if n := float64(len(trades)); n > 0 {
+		m.WinRate = float64(m.Wins) / n * 100
+		m.Expectancy = (grossWin - grossLoss) / n
+		m.ExpectancyR = sumR / n
+		m.AvgBars = bars / n
+	}
+	if grossLoss > 0 {
+		m.ProfitFactor = grossWin / grossLoss
+	}
+
+	var returns []float64
+	for i, p := range equity {
+		m.MaxDrawdown = math.Max(m.MaxDrawdown, p.Drawdown)
+		if i > 0 && equity[i-1].Equity > 0 {
+			returns = append(returns, p.Equity/equity[i-1].Equity-1)
+		}
+	}
+	if len(returns) > 1 && iv.Duration() > 0 {
+		mean := 0.0
+		for _, r := range returns {
+			mean += r
+		}
+		mean /= float64(len(returns))
+		variance := 0.0
+		for _, r := range returns {
+			variance += (r - mean) * (r - mean)
+		}
+		std := math.Sqrt(variance / float64(len(returns)-1))
+		if std > 0 {
+			perYear := float64(365*24*time.Hour) / float64(iv.Duration())
+			m.Sharpe = mean / std * math.Sqrt(perYear)
+		}
+	}
+	return m
+}
+
+func termNames(terms []rankTerm) []string {
+	names := make([]string, 0, len(terms))
+	for _, t := range terms {
+		if t.Weight != 1 {
+			names = append(names, fmt.Sprintf("%s:%g", t.Name, t.Weight))
+		} else {
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
+
+func joinFloats(vs []float64, sep string) string {
+	parts := make([]string, len(vs))
+	for i, v := range vs {
+		parts[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.Join(parts, sep)
+}
+
+// backtestTradeRows is how many of the latest trades the terminal shows;
+// --out has all of them.
+const backtestTradeRows = 15
+
+func printBacktest(r *BacktestReport, opts backtestOptions) {
+	m := r.Metrics
+	fmt.Printf("\n📊 BACKTEST %s %s (%s → %s, %d candle)\n", r.Symbol, r.Timeframe, r.From.Format("2006-01-02"), r.To.Format("2006-01-02"), r.Candles)
+	fmt.Printf("   Long: %s | Short: %s | SL %.1f ATR | TP %s R | fee %.2f%% | slippage %.2f%%\n\n",
+		orDefault(strings.Join(termNames(opts.Long), "+"), "-"), orDefault(strings.Join(termNames(opts.Short), "+"), "-"),
+		opts.SLATR, joinFloats(opts.TPs, "/"), opts.Fee, opts.Slippage)
+
+	pf := "-"
+	if m.ProfitFactor > 0 {
+		pf = fmt.Sprintf("%.2f", m.ProfitFactor)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Trades\t%d (%d menang)\n", m.Trades, m.Wins)
+	fmt.Fprintf(tw, "Win rate\t%.1f%%\n", m.WinRate)
+	fmt.Fprintf(tw, "Expectancy\t%.2f per trade (%+.2fR)\n", m.Expectancy, m.ExpectancyR)
+	fmt.Fprintf(tw, "Profit factor\t%s\n", pf)
+	fmt.Fprintf(tw, "Max drawdown\t%.2f%%\n", m.MaxDrawdown)
+	fmt.Fprintf(tw, "Sharpe\t%.2f\n", m.Sharpe)
+	fmt.Fprintf(tw, "Return\t%+.2f%% (%.2f → %.2f)\n", m.Return, opts.Capital, m.FinalEquity)
+	fmt.Fprintf(tw, "Fees\t%.2f\n", m.Fees)
+	fmt.Fprintf(tw, "Exposure\t%.1f%% candle, rata-rata %.1f candle per trade\n", m.Exposure, m.AvgBars)
+	tw.Flush()
+
+	if len(r.Trades) == 0 {
+		fmt.Println("\nTidak ada trade, coba longgarkan --long/--short atau --min-score")
+		return
+	}
+	trades := r.Trades[max(0, len(r.Trades)-backtestTradeRows):]
+	fmt.Printf("\n%d trade terakhir:\n", len(trades))
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENTRY\tSIDE\tPRICE\tSL\tEXIT\tBARS\tPNL\tR\tSINYAL")
+	for _, t := range trades {
+		fmt.Fprintf(tw, "%s\t%s\t%.4f\t%.4f\t%s\t%d\t%+.2f\t%+.2f\t%s\n",
+			t.EntryTime.Format("2006-01-02 15:04"), t.Side, t.Entry, t.SL, t.Reason, t.Bars, t.PnL, t.R, strings.Join(t.Signal, ","))
+	}
+	tw.Flush()
+	fmt.Println()
+}
+
+func writeEquityCSV(path string, points []EquityPoint) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{"time", "equity", "drawdown_pct"})
+	for _, p := range points {
+		w.Write([]string{p.Time.UTC().Format(time.RFC3339), strconv.FormatFloat(p.Equity, 'f', 2, 64), strconv.FormatFloat(p.Drawdown, 'f', 2, 64)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// writeEquityChart renders the equity curve and drawdown to filename,
+// SYMBOL_TF_backtest.html for backtest, next to the analysis charts.
+func writeEquityChart(r *BacktestReport, filename string) (string, error) {
+	axisLayout := "01-02 15:04"
+	if iv, err := ParseInterval(r.Timeframe); err == nil {
+		axisLayout = iv.AxisLayout()
+	}
+	var xAxis []string
+	var equityData, ddData []opts.LineData
+	for _, p := range r.Equity {
+		xAxis = append(xAxis, p.Time.Format(axisLayout))
+		equityData = append(equityData, opts.LineData{Value: math.Round(p.Equity*100) / 100})
+		ddData = append(ddData, opts.LineData{Value: -math.Round(p.Drawdown*100) / 100})
+	}
+
+	equity := charts.NewLine()
+	equity.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    fmt.Sprintf("Equity %s %s", r.Symbol, r.Timeframe),
+			Subtitle: fmt.Sprintf("%d trade | win rate %.1f%% | return %+.2f%% | max DD %.2f%% | Sharpe %.2f", r.Metrics.Trades, r.Metrics.WinRate, r.Metrics.Return, r.Metrics.MaxDrawdown, r.Metrics.Sharpe),
+		}),
+		charts.WithYAxisOpts(opts.YAxis{Scale: true}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: true, Trigger: "axis"}),
+		charts.WithDataZoomOpts(opts.DataZoom{Type: "slider", Start: 0, End: 100}),
+	)
+	equity.SetXAxis(xAxis).AddSeries("Equity", equityData,
+		charts.WithLineStyleOpts(opts.LineStyle{Color: "#30d158", Width: 2}),
+	)
+
+	drawdown := charts.NewLine()
+	drawdown.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Drawdown %"}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: true, Trigger: "axis"}),
+	)
+	drawdown.SetXAxis(xAxis).AddSeries("Drawdown", ddData,
+		charts.WithLineStyleOpts(opts.LineStyle{Color: "#ff453a", Width: 1}),
+		charts.WithAreaStyleOpts(opts.AreaStyle{Opacity: 0.3}),
+	)
+
+	page := components.NewPage()
+	page.AddCharts(equity, drawdown)
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return "", fmt.Errorf("buat chart %s: %w", filename, err)
+	}
+	defer f.Close()
+	if err := page.Render(f); err != nil {
+		return "", fmt.Errorf("render chart %s: %w", filename, err)
+	}
+	return filename, nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testPosition is a 5 unit position at 100 with the stop 2 away (risk 10)
+// and targets at 1R and 2R.
+func testPosition(o backtestOptions, side string, sizes []float64) *btPosition {
+	t := &BacktestTrade{Side: side, Entry: 100, SL: 98, Targets: []float64{102, 104}, Qty: 5, risk: 10}
+	if side == "short" {
+		t.SL, t.Targets = 102, []float64{98, 96}
+	}
+	pos := o.fill(t, sizes)
+	pos.atr = 1
+	return pos
+}
+
+func TestManage(t *testing.T) {
+	half := []float64{0.5, 0.5}
+	tests := []struct {
+		name    string
+		opts    backtestOptions
+		side    string
+		sizes   []float64
+		bars    []Candle
+		reasons []string
+		prices  []float64
+		r       float64
+		left    float64 // sisa qty
+	}{
+		{
+			name: "gap melewati SL keluar di open", side: "long", sizes: half,
+			bars:    bars([4]float64{97, 97.5, 96, 97}),
+			reasons: []string{"sl"}, prices: []float64{97}, r: -1.5,
+		},
+		{
+			name: "short gap melewati SL", side: "short", sizes: half,
+			bars:    bars([4]float64{103, 104, 102.5, 103}),
+			reasons: []string{"sl"}, prices: []float64{103}, r: -1.5,
+		},
+		{
+			name: "SL dan TP di bar yang sama: SL menang", side: "long", sizes: half,
+			bars:    bars([4]float64{100, 104.5, 97.5, 103}),
+			reasons: []string{"sl"}, prices: []float64{98}, r: -1,
+		},
+		{
+			name: "gap melewati semua TP terisi di open", side: "long", sizes: half,
+			bars:    bars([4]float64{105, 106, 104.5, 105}),
+			reasons: []string{"tp1", "tp2"}, prices: []float64{105, 105}, r: 2.5,
+		},
+		{
+			name: "TP parsial berjumlah qty penuh", side: "long", sizes: []float64{1.0 / 3, 2.0 / 3},
+			bars:    bars([4]float64{100, 102.5, 99.5, 102}, [4]float64{102, 104.5, 101.5, 104}),
+			reasons: []string{"tp1", "tp2"}, prices: []float64{102, 104}, r: (5.0/3*2 + 10.0/3*4) / 10,
+		},
+		{
+			name: "breakeven setelah TP1", opts: backtestOptions{Breakeven: true}, side: "long", sizes: half,
+			bars:    bars([4]float64{100, 102.5, 99.5, 102}, [4]float64{102, 102.5, 99.5, 100}),
+			reasons: []string{"tp1", "breakeven"}, prices: []float64{102, 100}, r: 0.5,
+		},
+		{
+			name: "tanpa breakeven SL tetap di tempat", side: "long", sizes: half,
+			bars:    bars([4]float64{100, 102.5, 99.5, 102}, [4]float64{102, 102.5, 99.5, 100}),
+			reasons: []string{"tp1"}, prices: []float64{102}, r: 0.5, left: 2.5,
+		},
+		{
+			name: "trailing stop mulai bar berikutnya", opts: backtestOptions{TrailATR: 1}, side: "long", sizes: half,
+			bars:    bars([4]float64{100, 102.5, 99.5, 102}, [4]float64{102, 103, 101, 101.2}),
+			reasons: []string{"tp1", "trail"}, prices: []float64{102, 101.5}, r: 0.875,
+		},
+		{
+			name: "max bars", opts: backtestOptions{MaxBars: 2}, side: "long", sizes: half,
+			bars:    bars([4]float64{100, 101, 99, 100.5}, [4]float64{100.5, 101, 99.5, 100.8}),
+			reasons: []string{"time"}, prices: []float64{100.8}, r: 0.4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos := testPosition(tt.opts, tt.side, tt.sizes)
+			for _, c := range tt.bars {
+				if pos.qty <= 0 {
+					break
+				}
+				tt.opts.manage(pos, c)
+			}
+			var reasons []string
+			var prices []float64
+			qty := 0.0
+			for _, e := range pos.trade.Exits {
+				reasons = append(reasons, e.Reason)
+				prices = append(prices, e.Price)
+				qty += e.Qty
+			}
+			if !reflect.DeepEqual(reasons, tt.reasons) || !reflect.DeepEqual(prices, tt.prices) {
+				t.Fatalf("exit %v @ %v, mau %v @ %v", reasons, prices, tt.reasons, tt.prices)
+			}
+			if math.Abs(pos.trade.R-tt.r) > 1e-9 {
+				t.Errorf("R = %.4f, mau %.4f", pos.trade.R, tt.r)
+			}
+			if math.Abs(pos.qty-tt.left) > 1e-9 || math.Abs(qty+pos.qty-pos.trade.Qty) > 1e-9 {
+				t.Errorf("sisa %.4f, keluar %.4f dari %.4f; mau sisa %.4f", pos.qty, qty, pos.trade.Qty, tt.left)
+			}
+		})
+	}
+}
+
+func TestBacktestBooksEntryFeeOnce(t *testing.T) {
+	rows := make([][4]float64, 40)
+	for i := range rows {
+		rows[i] = [4]float64{100, 100.5, 99.5, 100}
+	}
+	rows[32] = [4]float64{100, 102.5, 99.8, 102}
+	candles := bars(rows...)
+
+	o := backtestOptions{SLATR: 1, TPs: []float64{1, 2}, TPSizes: []float64{0.5, 0.5}, Fee: 0.1, Capital: 10000, Risk: 1, Lookback: 300}
+	o.TF = "1h"
+	signal := func(window []Candle) *btSignal {
+		if window[len(window)-1].Time.Equal(candles[30].Time) {
+			return &btSignal{Side: "long", ATR: 1}
+		}
+		return nil
+	}
+	report := backtest(candles, o, signal)
+	if len(report.Trades) != 1 {
+		t.Fatalf("trade = %d, mau 1", len(report.Trades))
+	}
+
+	// qty 100 di 100: fee entry 10, TP1 50 @ 101 fee 5.05, TP2 50 @ 102 fee 5.10
+	tr := report.Trades[0]
+	if !tr.EntryTime.Equal(candles[31].Time) || tr.Qty != 100 {
+		t.Fatalf("entry %s qty %.2f", tr.EntryTime.Format("15:04"), tr.Qty)
+	}
+	if math.Abs(tr.Fees-20.15) > 1e-9 || math.Abs(tr.PnL-129.85) > 1e-9 {
+		t.Errorf("fees %.4f pnl %.4f, mau 20.15 dan 129.85", tr.Fees, tr.PnL)
+	}
+	m := report.Metrics
+	if math.Abs(m.FinalEquity-10129.85) > 1e-9 || math.Abs(m.Fees-20.15) > 1e-9 {
+		t.Errorf("equity akhir %.4f fees %.4f, mau 10129.85 dan 20.15", m.FinalEquity, m.Fees)
+	}
+	// Bar entry: fee sudah dipotong, harga belum bergerak
+	if eq := report.Equity[31-30].Equity; math.Abs(eq-9990) > 1e-9 {
+		t.Errorf("equity di bar entry %.4f, mau 9990", eq)
+	}
+}
+
+func TestBacktestMetrics(t *testing.T) {
+	trades := []BacktestTrade{
+		{PnL: 200, R: 2, Fees: 4, Bars: 6},
+		{PnL: -100, R: -1, Fees: 2, Bars: 2},
+		{PnL: 50, R: 0.5, Fees: 3, Bars: 4},
+		{PnL: -100, R: -1, Fees: 2, Bars: 4},
+	}
+	day := 24 * time.Hour
+	equity := []EquityPoint{
+		{Time: barStart, Equity: 1000},
+		{Time: barStart.Add(day), Equity: 1010},
+		{Time: barStart.Add(2 * day), Equity: 1005, Drawdown: 0.495},
+		{Time: barStart.Add(3 * day), Equity: 1020},
+	}
+	m := backtestMetrics(trades, equity, 1000, 2, mustInterval("1d"))
+
+	want := BacktestMetrics{
+		Trades: 4, Wins: 2, WinRate: 50, Expectancy: 12.5, ExpectancyR: 0.125, ProfitFactor: 1.25,
+		MaxDrawdown: 0.495, Return: 2, FinalEquity: 1020, Fees: 11, AvgBars: 4, Exposure: 50,
+	}
+	if math.Abs(m.Sharpe-12.28960638) > 1e-6 {
+		t.Errorf("sharpe = %.8f, mau 12.28960638", m.Sharpe)
+	}
+	m.Sharpe = 0
+	for _, f := range []*float64{&m.Return, &m.WinRate, &m.Expectancy} {
+		*f = math.Round(*f*1e9) / 1e9
+	}
+	if m != want {
+		t.Errorf("metrics:\n got %+v\nwant %+v", m, want)
+	}
+
+	if m := backtestMetrics(trades[:1], nil, 1000, 0, mustInterval("1d")); m.ProfitFactor != 0 || m.Sharpe != 0 {
+		t.Errorf("tanpa trade rugi PF harus 0, dapat %+v", m)
+	}
+}
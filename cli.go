@@ -0,0 +1,670 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sdcoffey/techan"
+)
+
+// ================================
+// COMMAND LINE INTERFACE
+// ================================
+
+const usageText = `Usage:
+  ai-trade                      interactive mode (ditanya coin, timeframe, AI)
+  ai-trade analyze  [flags]     chart + S/R + patterns + analisa AI
+  ai-trade chart    [flags]     chart HTML saja, tanpa AI
+  ai-trade levels   [flags]     support/resistance saja, tanpa AI
+  ai-trade patterns [flags]     pattern detection saja, tanpa AI
+  ai-trade backfill [flags]     download history --from/--to ke CSV (--out)
+  ai-trade scan     [flags]     ranking watchlist / top volume pakai indikator, tanpa AI
+  ai-trade backtest [flags]     replay history bar per bar dengan rule entry (atau setup AI), fee, SL, multi-TP
+  ai-trade eval     [flags]     akurasi pattern: forward return per pattern/TF, kalibrasi confidence
+  ai-trade optimize [flags]     tuning threshold detector per symbol/TF (grid/random + walk-forward)
+  ai-trade watch    [flags]     stream kline live, analisa ulang tiap candle close
+  ai-trade paper    [flags]     paper trading live dari rule atau setup AI, state tersimpan di disk
+  ai-trade paper status         posisi terbuka, PnL realized/unrealized dan equity curve akun paper
+  ai-trade ws-stub  [flags]     server WebSocket lokal yang replay candle dari file
+  ai-trade prompts  [flags]     daftar template prompt (--prompt) beserta versinya
+  ai-trade usage    [flags]     ringkasan token & biaya AI per hari, model dan symbol
+
+Contoh:
+  ai-trade analyze --symbol SOL --tf 4h --ai deepseek --out report.json
+  ai-trade analyze --symbol SOL --tf 1h --tfs 15m,4h,1d
+  ai-trade analyze --symbol SOL --tf 5m --ai grok --prompt scalp
+  ai-trade analyze --symbol ETH --tf 4h --ai deepseek --show-reasoning
+  ai-trade analyze --symbol BTC --tf 4h --ai deepseek,grok,local
+  ai-trade analyze --symbol SOL --ai deepseek --budget 2
+  ai-trade usage --by model --since 2024-06-01
+  ai-trade scan --top 20 --ai deepseek --refresh
+  ai-trade analyze --provider file --file sol_1h.csv --ai mock --out report.json
+  ai-trade analyze --provider file --file sol_1h.csv --ai deepseek --ai-record fixtures/llm
+  ai-trade analyze --provider file --file sol_1h.csv --ai deepseek --ai-replay fixtures/llm
+  ai-trade analyze --symbol BTC --ai local --ai-url http://127.0.0.1:8080/v1 --ai-model qwen2.5
+  ai-trade backfill --symbol SOL --tf 1h --from 2024-01-01 --out sol_1h.csv
+  ai-trade scan --top 30 --tf 4h --rank squeeze+macd-bull-cross+near-support
+  ai-trade backtest --symbol SOL --tf 4h --from 2023-01-01 --long macd-bull-cross+near-support --tp 1.5,3 --breakeven
+  ai-trade backtest --symbol SOL --tf 1h --from 2024-01-01 --ai deepseek,grok --every 24 --horizon 48 --out bt_ai.json
+  ai-trade eval --symbols BTC,ETH,SOL --tf 1h --tfs 4h,1d --from 2022-01-01 --save
+  ai-trade optimize --symbols BTC,SOL --tf 1h --tfs 4h --from 2023-01-01 --long near-support+bullish-pattern --save
+  ai-trade paper --symbols BTC,SOL --tf 1h --long macd-bull-cross+trend-up --tp 1.5,3 --trail-atr 2
+  ai-trade paper --symbols SOL --tf 4h --ai deepseek --strict --expire 4
+  ai-trade backtest --provider file --file sol_1h.csv --symbol SOL --long bullish-pattern --short bearish-pattern --out bt.json
+
+Jalankan "ai-trade <command> -h" untuk daftar flag.
+`
+
+type cliOptions struct {
+	Symbol      string
+	TF          string
+	AI          string
+	Out         string
+	Provider    string
+	ProviderURL string
+	File        string
+	TFs         []string
+	Candles     int
+	From, To    time.Time
+	CacheDir    string
+	NoStore     bool
+	Offline     bool
+	LLM         LLMConfig
+	Fast        bool
+	Prompt      string
+	PromptDir   string
+	Format      string
+	Models      []string // ensemble members when --ai lists more than one
+	Ledger      string
+	PricesFile  string
+	Budget      float64
+	NoCache     bool
+	Refresh     bool
+	LLMCacheDir string
+	Record      string
+	Replay      string
+	Calibration string
+	Params      string
+
+	prompt      *promptTemplate
+	calibration *patternCalibration
+	params      *detectorParams
+}
+
+// AnalysisReport is the machine readable result written by --out.
+type AnalysisReport struct {
+	Symbol      string              `json:"symbol"`
+	Timeframe   string              `json:"timeframe"`
+	AI          string              `json:"ai,omitempty"`
+	GeneratedAt time.Time           `json:"generated_at"`
+	Price       float64             `json:"price"`
+	Levels      []SupportResistance `json:"support_resistance"`
+	Patterns    []Pattern           `json:"patterns"`
+	MTF         *MultiTimeframe     `json:"multi_timeframe,omitempty"`
+	Chart       string              `json:"chart,omitempty"`
+	Analysis    string              `json:"analysis,omitempty"`
+	Reasoning   string              `json:"reasoning,omitempty"`
+	Plan        *TradePlan          `json:"plan,omitempty"`
+	PlanIssues  []string            `json:"plan_issues,omitempty"`
+	Usage       *LLMUsage           `json:"usage,omitempty"`
+	Ensemble    *EnsembleResult     `json:"ensemble,omitempty"`
+	Verdict     *SetupVerdict       `json:"validator,omitempty"`
+}
+
+func runCommand(args []string) error {
+	cmd := "analyze"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	var err error
+	switch cmd {
+	case "analyze", "chart", "levels", "patterns", "backfill":
+		var opts cliOptions
+		if opts, err = parseCommonFlags(cmd, args); err == nil {
+			err = runPipeline(cmd, opts)
+		}
+	case "scan":
+		err = runScan(args)
+	case "backtest":
+		err = runBacktest(args)
+	case "eval":
+		err = runEval(args)
+	case "optimize":
+		err = runOptimize(args)
+	case "watch":
+		err = runWatch(args)
+	case "paper":
+		err = runPaper(args)
+	case "ws-stub":
+		err = runWSStub(args)
+	case "prompts":
+		err = runPrompts(args)
+	case "usage":
+		err = runUsage(args)
+	case "help":
+		fmt.Print(usageText)
+	default:
+		return fmt.Errorf("command tidak dikenal %q\n\n%s", cmd, usageText)
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+func parseCommonFlags(cmd string, args []string) (cliOptions, error) {
+	var opts cliOptions
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.StringVar(&opts.Symbol, "symbol", "", "coin atau pair, contoh: SOL, BTC, ETHUSDT")
+	fs.StringVar(&opts.Out, "out", "", "tulis report JSON ke file ini")
+	finish := dataFlags(fs, &opts)
+	if cmd == "analyze" {
+		fs.StringVar(&opts.AI, "ai", "deepseek", "AI yang dipakai: "+strings.Join(llmNames(), ", ")+"; pisah koma untuk ensemble")
+		aiFlags(fs, &opts)
+	}
+	tfs := ""
+	if cmd != "chart" && cmd != "backfill" {
+		fs.StringVar(&tfs, "tfs", "", "stack timeframe tambahan, mis. 15m,1h,4h,1d (--tf selalu ikut)")
+	}
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("argumen tidak dikenal: %s", strings.Join(fs.Args(), " "))
+	}
+	if err := finish(); err != nil {
+		return opts, err
+	}
+	if cmd == "backfill" && (opts.From.IsZero() || opts.Out == "") {
+		return opts, errors.New("backfill butuh --from dan --out")
+	}
+	if tfs != "" {
+		var err error
+		if opts.TFs, err = parseTimeframes(tfs, opts.TF); err != nil {
+			return opts, err
+		}
+	}
+
+	if strings.TrimSpace(opts.Symbol) == "" {
+		return opts, errors.New("--symbol wajib diisi")
+	}
+	opts.Symbol = normalizeSymbol(opts.Symbol)
+	if cmd == "analyze" {
+		return opts, validateAI(&opts)
+	}
+	return opts, nil
+}
+
+// dataFlags registers the timeframe and market data flags shared by every
+// command that loads candles. The returned func validates them after Parse.
+func dataFlags(fs *flag.FlagSet, opts *cliOptions) func() error {
+	fs.StringVar(&opts.TF, "tf", "1h", "timeframe Binance: "+strings.Join(binanceIntervals, ", "))
+	fs.StringVar(&opts.Provider, "provider", "binance", "sumber data: "+strings.Join(providerNames, ", "))
+	fs.StringVar(&opts.ProviderURL, "provider-url", "", "override base URL provider (mis. fixture server lokal)")
+	fs.StringVar(&opts.File, "file", "", "file CSV/Parquet untuk --provider file, boleh pakai {symbol} dan {interval}")
+	fs.IntVar(&opts.Candles, "candles", 500, "jumlah candle terakhir (di-paging kalau lebih dari limit provider)")
+	fs.StringVar(&opts.CacheDir, "cache-dir", defaultStoreDir(), "folder cache candle lokal")
+	fs.BoolVar(&opts.NoStore, "no-store", false, "jangan pakai cache candle lokal")
+	fs.BoolVar(&opts.Offline, "offline", false, "pakai cache candle lokal saja, tanpa request ke provider")
+	from := fs.String("from", "", "awal range history, mis. 2024-01-01 (aktifkan backfill)")
+	to := fs.String("to", "", "akhir range history (default: sekarang)")
+	fs.StringVar(&opts.Calibration, "calibration", defaultCalibrationFile(), "kalibrasi confidence pattern dari ai-trade eval --save (kosong = tanpa)")
+	fs.StringVar(&opts.Params, "params", defaultParamsFile(), "parameter detector per symbol/timeframe dari ai-trade optimize --save (kosong = default); backtest dan paper hanya memakai set yang di-tune dengan --long/--short yang sama")
+
+	return func() error {
+		var err error
+		if opts.From, err = parseDateFlag(*from); err != nil {
+			return err
+		}
+		if opts.To, err = parseDateFlag(*to); err != nil {
+			return err
+		}
+		if opts.Candles <= 0 {
+			return errors.New("--candles harus lebih dari 0")
+		}
+		if opts.Offline && opts.NoStore {
+			return errors.New("--offline butuh cache, jangan digabung dengan --no-store")
+		}
+		if opts.calibration, err = loadCalibration(opts.Calibration); err != nil {
+			return fmt.Errorf("--calibration: %w", err)
+		}
+		if opts.params, err = loadParams(opts.Params); err != nil {
+			return fmt.Errorf("--params: %w", err)
+		}
+		opts.TF = strings.TrimSpace(opts.TF)
+		_, err = ParseInterval(opts.TF)
+		return err
+	}
+}
+
+// aiFlags registers the LLM backend settings for commands that call an AI.
+func aiFlags(fs *flag.FlagSet, opts *cliOptions) {
+	fs.StringVar(&opts.LLM.BaseURL, "ai-url", "", "override base URL AI (mis. server lokal / mock)")
+	fs.StringVar(&opts.LLM.Model, "ai-model", "", "override model AI")
+	fs.Func("temperature", "temperature AI (default: default provider)", func(v string) error {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t < 0 || t > 2 {
+			return fmt.Errorf("temperature %q tidak valid (0 - 2)", v)
+		}
+		opts.LLM.Temperature = &t
+		return nil
+	})
+	fs.IntVar(&opts.LLM.MaxTokens, "max-tokens", 0, "batas token jawaban AI (0 = default provider)")
+	fs.DurationVar(&opts.LLM.Timeout, "ai-timeout", defaultLLMTimeout, "timeout request AI (termasuk baca stream)")
+	fs.IntVar(&opts.LLM.Retries, "ai-retries", defaultLLMRetries, "retry request AI saat 429 / 5xx (backoff + jitter)")
+	fs.StringVar(&opts.LLM.SystemPrompt, "system-prompt", "", "system prompt untuk AI")
+	fs.BoolVar(&opts.Fast, "fast", false, "DeepSeek mode cepat (deepseek-chat) tanpa DeepThink")
+	fs.StringVar(&opts.LLM.TraceDir, "save-reasoning", "", "simpan reasoning trace DeepThink ke folder ini")
+	fs.StringVar(&opts.Prompt, "prompt", defaultPrompt, "template prompt (lihat: ai-trade prompts)")
+	fs.StringVar(&opts.PromptDir, "prompt-dir", os.Getenv("PROMPT_DIR"), "folder berisi <nama>.tmpl yang menimpa template bawaan")
+	fs.StringVar(&opts.Format, "format", "json", "format jawaban AI: json (divalidasi lalu dirender) atau text (bebas)")
+	fs.BoolVar(&opts.LLM.Stream, "stream", true, "tampilkan jawaban AI token demi token (Ctrl-C untuk batal)")
+	fs.BoolVar(&opts.LLM.ShowReasoning, "show-reasoning", false, "stream reasoning model (redup) alih-alih baris progress")
+	fs.StringVar(&opts.Ledger, "ledger", defaultUsageLedger(), "file ledger token & biaya AI (kosong = tidak dicatat)")
+	fs.StringVar(&opts.PricesFile, "prices", defaultPricesFile(), "tabel harga per model (JSON, USD per 1M token)")
+	fs.BoolVar(&opts.NoCache, "no-cache", false, "jangan pakai cache jawaban AI")
+	fs.BoolVar(&opts.Refresh, "refresh", false, "abaikan cache jawaban AI, tanya ulang lalu simpan hasil baru")
+	fs.StringVar(&opts.LLMCacheDir, "ai-cache-dir", defaultLLMCacheDir(), "folder cache jawaban AI")
+	fs.StringVar(&opts.Record, "ai-record", "", "rekam setiap jawaban AI sebagai fixture di folder ini")
+	fs.StringVar(&opts.Replay, "ai-replay", "", "jawab AI hanya dari fixture di folder ini, tanpa network")
+	fs.Float64Var(&opts.Budget, "budget", envFloat("LLM_BUDGET_DAILY"), "batas biaya AI per hari dalam USD, 0 = tanpa batas")
+}
+
+// validateAI checks --ai and normalizes its name. Defaults are filled in
+// later so callers can still tell whether --ai-model was given.
+func validateAI(opts *cliOptions) error {
+	opts.Models = nil
+	seen := map[string]bool{}
+	for _, name := range strings.Split(opts.AI, ",") {
+		cfg := opts.LLM
+		cfg.Name = name
+		cfg, err := resolveLLMConfig(cfg)
+		if err != nil {
+			return err
+		}
+		if !seen[cfg.Name] {
+			seen[cfg.Name] = true
+			opts.Models = append(opts.Models, cfg.Name)
+		}
+	}
+	opts.AI, opts.LLM.Name = strings.Join(opts.Models, ","), opts.Models[0]
+
+	switch opts.Format = orDefault(opts.Format, "json"); opts.Format {
+	case "json", "text":
+	default:
+		return fmt.Errorf("--format %q tidak dikenal, pilih json atau text", opts.Format)
+	}
+
+	if len(opts.Models) == 1 {
+		opts.Models = nil
+	} else {
+		// Konsensus butuh jawaban terstruktur dari tiap model
+		if opts.Format != "json" {
+			return errors.New("ensemble butuh --format json")
+		}
+		if opts.LLM.BaseURL != "" || opts.LLM.Model != "" {
+			return errors.New("--ai-url / --ai-model tidak bisa untuk ensemble, atur <AI>_BASE_URL / <AI>_MODEL di .env")
+		}
+		opts.LLM.Stream = false
+	}
+
+	var err error
+	if opts.LLM.Meter, err = newUsageMeter(opts.Ledger, opts.PricesFile, opts.Budget); err != nil {
+		return err
+	}
+	switch {
+	case opts.Record != "" && opts.Replay != "":
+		return errors.New("--ai-record dan --ai-replay tidak bisa digabung")
+	case opts.Record != "" || opts.Replay != "":
+		opts.LLM.Cache = newFixtureCache(opts.Record+opts.Replay, opts.Replay != "")
+	case !opts.NoCache:
+		opts.LLM.Cache = newLLMCache(orDefault(opts.LLMCacheDir, defaultLLMCacheDir()), opts.Refresh)
+	}
+	opts.prompt, err = loadPrompt(orDefault(opts.Prompt, defaultPrompt), opts.PromptDir)
+	return err
+}
+
+// envFloat reads a numeric default from the environment; unset or invalid is 0.
+func envFloat(key string) float64 {
+	v, _ := strconv.ParseFloat(os.Getenv(key), 64)
+	return v
+}
+
+// runInteractive is the original prompt-driven flow, used when no arguments are given.
+func runInteractive() {
+	coinInput := askInput("Type coin name (contoh: sol, btc, eth): ")
+	symbol := normalizeSymbol(coinInput)
+
+	tf := askInput("Input timeframe (15m / 1h / 4h / 1d): ")
+	tf = strings.TrimSpace(tf)
+	if _, err := ParseInterval(tf); err != nil {
+		log.Fatal(err)
+	}
+
+	ai := askInput("Choose AI (" + strings.Join(llmNames(), " / ") + "): ")
+
+	opts := cliOptions{Symbol: symbol, TF: tf, AI: ai, Candles: 500, CacheDir: defaultStoreDir(),
+		LLM: LLMConfig{Retries: defaultLLMRetries, Stream: true}, Ledger: defaultUsageLedger(), PricesFile: defaultPricesFile(),
+		Budget: envFloat("LLM_BUDGET_DAILY")}
+	if err := validateAI(&opts); err != nil {
+		log.Fatal(err)
+	}
+	if err := runPipeline("analyze", opts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func normalizeSymbol(coin string) string {
+	symbol := strings.ToUpper(strings.TrimSpace(coin))
+	if !strings.HasSuffix(symbol, "USDT") {
+		symbol += "USDT"
+	}
+	return symbol
+}
+
+func runPipeline(cmd string, opts cliOptions) error {
+	provider, err := openProvider(opts)
+	if err != nil {
+		return err
+	}
+
+	if cmd == "analyze" {
+		if err := checkAIKeys(opts); err != nil {
+			return err
+		}
+		fmt.Printf("\n🔥 Mengambil %s %s + analisa pakai %s...\n\n", opts.Symbol, opts.TF, strings.ToUpper(opts.AI))
+	} else {
+		fmt.Printf("\n🔥 Mengambil %s %s dari %s...\n\n", opts.Symbol, opts.TF, provider.Name())
+	}
+
+	var candles []Candle
+	var series *techan.TimeSeries
+	if opts.From.IsZero() {
+		candles, series, err = fetchData(provider, opts.Symbol, opts.TF, opts.Candles)
+	} else {
+		candles, err = backfillCandles(provider, opts.Symbol, opts.TF, opts.From, opts.To)
+		series = buildSeries(candles, mustInterval(opts.TF))
+	}
+	if errors.Is(err, ErrInvalidSymbol) {
+		return fmt.Errorf("symbol %s tidak ada di %s: %w", opts.Symbol, provider.Name(), err)
+	}
+	if err != nil {
+		return err
+	}
+	if len(candles) == 0 {
+		return fmt.Errorf("tidak ada candle %s %s di range itu", opts.Symbol, opts.TF)
+	}
+	if !opts.From.IsZero() {
+		fmt.Printf("📥 Backfill %d candle (%s → %s)\n", len(candles), opts.From.Format("2006-01-02"), candleEnd(candles, opts.To).Format("2006-01-02 15:04"))
+	}
+
+	if cmd == "backfill" {
+		if err := writeCSVCandles(opts.Out, candles); err != nil {
+			return fmt.Errorf("tulis CSV %s: %w", opts.Out, err)
+		}
+		fmt.Printf("✅ %d candle disimpan → %s\n", len(candles), opts.Out)
+		return nil
+	}
+	// Analisa di --to cuma boleh lihat candle yang sudah close di titik itu
+	if !opts.To.IsZero() {
+		candles = closedOnly(candles, opts.To)
+		series = buildSeries(candles, mustInterval(opts.TF))
+		if len(candles) == 0 {
+			return fmt.Errorf("tidak ada candle %s %s yang close sebelum %s", opts.Symbol, opts.TF, opts.To.Format(time.RFC3339))
+		}
+	}
+
+	// Tambahan: Deteksi Support/Resistance dan Patterns
+	detectors := opts.params.config(opts.Symbol, opts.TF)
+	if detectors != defaultDetectorConfig {
+		fmt.Printf("⚙️  Parameter detector hasil optimize: %s\n", detectors.diff())
+	}
+	srLevels := detectSupportResistance(candles, detectors.SR)
+	patterns := detectPatterns(candles, detectors.Patterns)
+	opts.calibration.apply(patterns, opts.TF, detectors.Patterns)
+
+	report := AnalysisReport{
+		Symbol:      opts.Symbol,
+		Timeframe:   opts.TF,
+		GeneratedAt: time.Now(),
+		Levels:      srLevels,
+		Patterns:    patterns,
+	}
+	if len(candles) > 0 {
+		report.Price = candles[len(candles)-1].Close.InexactFloat64()
+	}
+	if len(opts.TFs) > 0 {
+		fmt.Printf("🧭 Mengambil stack timeframe %s...\n", strings.Join(opts.TFs, "/"))
+		if report.MTF, err = analyzeTimeframes(provider, opts.Symbol, opts.TFs, opts.Candles, opts.To, opts.params); err != nil {
+			return err
+		}
+	}
+
+	switch cmd {
+	case "levels":
+		printLevels(srLevels)
+		printMultiTimeframe(report.MTF)
+	case "patterns":
+		printPatterns(patterns)
+		printMultiTimeframe(report.MTF)
+	case "chart":
+		if report.Chart, err = generateTradingChart(candles, series, opts.Symbol, opts.TF, srLevels, patterns); err != nil {
+			return err
+		}
+	case "analyze":
+		if report.Chart, err = generateTradingChart(candles, series, opts.Symbol, opts.TF, srLevels, patterns); err != nil {
+			return err
+		}
+
+		// Ctrl-C membatalkan request AI dengan bersih, bukan mematikan proses
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		analysis := analyzeAI(ctx, opts, candles, series, opts.Symbol, opts.TF, srLevels, patterns, report.MTF)
+		cancelled := ctx.Err() != nil
+		stop()
+		if cancelled {
+			return errors.New("analisa AI dibatalkan")
+		}
+		report.AI = opts.AI
+		report.Analysis = analysis.Content
+		report.Reasoning = analysis.Reasoning
+		report.Usage = &analysis.Usage
+		report.Plan = analysis.Plan
+		report.PlanIssues = analysis.Problems
+		report.Verdict = analysis.Verdict
+		report.Ensemble = analysis.Ensemble
+		if analysis.Ensemble != nil {
+			path, err := writeEnsembleHTML(analysis.Ensemble, opts.Symbol, opts.TF)
+			if err != nil {
+				return fmt.Errorf("tulis ensemble HTML: %w", err)
+			}
+			fmt.Printf("🤝 Perbandingan ensemble disimpan → %s\n", path)
+		}
+
+		printBeautifulAnalysis(analysis, opts.Symbol, opts.TF, report.Chart, srLevels, patterns, report.MTF)
+	}
+
+	if opts.Out != "" {
+		if err := writeReport(opts.Out, report); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Report disimpan → %s\n", opts.Out)
+	}
+	return nil
+}
+
+// checkAIKeys fails early, before any candles are fetched, when a selected
+// backend needs a key that is not set.
+func checkAIKeys(opts cliOptions) error {
+	if opts.Replay != "" {
+		return nil
+	}
+	for _, name := range opts.models() {
+		cfg := opts.LLM
+		cfg.Name = name
+		if _, err := newLLMClient(cfg); err != nil {
+			return fmt.Errorf("⚠️  %w", err)
+		}
+	}
+	return nil
+}
+
+// AIAnalysis is the AI step's result. With --format json, Plan holds the
+// parsed answer and Content its rendering; Problems lists what was still
+// wrong after the corrective re-ask and Verdict is the market check of the
+// setup.
+type AIAnalysis struct {
+	LLMResponse
+	Plan     *TradePlan
+	Problems []string
+	Verdict  *SetupVerdict
+	Ensemble *EnsembleResult
+
+	meter *usageMeter
+}
+
+func callSelectedAI(ctx context.Context, opts cliOptions, candles []Candle, series *techan.TimeSeries, symbol, tf string, srLevels []SupportResistance, patterns []Pattern, mtf *MultiTimeframe) AIAnalysis {
+	jsonOutput := opts.Format != "text"
+	facts, prompt, err := buildPrompt(opts.prompt, series, symbol, tf, srLevels, patterns, mtf, jsonOutput)
+	if err != nil {
+		return AIAnalysis{LLMResponse: LLMResponse{Content: "Error prompt: " + err.Error()}}
+	}
+
+	cfg := opts.LLM
+	cfg.JSONMode = jsonOutput
+	cfg.ShowContent = !jsonOutput
+	cfg.Facts = facts
+	cfg.Symbol, cfg.TF = symbol, tf
+	cfg.PromptID = opts.prompt.ID()
+	cfg.CacheUntil = cacheExpiry(candles, tf, time.Now())
+	cfg.CacheKey = cacheKeyPrompt(opts, candles, symbol, tf, srLevels, patterns, mtf, jsonOutput, time.Now())
+	ask := func(prompt string) LLMResponse {
+		switch {
+		case opts.AI == "deepseek" && opts.Fast:
+			return callDeepSeek(ctx, cfg, prompt)
+		case opts.AI == "deepseek":
+			return callDeepSeekWithDeepThink(ctx, cfg, symbol, tf, prompt) // Ganti ke DeepThink
+		}
+		return callLLM(ctx, cfg, prompt)
+	}
+
+	resp := ask(prompt)
+	usage := resp.Usage
+	if !jsonOutput || ctx.Err() != nil || strings.HasPrefix(resp.Content, "Error API:") {
+		return AIAnalysis{LLMResponse: resp}
+	}
+
+	plan, problems := checkPlan(resp.Content)
+	if len(problems) > 0 {
+		fmt.Printf("🔁 Jawaban AI tidak valid (%s), minta koreksi sekali...\n", strings.Join(problems, "; "))
+		if cfg.CacheKey != "" {
+			cfg.CacheKey = correctionPrompt(cfg.CacheKey, resp.Content, problems)
+		}
+		resp = ask(correctionPrompt(prompt, resp.Content, problems))
+		usage.Add(resp.Usage)
+		resp.Usage = usage
+		plan, problems = checkPlan(resp.Content)
+	}
+	if plan == nil {
+		return AIAnalysis{LLMResponse: resp, Problems: problems}
+	}
+
+	out := AIAnalysis{LLMResponse: resp, Plan: plan, Problems: problems}
+	out.Verdict = validateSetup(plan.Setup, candles, summarizeIndicators(series, symbol, tf), srLevels, defaultValidatorConfig)
+	out.Content = renderPlan(plan)
+	if len(problems) > 0 {
+		out.Content += "\n⚠️  PLAN MASIH TIDAK VALID:\n• " + strings.Join(problems, "\n• ") + "\n"
+	}
+	if out.Verdict != nil {
+		out.Content += "\n" + renderVerdict(out.Verdict)
+	}
+	return out
+}
+
+// checkPlan parses and validates an answer. A nil plan means it could not
+// be parsed at all.
+func checkPlan(content string) (*TradePlan, []string) {
+	plan, err := parsePlan(content)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	return plan, plan.Validate()
+}
+
+// openProvider builds the --provider source and, unless disabled, puts the
+// local candle store in front of it. File sources are already local.
+func openProvider(opts cliOptions) (CandleProvider, error) {
+	provider, err := newProvider(opts.Provider, opts.ProviderURL, opts.File)
+	if err != nil {
+		return nil, err
+	}
+	if opts.NoStore || provider.Name() == "file" {
+		return provider, nil
+	}
+	return &cachedProvider{inner: provider, store: &candleStore{dir: opts.CacheDir}, offline: opts.Offline}, nil
+}
+
+func candleEnd(candles []Candle, fallback time.Time) time.Time {
+	if len(candles) == 0 {
+		return fallback
+	}
+	return candles[len(candles)-1].Time
+}
+
+func writeReport(path string, report AnalysisReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("tulis report %s: %w", path, err)
+	}
+	return nil
+}
+
+func printLevels(srLevels []SupportResistance) {
+	if len(srLevels) == 0 {
+		fmt.Println("🎯 Tidak ada level support/resistance yang valid.")
+		return
+	}
+	fmt.Println("🎯 KEY LEVELS:")
+	for _, level := range srLevels {
+		emoji := "🟢"
+		if level.Type == "resistance" {
+			emoji = "🔴"
+		}
+		fmt.Printf("%s %s: $%.4f (Strength: %d)\n", emoji, strings.ToUpper(level.Type), level.Price, level.Strength)
+	}
+}
+
+func printPatterns(patterns []Pattern) {
+	if len(patterns) == 0 {
+		fmt.Println("🎭 Tidak ada pattern terdeteksi.")
+		return
+	}
+	fmt.Println("🎭 PATTERNS DETECTED:")
+	for _, pattern := range patterns {
+		emoji := "🟢"
+		if pattern.Type == "bearish" {
+			emoji = "🔴"
+		} else if pattern.Type == "continuation" {
+			emoji = "🟡"
+		}
+		if hist := pattern.describe(); hist != "" {
+			fmt.Printf("%s %s (%.0f%% confidence, historis %s)\n", emoji, pattern.Name, pattern.Confidence*100, hist)
+			continue
+		}
+		fmt.Printf("%s %s (%.0f%% confidence)\n", emoji, pattern.Name, pattern.Confidence*100)
+	}
+}
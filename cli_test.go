@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readReport(t *testing.T, path string) AnalysisReport {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report AnalysisReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("report %s: %v", path, err)
+	}
+	return report
+}
+
+// TestAnalyzeFileMockReplay runs analyze end to end on the CSV fixture: once
+// against the mock backend while recording, then again from the recording only.
+func TestAnalyzeFileMockReplay(t *testing.T) {
+	fixture, err := filepath.Abs("testdata/SOLUSDT_1h.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := t.TempDir()
+	wd, _ := os.Getwd()
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	fixtures := filepath.Join(work, "fixtures")
+	common := []string{"analyze", "--provider", "file", "--file", fixture, "--symbol", "SOLUSDT", "--tf", "1h",
+		"--ledger", "", "--calibration", "", "--params", "", "--no-cache"}
+	run := func(out string, extra ...string) AnalysisReport {
+		t.Helper()
+		args := append(append(append([]string{}, common...), "--out", out), extra...)
+		if err := runCommand(args); err != nil {
+			t.Fatalf("analyze %v: %v", extra, err)
+		}
+		return readReport(t, out)
+	}
+
+	live := run(filepath.Join(work, "live.json"), "--ai", "mock", "--ai-record", fixtures)
+	if live.AI != "mock" || live.Symbol != "SOLUSDT" || live.Timeframe != "1h" {
+		t.Errorf("header report: ai %q symbol %q tf %q", live.AI, live.Symbol, live.Timeframe)
+	}
+	if live.Price != 149.11 {
+		t.Errorf("price = %v, mau close candle terakhir 149.11", live.Price)
+	}
+	if len(live.Levels) == 0 || live.Chart == "" {
+		t.Errorf("report tanpa level (%d) atau chart (%q)", len(live.Levels), live.Chart)
+	}
+	if _, err := os.Stat(filepath.Join(work, live.Chart)); err != nil {
+		t.Errorf("chart: %v", err)
+	}
+
+	plan := live.Plan
+	if plan == nil {
+		t.Fatalf("plan kosong, issues: %v", live.PlanIssues)
+	}
+	if len(live.PlanIssues) != 0 {
+		t.Errorf("plan issues: %v", live.PlanIssues)
+	}
+	if problems := plan.Validate(); len(problems) != 0 {
+		t.Errorf("plan di report tidak valid: %v", problems)
+	}
+	if plan.Symbol != "SOLUSDT" || plan.Timeframe != "1h" || plan.Setup.Direction == "" {
+		t.Errorf("plan: %+v", plan)
+	}
+	if live.Verdict == nil {
+		t.Error("validator tidak jalan")
+	}
+	recorded, _ := filepath.Glob(filepath.Join(fixtures, "*", "*.json"))
+	if len(recorded) == 0 {
+		t.Fatal("--ai-record tidak menulis fixture")
+	}
+
+	replay := run(filepath.Join(work, "replay.json"), "--ai", "mock", "--ai-replay", fixtures)
+	if !reflect.DeepEqual(replay.Plan, live.Plan) {
+		t.Errorf("plan replay beda dari live:\n%+v\n%+v", replay.Plan, live.Plan)
+	}
+	if replay.Analysis != live.Analysis {
+		t.Error("analisa replay beda dari live")
+	}
+
+	// Replay tidak boleh diam-diam jatuh ke backend live
+	empty := run(filepath.Join(work, "empty.json"), "--ai", "mock", "--ai-replay", t.TempDir())
+	if empty.Plan != nil {
+		t.Error("replay tanpa fixture tetap memberi plan")
+	}
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/sdcoffey/techan"
+)
+
+// ================================
+// ENSEMBLE (--ai deepseek,grok,local)
+// ================================
+
+// EnsembleMember is one model's answer inside an ensemble run.
+type EnsembleMember struct {
+	AI       string        `json:"ai"`
+	Model    string        `json:"model"`
+	Plan     *TradePlan    `json:"plan,omitempty"`
+	Problems []string      `json:"problems,omitempty"`
+	Verdict  *SetupVerdict `json:"validator,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Usage    LLMUsage      `json:"usage"`
+}
+
+// Spread is the range of one price across the members that agree on the
+// consensus direction.
+type Spread struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+	Pct  float64 `json:"pct"` // (max - min) / mean
+	ATR  float64 `json:"atr"` // (max - min) / ATR
+}
+
+// Consensus reconciles the members' plans.
+type Consensus struct {
+	Direction     string              `json:"direction"` // setup direction most members chose
+	Agreement     float64             `json:"agreement"` // share of valid members with that direction
+	Votes         map[string][]string `json:"votes"`
+	TrendVotes    map[string][]string `json:"trend_votes"`
+	Entry         *Spread             `json:"entry,omitempty"`
+	Target        *Spread             `json:"target,omitempty"`
+	SL            *Spread             `json:"sl,omitempty"`
+	RR            float64             `json:"rr,omitempty"` // from the mean entry, target and SL
+	Scenarios     []PlanScenario      `json:"scenarios"`    // probabilities averaged per name
+	Disagreements []string            `json:"disagreements"`
+}
+
+type EnsembleResult struct {
+	Members   []EnsembleMember `json:"members"`
+	Consensus Consensus        `json:"consensus"`
+}
+
+const (
+	ensembleSpreadATR     = 1.0  // level spread wider than this many ATR is a disagreement
+	ensembleSpreadPct     = 2.0  // fallback when there is no ATR
+	ensembleScenarioSwing = 20.0 // probability points between the most and least confident model
+)
+
+// models lists the backends in --ai; more than one means ensemble mode.
+func (o cliOptions) models() []string {
+	if len(o.Models) > 0 {
+		return o.Models
+	}
+	return []string{o.AI}
+}
+
+// analyzeAI runs the single selected AI, or every model of an ensemble.
+func analyzeAI(ctx context.Context, opts cliOptions, candles []Candle, series *techan.TimeSeries, symbol, tf string, srLevels []SupportResistance, patterns []Pattern, mtf *MultiTimeframe) AIAnalysis {
+	if len(opts.Models) < 2 {
+		out := callSelectedAI(ctx, opts, candles, series, symbol, tf, srLevels, patterns, mtf)
+		out.meter = opts.LLM.Meter
+		return out
+	}
+
+	fmt.Printf("🤝 Ensemble: %s dipanggil bersamaan...\n", strings.Join(opts.Models, ", "))
+	members := make([]EnsembleMember, len(opts.Models))
+	var wg sync.WaitGroup
+	for i, name := range opts.Models {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			o := opts
+			o.AI, o.LLM.Name, o.Models = name, name, nil
+			members[i] = newEnsembleMember(name, callSelectedAI(ctx, o, candles, series, symbol, tf, srLevels, patterns, mtf))
+		}(i, name)
+	}
+	wg.Wait()
+
+	res := &EnsembleResult{Members: members, Consensus: buildConsensus(members)}
+	out := AIAnalysis{LLMResponse: LLMResponse{Model: "ensemble", Content: renderEnsemble(res)}, Ensemble: res, meter: opts.LLM.Meter}
+	for _, m := range members {
+		out.Usage.Add(m.Usage)
+	}
+	return out
+}
+
+func newEnsembleMember(name string, a AIAnalysis) EnsembleMember {
+	m := EnsembleMember{AI: name, Model: a.Model, Plan: a.Plan, Problems: a.Problems, Verdict: a.Verdict, Usage: a.Usage}
+	switch {
+	case strings.HasPrefix(a.Content, "Error API:"):
+		m.Error = strings.TrimSpace(strings.TrimPrefix(a.Content, "Error API:"))
+	case a.Plan == nil:
+		m.Error = "jawaban bukan plan JSON: " + strings.Join(a.Problems, "; ")
+	}
+	return m
+}
+
+// buildConsensus votes on direction and measures how far apart the
+// members that share the winning direction put their levels.
+func buildConsensus(members []EnsembleMember) Consensus {
+	c := Consensus{Votes: map[string][]string{}, TrendVotes: map[string][]string{}}
+	var plans []EnsembleMember
+	for _, m := range members {
+		if m.Plan == nil {
+			continue
+		}
+		plans = append(plans, m)
+		c.Votes[m.Plan.Setup.Direction] = append(c.Votes[m.Plan.Setup.Direction], m.AI)
+		c.TrendVotes[m.Plan.Trend.Direction] = append(c.TrendVotes[m.Plan.Trend.Direction], m.AI)
+	}
+	if len(plans) == 0 {
+		c.Direction = "none"
+		c.Disagreements = append(c.Disagreements, "tidak ada model yang memberi plan valid")
+		return c
+	}
+
+	c.Direction = majority(c.Votes, "none")
+	c.Agreement = float64(len(c.Votes[c.Direction])) / float64(len(plans))
+	if len(c.Votes) > 1 {
+		c.Disagreements = append(c.Disagreements, "arah setup beda: "+formatVotes(c.Votes))
+	}
+	if len(c.TrendVotes) > 1 {
+		c.Disagreements = append(c.Disagreements, "arah trend beda: "+formatVotes(c.TrendVotes))
+	}
+
+	// Spread level hanya antar model yang searah
+	var atr float64
+	var entries, targets, stops []float64
+	for _, m := range plans {
+		if m.Verdict != nil && atr == 0 {
+			atr = m.Verdict.ATR
+		}
+		s := m.Plan.Setup
+		if s.Direction != c.Direction || c.Direction == "none" {
+			continue
+		}
+		entries = append(entries, s.EntryMid())
+		targets = append(targets, s.Target())
+		stops = append(stops, s.SL)
+	}
+	c.Entry, c.Target, c.SL = newSpread(entries, atr), newSpread(targets, atr), newSpread(stops, atr)
+	for _, sp := range []struct {
+		name string
+		s    *Spread
+	}{{"entry", c.Entry}, {"target", c.Target}, {"SL", c.SL}} {
+		if sp.s == nil || sp.s.Max == sp.s.Min {
+			continue
+		}
+		if (atr > 0 && sp.s.ATR > ensembleSpreadATR) || (atr == 0 && sp.s.Pct > ensembleSpreadPct) {
+			c.Disagreements = append(c.Disagreements, fmt.Sprintf("%s menyebar %.4f - %.4f (%.1f%%, %.1f ATR)", sp.name, sp.s.Min, sp.s.Max, sp.s.Pct, sp.s.ATR))
+		}
+	}
+	if c.Entry != nil && c.SL != nil && c.Target != nil {
+		if risk := math.Abs(c.Entry.Mean - c.SL.Mean); risk > 0 {
+			c.RR = math.Abs(c.Target.Mean-c.Entry.Mean) / risk
+		}
+	}
+
+	// Rata-rata probability per skenario
+	type agg struct {
+		sum, min, max float64
+		n             int
+	}
+	byName := map[string]*agg{}
+	var order []string
+	for _, m := range plans {
+		for _, sc := range m.Plan.Scenarios {
+			a, ok := byName[sc.Name]
+			if !ok {
+				a = &agg{min: sc.Probability, max: sc.Probability}
+				byName[sc.Name] = a
+				order = append(order, sc.Name)
+			}
+			a.sum += sc.Probability
+			a.min = math.Min(a.min, sc.Probability)
+			a.max = math.Max(a.max, sc.Probability)
+			a.n++
+		}
+	}
+	for _, name := range order {
+		a := byName[name]
+		// Model yang tidak menyebut skenario ini dihitung 0%
+		c.Scenarios = append(c.Scenarios, PlanScenario{Name: name, Probability: a.sum / float64(len(plans))})
+		if a.n < len(plans) {
+			a.min = 0
+		}
+		if a.max-a.min > ensembleScenarioSwing {
+			c.Disagreements = append(c.Disagreements, fmt.Sprintf("probability %s %.0f%% - %.0f%%", name, a.min, a.max))
+		}
+	}
+	return c
+}
+
+func newSpread(values []float64, atr float64) *Spread {
+	if len(values) == 0 {
+		return nil
+	}
+	s := &Spread{Min: values[0], Max: values[0]}
+	for _, v := range values {
+		s.Min, s.Max = math.Min(s.Min, v), math.Max(s.Max, v)
+		s.Mean += v
+	}
+	s.Mean /= float64(len(values))
+	if s.Mean != 0 {
+		s.Pct = (s.Max - s.Min) / s.Mean * 100
+	}
+	if atr > 0 {
+		s.ATR = (s.Max - s.Min) / atr
+	}
+	return s
+}
+
+// majority picks the direction with most votes. A tie for the top has no
+// majority and gives tie ("none" for setups, "neutral" for trends), so a
+// 1-1 long/short split never trades.
+func majority(votes map[string][]string, tie string) string {
+	best, top := tie, 0
+	for dir, names := range votes {
+		switch {
+		case len(names) > top:
+			best, top = dir, len(names)
+		case len(names) == top:
+			best = tie
+		}
+	}
+	return best
+}
+
+func formatVotes(votes map[string][]string) string {
+	var parts []string
+	for dir, names := range votes {
+		parts = append(parts, fmt.Sprintf("%s (%s)", dir, strings.Join(names, ", ")))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " vs ")
+}
+
+// ensembleRow is one line of the side-by-side comparison.
+type ensembleRow struct {
+	Label     string
+	Cells     []string
+	Consensus string
+	Differs   bool
+}
+
+func ensembleRows(res *EnsembleResult) []ensembleRow {
+	c := res.Consensus
+	price := func(v float64) string {
+		if v == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.4f", v)
+	}
+	spread := func(s *Spread) string {
+		if s == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.4f (±%.1f%%)", s.Mean, s.Pct/2)
+	}
+	probability := func(p *TradePlan, name string) string {
+		for _, sc := range p.Scenarios {
+			if sc.Name == name {
+				return fmt.Sprintf("%.0f%%", sc.Probability)
+			}
+		}
+		return "-"
+	}
+
+	type field struct {
+		label     string
+		cell      func(p *TradePlan, m EnsembleMember) string
+		consensus string
+	}
+	fields := []field{
+		{"Trend", func(p *TradePlan, _ EnsembleMember) string {
+			return fmt.Sprintf("%s %d/10", p.Trend.Direction, p.Trend.Strength)
+		}, majority(c.TrendVotes, "neutral")},
+		{"Setup", func(p *TradePlan, _ EnsembleMember) string { return p.Setup.Direction }, fmt.Sprintf("%s (%.0f%% setuju)", c.Direction, c.Agreement*100)},
+		{"Entry", func(p *TradePlan, _ EnsembleMember) string {
+			if p.Setup.Direction == "none" {
+				return "-"
+			}
+			return fmt.Sprintf("%.4f - %.4f", p.Setup.EntryLow, p.Setup.EntryHigh)
+		}, spread(c.Entry)},
+		{"Target", func(p *TradePlan, _ EnsembleMember) string { return price(p.Setup.Target()) }, spread(c.Target)},
+		{"SL", func(p *TradePlan, _ EnsembleMember) string { return price(p.Setup.SL) }, spread(c.SL)},
+		{"RR", func(p *TradePlan, _ EnsembleMember) string {
+			if p.Setup.Direction == "none" {
+				return "-"
+			}
+			return fmt.Sprintf("1:%.2f", p.Setup.ComputedRR())
+		}, fmt.Sprintf("1:%.2f", c.RR)},
+	}
+	for _, sc := range c.Scenarios {
+		name, avg := sc.Name, sc.Probability
+		fields = append(fields, field{titleCase(name) + " %", func(p *TradePlan, _ EnsembleMember) string { return probability(p, name) }, fmt.Sprintf("%.0f%%", avg)})
+	}
+	fields = append(fields,
+		field{"Setup quality", func(p *TradePlan, _ EnsembleMember) string { return fmt.Sprintf("%d/10", p.Confidence.SetupQuality) }, ""},
+		field{"Validator", func(_ *TradePlan, m EnsembleMember) string {
+			if m.Verdict == nil {
+				return "-"
+			}
+			return m.Verdict.Verdict
+		}, ""},
+	)
+
+	rows := make([]ensembleRow, 0, len(fields))
+	for _, f := range fields {
+		row := ensembleRow{Label: f.label, Consensus: f.consensus}
+		seen := map[string]bool{}
+		for _, m := range res.Members {
+			cell := "ERROR"
+			if m.Plan != nil {
+				cell = f.cell(m.Plan, m)
+				seen[cell] = true
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		row.Differs = len(seen) > 1
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// renderEnsemble is the terminal view: one column per model, a consensus
+// column, and ⚠️ on rows where the models disagree.
+func renderEnsemble(res *EnsembleResult) string {
+	var b strings.Builder
+	b.WriteString("🤝 ENSEMBLE CONSENSUS\n\n")
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	header := []string{"", ""}
+	for _, m := range res.Members {
+		header = append(header, strings.ToUpper(m.AI))
+	}
+	fmt.Fprintln(tw, strings.Join(append(header, "CONSENSUS"), "\t"))
+	for _, row := range ensembleRows(res) {
+		mark := ""
+		if row.Differs {
+			mark = "⚠️"
+		}
+		fmt.Fprintln(tw, strings.Join(append(append([]string{mark, row.Label}, row.Cells...), row.Consensus), "\t"))
+	}
+	tw.Flush()
+
+	b.WriteString("\n")
+	for _, m := range res.Members {
+		if m.Error != "" {
+			fmt.Fprintf(&b, "❌ %s: %s\n", m.AI, m.Error)
+		}
+	}
+	if len(res.Consensus.Disagreements) == 0 {
+		b.WriteString("✅ Semua model sepakat.\n")
+		return b.String()
+	}
+	b.WriteString("⚠️  PERBEDAAN:\n")
+	for _, d := range res.Consensus.Disagreements {
+		fmt.Fprintf(&b, "• %s\n", d)
+	}
+	return b.String()
+}
+
+var ensembleHTML = template.Must(template.New("ensemble").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Symbol}} {{.TF}} Ensemble</title>
+<style>
+body { font-family: sans-serif; background: #131722; color: #d1d4dc; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #2a2e39; padding: 6px 12px; text-align: left; }
+th { background: #1e222d; }
+tr.diff td { background: #3b2a1a; }
+td.consensus { font-weight: bold; color: #26a69a; }
+li { margin: 4px 0; }
+pre { white-space: pre-wrap; background: #1e222d; padding: 1em; }
+</style>
+</head>
+<body>
+<h1>{{.Symbol}} {{.TF}} – Ensemble</h1>
+<table>
+<tr><th></th>{{range .Result.Members}}<th>{{.AI}}<br><small>{{.Model}}</small></th>{{end}}<th>Consensus</th></tr>
+{{range .Rows}}<tr{{if .Differs}} class="diff"{{end}}><th>{{.Label}}</th>{{range .Cells}}<td>{{.}}</td>{{end}}<td class="consensus">{{.Consensus}}</td></tr>
+{{end}}</table>
+{{with .Result.Consensus.Disagreements}}<h2>⚠️ Perbedaan</h2>
+<ul>{{range .}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{range .Result.Members}}<h3>{{.AI}}</h3>
+{{if .Error}}<p>❌ {{.Error}}</p>{{else}}<pre>{{.Plan.KeyRisk}}
+{{range .Plan.Insights}}• {{.}}
+{{end}}</pre>{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// writeEnsembleHTML saves the side-by-side table as SYMBOL_TF_ensemble.html.
+func writeEnsembleHTML(res *EnsembleResult, symbol, tf string) (string, error) {
+	filename := fmt.Sprintf("%s_%s_ensemble.html", symbol, tf)
+	f, err := os.Create(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	data := struct {
+		Symbol, TF string
+		Result     *EnsembleResult
+		Rows       []ensembleRow
+	}{symbol, tf, res, ensembleRows(res)}
+	return filename, ensembleHTML.Execute(f, data)
+}
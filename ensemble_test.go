@@ -0,0 +1,153 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// member builds an ensemble member with a valid plan; atr 0 leaves the
+// validator out.
+func member(name, dir string, entry, sl, tp float64, atr float64, scenarios ...PlanScenario) EnsembleMember {
+	trend := map[string]string{"long": "bullish", "short": "bearish", "none": "neutral"}[dir]
+	plan := &TradePlan{
+		Trend:     PlanTrend{Direction: trend, Strength: 6},
+		Scenarios: scenarios,
+		Setup:     TradeSetup{Direction: dir, EntryLow: entry - 0.5, EntryHigh: entry + 0.5, SL: sl, TP1: tp},
+	}
+	if scenarios == nil {
+		plan.Scenarios = []PlanScenario{{Name: "bullish", Probability: 50}, {Name: "bearish", Probability: 50}}
+	}
+	m := EnsembleMember{AI: name, Plan: plan}
+	if atr > 0 {
+		m.Verdict = &SetupVerdict{ATR: atr}
+	}
+	return m
+}
+
+func TestBuildConsensus(t *testing.T) {
+	tests := []struct {
+		name          string
+		members       []EnsembleMember
+		direction     string
+		agreement     float64
+		entry         float64 // rata-rata entry searah, 0 = tidak ada spread
+		disagreements []string
+	}{
+		{
+			name:      "semua setuju",
+			members:   []EnsembleMember{member("a", "long", 100, 97, 106, 2), member("b", "long", 100.4, 97.2, 106.5, 2), member("c", "long", 99.8, 96.8, 105.8, 2)},
+			direction: "long", agreement: 1, entry: (100 + 100.4 + 99.8) / 3,
+		},
+		{
+			name:      "1-1 long short tidak ada mayoritas",
+			members:   []EnsembleMember{member("a", "long", 100, 97, 106, 2), member("b", "short", 100, 103, 94, 2)},
+			direction: "none", agreement: 0,
+			disagreements: []string{"arah setup beda: long (a) vs short (b)", "arah trend beda: bearish (b) vs bullish (a)"},
+		},
+		{
+			name:      "2 lawan 1, spread cuma dari yang searah",
+			members:   []EnsembleMember{member("a", "long", 100, 97, 106, 2), member("b", "short", 110, 113, 104, 2), member("c", "long", 101, 98, 107, 2)},
+			direction: "long", agreement: 2.0 / 3, entry: 100.5,
+			disagreements: []string{"arah setup beda: long (a, c) vs short (b)", "arah trend beda: bearish (b) vs bullish (a, c)"},
+		},
+		{
+			name:      "member tanpa plan tidak ikut voting",
+			members:   []EnsembleMember{member("a", "long", 100, 97, 106, 2), {AI: "b", Error: "HTTP 401"}, member("c", "long", 100, 97, 106, 2)},
+			direction: "long", agreement: 1, entry: 100,
+		},
+		{
+			name:      "entry menyebar lebih dari 1 ATR",
+			members:   []EnsembleMember{member("a", "long", 100, 97, 106, 2), member("b", "long", 103, 97, 106, 2)},
+			direction: "long", agreement: 1, entry: 101.5,
+			disagreements: []string{"entry menyebar 100.0000 - 103.0000 (3.0%, 1.5 ATR)"},
+		},
+		{
+			name:      "entry dalam 1 ATR",
+			members:   []EnsembleMember{member("a", "long", 100, 97, 106, 4), member("b", "long", 103, 97, 106, 4)},
+			direction: "long", agreement: 1, entry: 101.5,
+		},
+		{
+			name:      "tanpa ATR pakai persen",
+			members:   []EnsembleMember{member("a", "long", 100, 97, 106, 0), member("b", "long", 101.5, 97, 109, 0)},
+			direction: "long", agreement: 1, entry: 100.75,
+			disagreements: []string{"target menyebar 106.0000 - 109.0000 (2.8%, 0.0 ATR)"},
+		},
+		{
+			name:          "tidak ada plan sama sekali",
+			members:       []EnsembleMember{{AI: "a", Error: "timeout"}},
+			direction:     "none",
+			disagreements: []string{"tidak ada model yang memberi plan valid"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := buildConsensus(tt.members)
+			if c.Direction != tt.direction || math.Abs(c.Agreement-tt.agreement) > 1e-9 {
+				t.Errorf("arah %s setuju %.2f, mau %s %.2f", c.Direction, c.Agreement, tt.direction, tt.agreement)
+			}
+			switch {
+			case tt.entry == 0 && c.Entry != nil:
+				t.Errorf("spread entry %+v padahal tidak ada arah", c.Entry)
+			case tt.entry != 0 && (c.Entry == nil || math.Abs(c.Entry.Mean-tt.entry) > 1e-9):
+				t.Errorf("entry %+v, mau rata-rata %.4f", c.Entry, tt.entry)
+			}
+			if !reflect.DeepEqual(c.Disagreements, tt.disagreements) {
+				t.Errorf("disagreements:\n got %q\nwant %q", c.Disagreements, tt.disagreements)
+			}
+		})
+	}
+}
+
+func TestBuildConsensusScenarios(t *testing.T) {
+	a := member("a", "long", 100, 97, 106, 2, PlanScenario{Name: "bullish", Probability: 60}, PlanScenario{Name: "neutral", Probability: 40})
+	b := member("b", "long", 100, 97, 106, 2, PlanScenario{Name: "bullish", Probability: 60}, PlanScenario{Name: "neutral", Probability: 10}, PlanScenario{Name: "bearish", Probability: 30})
+	c := buildConsensus([]EnsembleMember{a, b})
+
+	// bearish tidak disebut a: dihitung 0%, jadi rata-rata 15 dan selisih 30
+	want := []PlanScenario{{Name: "bullish", Probability: 60}, {Name: "neutral", Probability: 25}, {Name: "bearish", Probability: 15}}
+	if !reflect.DeepEqual(c.Scenarios, want) {
+		t.Errorf("skenario %+v, mau %+v", c.Scenarios, want)
+	}
+	wantDis := []string{"probability neutral 10% - 40%", "probability bearish 0% - 30%"}
+	if !reflect.DeepEqual(c.Disagreements, wantDis) {
+		t.Errorf("disagreements %q, mau %q", c.Disagreements, wantDis)
+	}
+
+	// RR dari rata-rata entry, target dan SL
+	if math.Abs(c.RR-2) > 1e-9 {
+		t.Errorf("RR %.4f, mau 2", c.RR)
+	}
+}
+
+func TestMajority(t *testing.T) {
+	tests := []struct {
+		votes map[string][]string
+		tie   string
+		want  string
+	}{
+		{map[string][]string{"long": {"a", "b"}, "short": {"c"}}, "none", "long"},
+		{map[string][]string{"long": {"a"}, "short": {"b"}}, "none", "none"},
+		{map[string][]string{"long": {"a"}, "none": {"b"}}, "none", "none"},
+		{map[string][]string{"short": {"a"}, "none": {"b", "c"}}, "none", "none"},
+		{map[string][]string{"long": {"a"}, "short": {"b"}, "none": {"c", "d"}}, "none", "none"},
+		{map[string][]string{"long": {"a"}, "short": {"b", "c", "d"}, "none": {"e"}}, "none", "short"},
+		{map[string][]string{"bullish": {"a"}, "bearish": {"b"}}, "neutral", "neutral"},
+		{map[string][]string{}, "none", "none"},
+	}
+	for _, tt := range tests {
+		if got := majority(tt.votes, tt.tie); got != tt.want {
+			t.Errorf("majority(%v) = %q, mau %q", tt.votes, got, tt.want)
+		}
+	}
+	// Urutan map tidak boleh mengubah hasil seri
+	for i := 0; i < 20; i++ {
+		if got := majority(map[string][]string{"long": {"a", "b"}, "short": {"c", "d"}, "none": {"e"}}, "none"); got != "none" {
+			t.Fatalf("seri 2-2 = %q", got)
+		}
+	}
+	if !strings.Contains(formatVotes(map[string][]string{"short": {"b"}, "long": {"a", "c"}}), "long (a, c) vs short (b)") {
+		t.Error("formatVotes tidak urut")
+	}
+}
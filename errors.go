@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ================================
+// MARKET DATA ERRORS
+// ================================
+
+// Error kinds for market data requests. Check with errors.Is.
+var (
+	ErrInvalidSymbol = errors.New("invalid symbol")
+	ErrRateLimited   = errors.New("rate limited")
+	ErrBadPayload    = errors.New("bad payload")
+	ErrNetwork       = errors.New("network error")
+)
+
+// MarketError is returned by providers when a request fails. Kind is one of
+// the Err* values above; Code and Msg carry the venue's own error, e.g.
+// Binance {"code":-1121,"msg":"Invalid symbol."}.
+type MarketError struct {
+	Kind       error
+	Provider   string
+	Status     int
+	Code       int
+	Msg        string
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *MarketError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %v", e.Provider, e.Kind)
+	if e.Status != 0 {
+		fmt.Fprintf(&b, " (HTTP %d)", e.Status)
+	}
+	if e.Msg != "" {
+		if e.Code != 0 {
+			fmt.Fprintf(&b, ": [%d] %s", e.Code, e.Msg)
+		} else {
+			fmt.Fprintf(&b, ": %s", e.Msg)
+		}
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	return b.String()
+}
+
+func (e *MarketError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+func (e *MarketError) retryable() bool {
+	return errors.Is(e.Kind, ErrRateLimited) || errors.Is(e.Kind, ErrNetwork)
+}
+
+// statusError maps an HTTP status to an error kind. 418 is Binance's IP ban
+// after ignoring 429s, so it is treated as rate limiting with its Retry-After.
+func statusError(provider string, resp *http.Response) *MarketError {
+	e := &MarketError{Kind: ErrBadPayload, Provider: provider, Status: resp.StatusCode}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusTeapot:
+		e.Kind = ErrRateLimited
+		e.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	case resp.StatusCode >= 500:
+		e.Kind = ErrNetwork
+	}
+	return e
+}
+
+// parseRetryAfter reads either delay-seconds or an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// venueErrorKind classifies the error codes each venue puts in its body.
+func venueErrorKind(provider string, code int, msg string) error {
+	switch {
+	case strings.HasPrefix(provider, "binance"):
+		// https://developers.binance.com/docs/binance-spot-api-docs/errors
+		switch code {
+		case -1121, -1122:
+			return ErrInvalidSymbol
+		case -1003, -1015:
+			return ErrRateLimited
+		case -1000, -1001, -1007:
+			return ErrNetwork
+		}
+	case strings.HasPrefix(provider, "bybit"):
+		switch {
+		case code == 10006 || code == 10018:
+			return ErrRateLimited
+		case code == 10000 || code == 10016:
+			return ErrNetwork
+		case code == 10001 && strings.Contains(strings.ToLower(msg), "symbol"):
+			return ErrInvalidSymbol
+		}
+	case strings.HasPrefix(provider, "okx"):
+		switch code {
+		case 51001:
+			return ErrInvalidSymbol
+		case 50011, 50061:
+			return ErrRateLimited
+		case 50001, 50013:
+			return ErrNetwork
+		}
+	}
+	return ErrBadPayload
+}
+
+// parseVenueError pulls the code and message out of an error body. Binance
+// uses {"code":-1121,"msg":...}, OKX a string code and Bybit retCode/retMsg.
+func parseVenueError(body []byte) (int, string) {
+	var v struct {
+		Code    json.RawMessage `json:"code"`
+		Msg     string          `json:"msg"`
+		RetCode int             `json:"retCode"`
+		RetMsg  string          `json:"retMsg"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return 0, ""
+	}
+	if v.RetCode != 0 || v.RetMsg != "" {
+		return v.RetCode, v.RetMsg
+	}
+	code, _ := strconv.Atoi(strings.Trim(string(v.Code), `"`))
+	return code, v.Msg
+}
+
+// venueError builds a MarketError for a non-zero code found in a 200 body.
+func venueError(provider string, code int, msg string) *MarketError {
+	return &MarketError{Kind: venueErrorKind(provider, code, msg), Provider: provider, Status: http.StatusOK, Code: code, Msg: msg}
+}
+
+const (
+	maxRetries   = 4
+	maxRetryWait = 2 * time.Minute
+)
+
+// withRetry runs fn again for rate limit and network errors, using the
+// server's Retry-After when given and exponential backoff with jitter
+// otherwise. Waits longer than maxRetryWait (e.g. a long 418 ban) are not
+// slept through; the error is returned instead.
+func withRetry(fn func() error) error {
+	backoff := 500 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		var me *MarketError
+		if err == nil || !errors.As(err, &me) || !me.retryable() || attempt >= maxRetries {
+			return err
+		}
+
+		wait := me.RetryAfter
+		if wait <= 0 {
+			wait = backoff + time.Duration(rand.Int63n(int64(backoff/2)))
+			backoff *= 2
+		}
+		if wait > maxRetryWait {
+			return err
+		}
+		retrySleep(wait)
+	}
+}
+
+// retrySleep is time.Sleep; tests swap it to check the waits.
+var retrySleep = time.Sleep
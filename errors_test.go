@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recordSleeps swaps retrySleep for the duration of the test.
+func recordSleeps(t *testing.T) *[]time.Duration {
+	var sleeps []time.Duration
+	orig := retrySleep
+	retrySleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	t.Cleanup(func() { retrySleep = orig })
+	return &sleeps
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"7", 7 * time.Second},
+		{" 120 ", 2 * time.Minute},
+		{"0", 0},
+		{"besok", 0},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", time.Until(time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC))},
+	}
+	for _, tt := range tests {
+		got := parseRetryAfter(tt.in)
+		if diff := got - tt.want; diff < -time.Second || diff > time.Second {
+			t.Errorf("parseRetryAfter(%q) = %s, mau %s", tt.in, got, tt.want)
+		}
+	}
+
+	// HTTP-date di masa depan: sisa waktu sampai tanggal itu
+	at := time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(at); got <= 88*time.Second || got > 90*time.Second {
+		t.Errorf("parseRetryAfter(%q) = %s, mau sekitar 90s", at, got)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		status     int
+		retryAfter string
+		kind       error
+		wait       time.Duration
+	}{
+		{http.StatusTooManyRequests, "3", ErrRateLimited, 3 * time.Second},
+		{http.StatusTeapot, "120", ErrRateLimited, 2 * time.Minute},
+		{http.StatusServiceUnavailable, "", ErrNetwork, 0},
+		{http.StatusBadGateway, "5", ErrNetwork, 0},
+		{http.StatusBadRequest, "", ErrBadPayload, 0},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		if tt.retryAfter != "" {
+			resp.Header.Set("Retry-After", tt.retryAfter)
+		}
+		e := statusError("binance", resp)
+		if !errors.Is(e, tt.kind) || e.RetryAfter != tt.wait || e.Status != tt.status {
+			t.Errorf("HTTP %d: %v retry-after %s, mau %v %s", tt.status, e, e.RetryAfter, tt.kind, tt.wait)
+		}
+	}
+}
+
+func TestVenueErrorKind(t *testing.T) {
+	tests := []struct {
+		provider string
+		code     int
+		msg      string
+		kind     error
+	}{
+		{"binance", -1121, "Invalid symbol.", ErrInvalidSymbol},
+		{"binance-futures", -1003, "Too many requests", ErrRateLimited},
+		{"binance", -1001, "Internal error", ErrNetwork},
+		{"binance", -1100, "Illegal characters", ErrBadPayload},
+		{"bybit", 10006, "Too many visits", ErrRateLimited},
+		{"bybit-linear", 10001, "params error: symbol invalid", ErrInvalidSymbol},
+		{"bybit", 10001, "params error: interval invalid", ErrBadPayload},
+		{"bybit", 10016, "server error", ErrNetwork},
+		{"okx", 51001, "Instrument ID does not exist", ErrInvalidSymbol},
+		{"okx-swap", 50011, "Rate limit reached", ErrRateLimited},
+		{"okx", 50001, "Service temporarily unavailable", ErrNetwork},
+		{"file", -1121, "", ErrBadPayload},
+	}
+	for _, tt := range tests {
+		if got := venueErrorKind(tt.provider, tt.code, tt.msg); got != tt.kind {
+			t.Errorf("venueErrorKind(%s, %d, %q) = %v, mau %v", tt.provider, tt.code, tt.msg, got, tt.kind)
+		}
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	rateLimited := func(wait time.Duration) error {
+		return &MarketError{Kind: ErrRateLimited, Provider: "binance", Status: 429, RetryAfter: wait}
+	}
+	network := &MarketError{Kind: ErrNetwork, Provider: "binance", Status: 503}
+	other := errors.New("bukan MarketError")
+
+	tests := []struct {
+		name   string
+		errs   []error // per attempt, sisanya sukses
+		calls  int
+		sleeps []time.Duration // -1 = backoff dengan jitter
+		err    error
+	}{
+		{name: "langsung sukses", calls: 1},
+		{name: "Retry-After dipakai apa adanya", errs: []error{rateLimited(3 * time.Second), rateLimited(time.Second)}, calls: 3, sleeps: []time.Duration{3 * time.Second, time.Second}},
+		{name: "tanpa Retry-After pakai backoff", errs: []error{network, network}, calls: 3, sleeps: []time.Duration{-1, -1}},
+		{name: "lebih dari maxRetryWait tidak ditunggu", errs: []error{rateLimited(maxRetryWait + time.Second)}, calls: 1, err: ErrRateLimited},
+		{name: "tepat maxRetryWait masih ditunggu", errs: []error{rateLimited(maxRetryWait)}, calls: 2, sleeps: []time.Duration{maxRetryWait}},
+		{name: "symbol salah tidak diulang", errs: []error{&MarketError{Kind: ErrInvalidSymbol}}, calls: 1, err: ErrInvalidSymbol},
+		{name: "error lain tidak diulang", errs: []error{other}, calls: 1, err: other},
+		{
+			name:   "menyerah setelah maxRetries",
+			errs:   []error{network, network, network, network, network, network},
+			calls:  maxRetries + 1,
+			sleeps: []time.Duration{-1, -1, -1, -1},
+			err:    ErrNetwork,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sleeps := recordSleeps(t)
+			calls := 0
+			err := withRetry(func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if calls != tt.calls || !errors.Is(err, tt.err) {
+				t.Fatalf("%d call, err %v; mau %d call, err %v", calls, err, tt.calls, tt.err)
+			}
+			if len(*sleeps) != len(tt.sleeps) {
+				t.Fatalf("tidur %v, mau %d kali", *sleeps, len(tt.sleeps))
+			}
+			backoff := 500 * time.Millisecond
+			for i, want := range tt.sleeps {
+				got := (*sleeps)[i]
+				if want < 0 {
+					// backoff + jitter sampai setengahnya, dobel tiap kali
+					if got < backoff || got >= backoff*3/2 {
+						t.Errorf("tidur ke-%d %s, mau %s - %s", i+1, got, backoff, backoff*3/2)
+					}
+					backoff *= 2
+					continue
+				}
+				if got != want {
+					t.Errorf("tidur ke-%d %s, mau %s", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestTeapotIsRateLimit runs a Binance 418 ban with Retry-After through a
+// real provider request.
+func TestTeapotIsRateLimit(t *testing.T) {
+	sleeps := recordSleeps(t)
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= 2 {
+			w.Header().Set("Retry-After", "4")
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte(`{"code":-1003,"msg":"Way too many requests; IP banned."}`))
+			return
+		}
+		w.Write([]byte(`[[1709251200000,"140","141","139","140.5","10",1709254799999,"0",1,"0","0","0"]]`))
+	}))
+	defer srv.Close()
+
+	provider, err := newProvider("binance", srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	candles, err := provider.Candles("SOLUSDT", "1h", time.Time{}, time.Time{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 1 || requests != 3 || !reflect.DeepEqual(*sleeps, []time.Duration{4 * time.Second, 4 * time.Second}) {
+		t.Errorf("%d candle setelah %d request, tidur %v", len(candles), requests, *sleeps)
+	}
+
+	// Ban lebih lama dari maxRetryWait langsung dikembalikan
+	*sleeps, requests = nil, 0
+	ban := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "600")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer ban.Close()
+	provider, _ = newProvider("binance", ban.URL, "")
+	_, err = provider.Candles("SOLUSDT", "1h", time.Time{}, time.Time{}, 1)
+	var me *MarketError
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &me) || me.RetryAfter != 10*time.Minute || requests != 1 || len(*sleeps) != 0 {
+		t.Errorf("ban 10 menit: err %v, %d request, tidur %v", err, requests, *sleeps)
+	}
+}
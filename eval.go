@@ -0,0 +1,551 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ================================
+// PATTERN EVALUATION & CALIBRATION
+// ================================
+
+// patternEvent is one fresh detection and the move after it. Returns are
+// in percent, signed so that positive means the pattern was right.
+type patternEvent struct {
+	Symbol     string
+	TF         string
+	Pattern    string
+	Type       string
+	Time       time.Time
+	Confidence float64
+	Returns    map[int]float64
+	Detector   string // patternConfig.hash() yang mendeteksi
+}
+
+// PatternStat is the forward performance of one pattern on one timeframe;
+// TF "*" pools every timeframe.
+type PatternStat struct {
+	Pattern  string        `json:"pattern"`
+	Type     string        `json:"type"`
+	TF       string        `json:"timeframe"`
+	Samples  int           `json:"samples"`
+	Horizons []HorizonStat `json:"horizons"`
+}
+
+type HorizonStat struct {
+	Bars      int     `json:"bars"`
+	HitRate   float64 `json:"hit_rate_pct"`
+	AvgReturn float64 `json:"avg_return_pct"`
+}
+
+type PatternEvalReport struct {
+	Symbols     []string            `json:"symbols"`
+	TFs         []string            `json:"timeframes"`
+	Horizons    []int               `json:"horizons"`
+	Events      int                 `json:"events"`
+	Stats       []PatternStat       `json:"stats"`
+	Calibration *patternCalibration `json:"calibration"`
+}
+
+// patternCalibration maps a detector's confidence to the hit rate seen in
+// history at Horizon candles. Entries are keyed by pattern name, then by
+// timeframe ("*" = all timeframes pooled). Each entry records the detector
+// config it was measured with and only calibrates detections made with the
+// same thresholds.
+type patternCalibration struct {
+	Horizon    int                                     `json:"horizon"`
+	MinSamples int                                     `json:"min_samples"`
+	Created    time.Time                               `json:"created"`
+	Source     string                                  `json:"source"`
+	Patterns   map[string]map[string]*calibrationEntry `json:"patterns"`
+}
+
+type calibrationEntry struct {
+	Detector    string          `json:"detector,omitempty"` // patternConfig.hash(), mixedDetector kalau gabungan beberapa config
+	Samples     int             `json:"samples"`
+	Hits        int             `json:"hits"`
+	Probability float64         `json:"probability"`
+	Bins        []confidenceBin `json:"bins"`
+}
+
+// mixedDetector marks an entry pooled from detections with different
+// configs; it matches no config.
+const mixedDetector = "mixed"
+
+// hash identifies the thresholds in calibration entries.
+func (c patternConfig) hash() string {
+	return shortHash(c)
+}
+
+// id identifies a calibration file in cache keys; "none" without one.
+func (c *patternCalibration) id() string {
+	if c == nil {
+		return "none"
+	}
+	return shortHash(c)
+}
+
+func shortHash(v any) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
+
+// matches reports whether e was measured with the config that hashes to
+// detector. Files from before the field was added were made with the
+// defaults.
+func (e *calibrationEntry) matches(detector string) bool {
+	if e.Detector == "" {
+		return detector == defaultDetectorConfig.Patterns.hash()
+	}
+	return e.Detector == detector
+}
+
+// confidenceBin is the hit rate of detections with From <= confidence < To.
+type confidenceBin struct {
+	From        float64 `json:"from"`
+	To          float64 `json:"to"`
+	Samples     int     `json:"samples"`
+	Hits        int     `json:"hits"`
+	Probability float64 `json:"probability"`
+}
+
+const confidenceBinWidth = 0.1
+
+func defaultCalibrationFile() string {
+	if v := os.Getenv("PATTERN_CALIBRATION"); v != "" {
+		return v
+	}
+	return filepath.Join(filepath.Dir(defaultUsageLedger()), "calibration.json")
+}
+
+// loadCalibration reads path; a missing file means no calibration.
+func loadCalibration(path string) (*patternCalibration, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c patternCalibration
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// apply sets the empirical probability on each pattern. It prefers the
+// confidence bin on this timeframe and falls back to the whole timeframe,
+// then to all timeframes pooled, skipping anything with too few samples or
+// measured with other thresholds than cfg, the config patterns came from.
+func (c *patternCalibration) apply(patterns []Pattern, tf string, cfg patternConfig) {
+	if c == nil {
+		return
+	}
+	detector := cfg.hash()
+	for i := range patterns {
+		p := &patterns[i]
+		if prob, n, ok := c.lookup(p.Name, p.Confidence, tf, detector); ok {
+			p.Probability, p.Samples, p.Horizon = prob, n, c.Horizon
+		}
+	}
+}
+
+func (c *patternCalibration) lookup(name string, confidence float64, tf, detector string) (float64, int, bool) {
+	byTF := c.Patterns[name]
+	for _, key := range []string{tf, "*"} {
+		e := byTF[key]
+		if e == nil || !e.matches(detector) {
+			continue
+		}
+		for _, b := range e.Bins {
+			if confidence >= b.From && confidence < b.To && b.Samples >= c.MinSamples {
+				return b.Probability, b.Samples, true
+			}
+		}
+		if e.Samples >= c.MinSamples {
+			return e.Probability, e.Samples, true
+		}
+	}
+	return 0, 0, false
+}
+
+// describe is the short form used in terminal output and prompts.
+func (p Pattern) describe() string {
+	if p.Samples == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.0f%% dalam %d candle (n=%d)", p.Probability*100, p.Horizon, p.Samples)
+}
+
+// patternDirection is +1 when the pattern calls for higher prices and -1
+// for lower. Continuation patterns follow the move into the pattern.
+func patternDirection(p Pattern, window []Candle) float64 {
+	switch p.Type {
+	case "bullish":
+		return 1
+	case "bearish":
+		return -1
+	}
+	if window[len(window)-1].Close.LessThan(window[0].Close) {
+		return -1
+	}
+	return 1
+}
+
+// collectPatternEvents slides the detectors over candles one bar at a time,
+// exactly as live analysis would see them, and records every pattern the
+// bar it first appears. A pattern still detected on the next bar is the
+// same setup and is not counted again. Bars too close to the end to have
+// every horizon are skipped.
+func collectPatternEvents(candles []Candle, symbol, tf string, horizons []int, cfg patternConfig) []patternEvent {
+	maxH := horizons[len(horizons)-1]
+	patternWindow := cfg.window()
+	detector := cfg.hash()
+	var events []patternEvent
+	prev := map[string]bool{}
+	for i := patternWindow - 1; i+maxH < len(candles); i++ {
+		window := candles[i+1-patternWindow : i+1]
+		entry := window[len(window)-1].Close.InexactFloat64()
+		cur := map[string]bool{}
+		for _, p := range detectPatterns(window, cfg) {
+			cur[p.Name] = true
+			if prev[p.Name] || entry <= 0 {
+				continue
+			}
+			dir := patternDirection(p, window)
+			ev := patternEvent{Symbol: symbol, TF: tf, Pattern: p.Name, Type: p.Type, Time: window[len(window)-1].Time, Confidence: p.Confidence, Returns: map[int]float64{}, Detector: detector}
+			for _, h := range horizons {
+				ev.Returns[h] = dir * (candles[i+h].Close.InexactFloat64()/entry - 1) * 100
+			}
+			events = append(events, ev)
+		}
+		prev = cur
+	}
+	return events
+}
+
+// patternStats groups events by pattern and timeframe, plus "*" per pattern.
+func patternStats(events []patternEvent, horizons []int) []PatternStat {
+	groups := map[[2]string][]patternEvent{}
+	types := map[string]string{}
+	for _, ev := range events {
+		groups[[2]string{ev.Pattern, ev.TF}] = append(groups[[2]string{ev.Pattern, ev.TF}], ev)
+		groups[[2]string{ev.Pattern, "*"}] = append(groups[[2]string{ev.Pattern, "*"}], ev)
+		types[ev.Pattern] = ev.Type
+	}
+
+	var stats []PatternStat
+	for key, evs := range groups {
+		st := PatternStat{Pattern: key[0], Type: types[key[0]], TF: key[1], Samples: len(evs)}
+		for _, h := range horizons {
+			hits, sum := 0, 0.0
+			for _, ev := range evs {
+				if ev.Returns[h] > 0 {
+					hits++
+				}
+				sum += ev.Returns[h]
+			}
+			st.Horizons = append(st.Horizons, HorizonStat{Bars: h, HitRate: float64(hits) / float64(len(evs)) * 100, AvgReturn: sum / float64(len(evs))})
+		}
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].TF != stats[j].TF {
+			return tfOrder(stats[i].TF) < tfOrder(stats[j].TF)
+		}
+		if stats[i].Samples != stats[j].Samples {
+			return stats[i].Samples > stats[j].Samples
+		}
+		return stats[i].Pattern < stats[j].Pattern
+	})
+	return stats
+}
+
+// tfOrder sorts timeframes by length with "*" last.
+func tfOrder(tf string) time.Duration {
+	if iv, err := ParseInterval(tf); err == nil {
+		return iv.Duration()
+	}
+	return math.MaxInt64
+}
+
+// calibrate turns the events into hit rates per confidence bin at horizon.
+// Probabilities use Laplace smoothing, (hits+1)/(n+2), so a bin with a
+// handful of samples is pulled towards 50% instead of reading 0 or 100%.
+func calibrate(events []patternEvent, horizon, minSamples int, source string) *patternCalibration {
+	c := &patternCalibration{Horizon: horizon, MinSamples: minSamples, Created: time.Now(), Source: source, Patterns: map[string]map[string]*calibrationEntry{}}
+	add := func(name, tf string, conf float64, hit bool, detector string) {
+		if c.Patterns[name] == nil {
+			c.Patterns[name] = map[string]*calibrationEntry{}
+		}
+		e := c.Patterns[name][tf]
+		if e == nil {
+			e = &calibrationEntry{Detector: detector}
+			c.Patterns[name][tf] = e
+		}
+		if e.Detector != detector {
+			e.Detector = mixedDetector
+		}
+		bin := math.Floor(conf/confidenceBinWidth) * confidenceBinWidth
+		idx := -1
+		for i, b := range e.Bins {
+			if math.Abs(b.From-bin) < 1e-9 {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			e.Bins = append(e.Bins, confidenceBin{From: round4(bin), To: round4(bin + confidenceBinWidth)})
+			idx = len(e.Bins) - 1
+		}
+		e.Samples++
+		e.Bins[idx].Samples++
+		if hit {
+			e.Hits++
+			e.Bins[idx].Hits++
+		}
+	}
+	for _, ev := range events {
+		hit := ev.Returns[horizon] > 0
+		add(ev.Pattern, ev.TF, ev.Confidence, hit, ev.Detector)
+		add(ev.Pattern, "*", ev.Confidence, hit, ev.Detector)
+	}
+	for _, byTF := range c.Patterns {
+		for _, e := range byTF {
+			e.Probability = laplace(e.Hits, e.Samples)
+			sort.Slice(e.Bins, func(i, j int) bool { return e.Bins[i].From < e.Bins[j].From })
+			for i := range e.Bins {
+				e.Bins[i].Probability = laplace(e.Bins[i].Hits, e.Bins[i].Samples)
+			}
+		}
+	}
+	return c
+}
+
+func laplace(hits, n int) float64 {
+	return round4(float64(hits+1) / float64(n+2))
+}
+
+func parseIntList(s string) ([]int, error) {
+	var out []int
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("%q bukan angka positif", part)
+		}
+		out = append(out, v)
+	}
+	sort.Ints(out)
+	return out, nil
+}
+
+type evalOptions struct {
+	cliOptions
+	Symbols    []string
+	Horizons   []int
+	Horizon    int
+	MinSamples int
+	Save       bool
+}
+
+func parseEvalFlags(args []string) (evalOptions, error) {
+	var opts evalOptions
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	symbols := fs.String("symbols", "", "symbol dipisah koma, contoh: SOL,BTC,ETH")
+	finish := dataFlags(fs, &opts.cliOptions)
+	tfs := fs.String("tfs", "", "timeframe tambahan yang dievaluasi, mis. 1h,4h,1d (--tf selalu ikut)")
+	horizons := fs.String("horizons", "5,10,20", "forward return setelah N candle, pisah koma")
+	fs.IntVar(&opts.Horizon, "horizon", 10, "horizon (candle) untuk kalibrasi confidence")
+	fs.IntVar(&opts.MinSamples, "min-samples", 20, "sampel minimal sebelum probabilitas kalibrasi dipakai")
+	fs.BoolVar(&opts.Save, "save", false, "simpan kalibrasi ke --calibration, dipakai di report dan prompt")
+	fs.StringVar(&opts.Out, "out", "", "tulis hasil evaluasi JSON ke file ini")
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("argumen tidak dikenal: %s", strings.Join(fs.Args(), " "))
+	}
+	if err := finish(); err != nil {
+		return opts, err
+	}
+
+	for _, s := range strings.Split(*symbols, ",") {
+		if strings.TrimSpace(s) != "" {
+			opts.Symbols = append(opts.Symbols, normalizeSymbol(s))
+		}
+	}
+	if len(opts.Symbols) == 0 {
+		return opts, errors.New("eval butuh --symbols")
+	}
+	opts.TFs = []string{opts.TF}
+	if *tfs != "" {
+		var err error
+		if opts.TFs, err = parseTimeframes(*tfs, opts.TF); err != nil {
+			return opts, err
+		}
+	}
+	var err error
+	if opts.Horizons, err = parseIntList(*horizons); err != nil {
+		return opts, fmt.Errorf("--horizons: %w", err)
+	}
+	if opts.Horizon <= 0 || opts.MinSamples <= 0 {
+		return opts, errors.New("--horizon dan --min-samples harus lebih dari 0")
+	}
+	if !containsInt(opts.Horizons, opts.Horizon) {
+		opts.Horizons = append(opts.Horizons, opts.Horizon)
+		sort.Ints(opts.Horizons)
+	}
+	if opts.Save && opts.Calibration == "" {
+		return opts, errors.New("--save butuh --calibration")
+	}
+	return opts, nil
+}
+
+func containsInt(vs []int, v int) bool {
+	for _, x := range vs {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// runEval measures every detector over the requested history and, with
+// --save, writes the calibration that analyze, scan and watch pick up.
+func runEval(args []string) error {
+	opts, err := parseEvalFlags(args)
+	if err != nil {
+		return err
+	}
+	provider, err := openProvider(opts.cliOptions)
+	if err != nil {
+		return err
+	}
+
+	var events []patternEvent
+	for _, symbol := range opts.Symbols {
+		for _, tf := range opts.TFs {
+			var candles []Candle
+			if opts.From.IsZero() {
+				candles, err = fetchLatest(provider, symbol, tf, opts.Candles)
+			} else {
+				candles, err = backfillCandles(provider, symbol, tf, opts.From, opts.To)
+			}
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", symbol, tf, err)
+			}
+			candles = closedOnly(candles, time.Now())
+			evs := collectPatternEvents(candles, symbol, tf, opts.Horizons, opts.params.config(symbol, tf).Patterns)
+			fmt.Printf("🔬 %s %s: %d candle, %d deteksi\n", symbol, tf, len(candles), len(evs))
+			events = append(events, evs...)
+		}
+	}
+	if len(events) == 0 {
+		return errors.New("tidak ada pattern terdeteksi, tambah history dengan --from atau --candles")
+	}
+
+	source := fmt.Sprintf("%s %s", strings.Join(opts.Symbols, ","), strings.Join(opts.TFs, ","))
+	if !opts.From.IsZero() {
+		source += " dari " + opts.From.Format("2006-01-02")
+	}
+	report := PatternEvalReport{
+		Symbols: opts.Symbols, TFs: opts.TFs, Horizons: opts.Horizons, Events: len(events),
+		Stats:       patternStats(events, opts.Horizons),
+		Calibration: calibrate(events, opts.Horizon, opts.MinSamples, source),
+	}
+	printPatternEval(report)
+
+	if opts.Save {
+		if err := writeJSONFile(opts.Calibration, report.Calibration); err != nil {
+			return fmt.Errorf("simpan kalibrasi: %w", err)
+		}
+		fmt.Printf("✅ Kalibrasi disimpan → %s (dipakai analyze, scan dan watch selama parameter detector sama)\n", opts.Calibration)
+	}
+	if opts.Out != "" {
+		if err := writeJSONFile(opts.Out, report); err != nil {
+			return fmt.Errorf("tulis hasil eval: %w", err)
+		}
+		fmt.Printf("✅ Hasil evaluasi disimpan → %s\n", opts.Out)
+	}
+	return nil
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func printPatternEval(r PatternEvalReport) {
+	fmt.Printf("\n📐 FORWARD RETURN PER PATTERN (%d deteksi, return searah pattern)\n\n", r.Events)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := "PATTERN\tTF\tN\t"
+	for _, h := range r.Horizons {
+		header += fmt.Sprintf("HIT %d\tAVG %d\t", h, h)
+	}
+	fmt.Fprintln(tw, header)
+	for _, st := range r.Stats {
+		row := fmt.Sprintf("%s\t%s\t%d\t", st.Pattern, st.TF, st.Samples)
+		for _, h := range st.Horizons {
+			row += fmt.Sprintf("%.0f%%\t%+.2f%%\t", h.HitRate, h.AvgReturn)
+		}
+		fmt.Fprintln(tw, row)
+	}
+	tw.Flush()
+
+	c := r.Calibration
+	fmt.Printf("\n🎯 KALIBRASI CONFIDENCE → PROBABILITAS (%d candle, bin dengan < %d sampel tidak dipakai)\n\n", c.Horizon, c.MinSamples)
+	names := make([]string, 0, len(c.Patterns))
+	for name := range c.Patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATTERN\tTF\tTOTAL\tPER CONFIDENCE")
+	for _, name := range names {
+		tfNames := make([]string, 0, len(c.Patterns[name]))
+		for tf := range c.Patterns[name] {
+			tfNames = append(tfNames, tf)
+		}
+		sort.Slice(tfNames, func(i, j int) bool { return tfOrder(tfNames[i]) < tfOrder(tfNames[j]) })
+		for _, tf := range tfNames {
+			e := c.Patterns[name][tf]
+			var bins []string
+			for _, b := range e.Bins {
+				mark := ""
+				if b.Samples < c.MinSamples {
+					mark = "*"
+				}
+				bins = append(bins, fmt.Sprintf("%.0f-%.0f%%: %.0f%% (n=%d)%s", b.From*100, b.To*100, b.Probability*100, b.Samples, mark))
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%.0f%% (n=%d)\t%s\n", name, tf, e.Probability*100, e.Samples, strings.Join(bins, ", "))
+		}
+	}
+	tw.Flush()
+	fmt.Println("\n* = sampel kurang, pakai total timeframe / semua timeframe")
+}
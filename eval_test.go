@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestCalibrationSkipsOtherDetectorConfig(t *testing.T) {
+	tuned := defaultDetectorConfig.Patterns
+	tuned.HSWindow = 40
+	def, tun := defaultDetectorConfig.Patterns.hash(), tuned.hash()
+
+	events := func(tf, detector string, n, hits int) []patternEvent {
+		evs := make([]patternEvent, n)
+		for i := range evs {
+			ret := -1.0
+			if i < hits {
+				ret = 1
+			}
+			evs[i] = patternEvent{Pattern: "Double Bottom", TF: tf, Confidence: 0.75, Returns: map[int]float64{10: ret}, Detector: detector}
+		}
+		return evs
+	}
+	// 1h dari config default, 4h dari config hasil tune: pooled "*" jadi campuran
+	all := append(events("1h", def, 20, 15), events("4h", tun, 20, 5)...)
+	c := calibrate(all, 10, 5, "test")
+	if got := c.Patterns["Double Bottom"]["*"].Detector; got != mixedDetector {
+		t.Fatalf("entry * detector = %q, mau %q", got, mixedDetector)
+	}
+
+	tests := []struct {
+		name string
+		tf   string
+		cfg  patternConfig
+		want float64 // 0 = tidak dikalibrasi
+	}{
+		{"1h default", "1h", defaultDetectorConfig.Patterns, laplace(15, 20)},
+		{"4h tuned", "4h", tuned, laplace(5, 20)},
+		{"1h dengan config lain", "1h", tuned, 0},
+		{"tf tanpa entry tidak jatuh ke pooled campuran", "1d", defaultDetectorConfig.Patterns, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patterns := []Pattern{{Name: "Double Bottom", Confidence: 0.75}}
+			c.apply(patterns, tt.tf, tt.cfg)
+			if patterns[0].Probability != tt.want {
+				t.Errorf("probability = %v, mau %v", patterns[0].Probability, tt.want)
+			}
+		})
+	}
+
+	// File lama tanpa field detector dibuat dengan config default
+	legacy := &patternCalibration{MinSamples: 5, Patterns: map[string]map[string]*calibrationEntry{
+		"Double Bottom": {"1h": {Samples: 10, Hits: 8, Probability: 0.75}},
+	}}
+	for _, cfg := range []patternConfig{defaultDetectorConfig.Patterns, tuned} {
+		patterns := []Pattern{{Name: "Double Bottom", Confidence: 0.75}}
+		legacy.apply(patterns, "1h", cfg)
+		if want := cfg == defaultDetectorConfig.Patterns; (patterns[0].Samples > 0) != want {
+			t.Errorf("entry lama dipakai untuk config %+v: %v, mau %v", cfg, patterns[0].Samples > 0, want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sdcoffey/techan"
+)
+
+// IndicatorSummary is the indicator snapshot at the last candle of a series.
+// It is sent to the LLM as "technical_indicators" and reused by watch mode
+// to spot material changes between candles.
+type IndicatorSummary struct {
+	Symbol        string `json:"symbol"`
+	Timeframe     string `json:"timeframe"`
+	CurrentPrice  string `json:"current_price"`
+	PriceChange   string `json:"price_change_24h"`
+	TrendStrength string `json:"trend_strength"`
+	Volatility    string `json:"volatility"`
+
+	// Moving Averages
+	EMA5         string `json:"ema_5"`
+	EMA10        string `json:"ema_10"`
+	EMA30        string `json:"ema_30"`
+	EMA50        string `json:"ema_50"`
+	EMA200       string `json:"ema_200"`
+	EMAAlignment string `json:"ema_alignment"`
+
+	// Bollinger Bands
+	BBUpper    string `json:"bb_upper"`
+	BBMiddle   string `json:"bb_middle"`
+	BBLower    string `json:"bb_lower"`
+	BBPosition string `json:"bb_position"`
+	BBSqueeze  string `json:"bb_squeeze"`
+
+	// Oscillators
+	RSI        string `json:"rsi"`
+	RSITrend   string `json:"rsi_trend"`
+	MACD       string `json:"macd"`
+	MACDSignal string `json:"macd_signal"`
+	MACDHist   string `json:"macd_hist"`
+	MACDTrend  string `json:"macd_trend"`
+
+	// Volume Analysis
+	Volume      string `json:"volume"`
+	VolumeTrend string `json:"volume_trend"`
+	VolumeVsAvg string `json:"volume_vs_avg"`
+
+	// Volatility
+	ATR        string `json:"atr"`
+	ATRPercent string `json:"atr_percent"`
+
+	// Raw values for code that needs numbers, not prompt text
+	Price    float64 `json:"-"`
+	ATRValue float64 `json:"-"`
+	RSIValue float64 `json:"-"`
+}
+
+func summarizeIndicators(series *techan.TimeSeries, symbol, tf string) IndicatorSummary {
+	close := techan.NewClosePriceIndicator(series)
+	ema5 := techan.NewEMAIndicator(close, 5)
+	ema10 := techan.NewEMAIndicator(close, 10)
+	ema30 := techan.NewEMAIndicator(close, 30)
+	ema50 := techan.NewEMAIndicator(close, 50)
+	ema200 := techan.NewEMAIndicator(close, 200)
+	bb := techan.NewBollingerBandIndicator(close, 20, 2.0)
+	rsi := techan.NewRSIIndicator(close, 14)
+	macd := techan.NewMACDIndicator(close, 12, 26)
+	signal := techan.NewEMAIndicator(macd, 9)
+
+	// Additional indicators for deeper analysis
+	volume := techan.NewVolumeIndicator(series)
+	atr := techan.NewAverageTrueRangeIndicator(series, 14)
+
+	last := series.LastIndex()
+	prev := last - 1
+
+	// Calculate price changes
+	currentPrice := close.Calculate(last).InexactFloat64()
+	prevPrice := close.Calculate(prev).InexactFloat64()
+	priceChange := ((currentPrice - prevPrice) / prevPrice) * 100
+
+	macdVal := macd.Calculate(last).InexactFloat64()
+	signalVal := signal.Calculate(last).InexactFloat64()
+	atrVal := atr.Calculate(last).InexactFloat64()
+	rsiVal := rsi.Calculate(last)
+
+	return IndicatorSummary{
+		Symbol:        symbol,
+		Timeframe:     tf,
+		CurrentPrice:  fmt.Sprintf("%.4f", currentPrice),
+		PriceChange:   fmt.Sprintf("%.2f%%", priceChange),
+		TrendStrength: calculateTrendStrength(series),
+		Volatility:    fmt.Sprintf("%.2f%%", calculateVolatility(series)*100),
+
+		EMA5:         ema5.Calculate(last).StringFixed(4),
+		EMA10:        ema10.Calculate(last).StringFixed(4),
+		EMA30:        ema30.Calculate(last).StringFixed(4),
+		EMA50:        ema50.Calculate(last).StringFixed(4),
+		EMA200:       ema200.Calculate(last).StringFixed(4),
+		EMAAlignment: getEMAAlignment(ema5, ema10, ema30, ema50, ema200, last),
+
+		BBUpper:    bb.UpperBand(last).StringFixed(4),
+		BBMiddle:   bb.MiddleBand(last).StringFixed(4),
+		BBLower:    bb.LowerBand(last).StringFixed(4),
+		BBPosition: getBBPosition(close, bb, last),
+		BBSqueeze:  isBBSqueeze(bb, last),
+
+		RSI:        rsiVal.StringFixed(2),
+		RSITrend:   getRSITrend(rsi, last),
+		MACD:       fmt.Sprintf("%.4f", macdVal),
+		MACDSignal: fmt.Sprintf("%.4f", signalVal),
+		MACDHist:   fmt.Sprintf("%.4f", macdVal-signalVal),
+		MACDTrend:  getMACDTrend(macd, signal, last),
+
+		Volume:      volume.Calculate(last).StringFixed(2),
+		VolumeTrend: getVolumeTrend(volume, last),
+		VolumeVsAvg: getVolumeVsAverage(volume, last),
+
+		ATR:        atr.Calculate(last).StringFixed(4),
+		ATRPercent: fmt.Sprintf("%.2f%%", (atrVal/currentPrice)*100),
+
+		Price:    currentPrice,
+		ATRValue: atrVal,
+		RSIValue: rsiVal.InexactFloat64(),
+	}
+}
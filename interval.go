@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ================================
+// KLINE INTERVALS
+// ================================
+
+// Interval is a parsed Binance kline interval: 1m 3m 5m 15m 30m 1h 2h 4h 6h
+// 8h 12h 1d 3d 1w 1M. Note that "1m" is one minute and "1M" one month.
+type Interval struct {
+	Code  string
+	Count int
+	Unit  byte // 'm', 'h', 'd', 'w' or 'M'
+}
+
+var binanceIntervals = []string{"1m", "3m", "5m", "15m", "30m", "1h", "2h", "4h", "6h", "8h", "12h", "1d", "3d", "1w", "1M"}
+
+func ParseInterval(s string) (Interval, error) {
+	for _, code := range binanceIntervals {
+		if s == code {
+			n, _ := strconv.Atoi(s[:len(s)-1])
+			return Interval{Code: s, Count: n, Unit: s[len(s)-1]}, nil
+		}
+	}
+	return Interval{}, fmt.Errorf("timeframe %q tidak valid! Pilih: 5m, 15m, 30m, 1h, 4h, 1d, 1w, dll", s)
+}
+
+// mustInterval is for intervals that were already validated at the CLI.
+func mustInterval(s string) Interval {
+	iv, err := ParseInterval(s)
+	if err != nil {
+		panic(err)
+	}
+	return iv
+}
+
+func (iv Interval) String() string { return iv.Code }
+
+// Duration is the nominal candle length. A month counts as 30 days; use
+// Next for calendar-correct boundaries.
+func (iv Interval) Duration() time.Duration {
+	n := time.Duration(iv.Count)
+	switch iv.Unit {
+	case 'm':
+		return n * time.Minute
+	case 'h':
+		return n * time.Hour
+	case 'd':
+		return n * 24 * time.Hour
+	case 'w':
+		return n * 7 * 24 * time.Hour
+	case 'M':
+		return n * 30 * 24 * time.Hour
+	}
+	return 0
+}
+
+// Next returns the open time of the candle after the one opening at t.
+func (iv Interval) Next(t time.Time) time.Time {
+	if iv.Unit == 'M' {
+		return t.AddDate(0, iv.Count, 0)
+	}
+	return t.Add(iv.Duration())
+}
+
+// CloseTime follows Binance: the last millisecond before the next candle.
+func (iv Interval) CloseTime(open time.Time) time.Time {
+	return iv.Next(open).Add(-time.Millisecond)
+}
+
+// AxisLayout is the time format for chart labels at this interval.
+func (iv Interval) AxisLayout() string {
+	switch {
+	case iv.Unit == 'M':
+		return "2006-01"
+	case iv.Duration() >= 24*time.Hour:
+		return "2006-01-02"
+	default:
+		return "01-02 15:04"
+	}
+}
+
+// fillCloseTimes sets CloseTime on candles from sources that only report
+// the open time.
+func fillCloseTimes(candles []Candle, iv Interval) {
+	for i := range candles {
+		if candles[i].CloseTime.IsZero() {
+			candles[i].CloseTime = iv.CloseTime(candles[i].Time)
+		}
+	}
+}
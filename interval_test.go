@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIntervalNextAndCloseTime(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	ms := time.Millisecond
+	tests := []struct {
+		code  string
+		open  string
+		next  string
+		close time.Time
+	}{
+		{"1m", "2024-03-01 10:59", "2024-03-01 11:00", at("2024-03-01 11:00").Add(-ms)},
+		{"15m", "2024-03-01 23:45", "2024-03-02 00:00", at("2024-03-02 00:00").Add(-ms)},
+		{"1h", "2024-03-01 10:00", "2024-03-01 11:00", at("2024-03-01 10:59").Add(time.Minute - ms)},
+		{"4h", "2024-02-29 20:00", "2024-03-01 00:00", at("2024-03-01 00:00").Add(-ms)},
+		{"1d", "2024-02-28 00:00", "2024-02-29 00:00", at("2024-02-29 00:00").Add(-ms)},
+		{"1w", "2024-02-26 00:00", "2024-03-04 00:00", at("2024-03-04 00:00").Add(-ms)},
+		// 1M ikut kalender, bukan 30 hari
+		{"1M", "2024-01-01 00:00", "2024-02-01 00:00", at("2024-02-01 00:00").Add(-ms)},
+		{"1M", "2024-02-01 00:00", "2024-03-01 00:00", at("2024-03-01 00:00").Add(-ms)},
+		{"1M", "2023-02-01 00:00", "2023-03-01 00:00", at("2023-03-01 00:00").Add(-ms)},
+		{"1M", "2024-12-01 00:00", "2025-01-01 00:00", at("2025-01-01 00:00").Add(-ms)},
+	}
+	for _, tt := range tests {
+		iv := mustInterval(tt.code)
+		open := at(tt.open)
+		if got := iv.Next(open); !got.Equal(at(tt.next)) {
+			t.Errorf("%s Next(%s) = %s, mau %s", tt.code, tt.open, got.Format(time.RFC3339), tt.next)
+		}
+		closeTime := iv.CloseTime(open)
+		if !closeTime.Equal(tt.close) {
+			t.Errorf("%s CloseTime(%s) = %s, mau %s", tt.code, tt.open, closeTime.Format(time.RFC3339Nano), tt.close.Format(time.RFC3339Nano))
+		}
+		// Candle close 1ms sebelum candle berikutnya open, seperti Binance
+		if !closeTime.Before(iv.Next(open)) || iv.Next(open).Sub(closeTime) != ms {
+			t.Errorf("%s: close %s tidak tepat sebelum open berikutnya", tt.code, closeTime.Format(time.RFC3339Nano))
+		}
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		code  string
+		count int
+		unit  byte
+		dur   time.Duration
+	}{
+		{"1m", 1, 'm', time.Minute},
+		{"30m", 30, 'm', 30 * time.Minute},
+		{"12h", 12, 'h', 12 * time.Hour},
+		{"3d", 3, 'd', 72 * time.Hour},
+		{"1w", 1, 'w', 7 * 24 * time.Hour},
+		{"1M", 1, 'M', 30 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		iv, err := ParseInterval(tt.code)
+		if err != nil || iv.Count != tt.count || iv.Unit != tt.unit || iv.Duration() != tt.dur || iv.String() != tt.code {
+			t.Errorf("ParseInterval(%q) = %+v (%s), %v", tt.code, iv, iv.Duration(), err)
+		}
+	}
+	for _, bad := range []string{"", "2m", "1H", "60", "1y"} {
+		if _, err := ParseInterval(bad); err == nil {
+			t.Errorf("ParseInterval(%q) harus error", bad)
+		}
+	}
+}
+
+func TestFillCloseTimes(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	given := jan.Add(time.Hour)
+	candles := []Candle{{Time: jan}, {Time: jan.AddDate(0, 1, 0), CloseTime: given}}
+	fillCloseTimes(candles, mustInterval("1M"))
+	if want := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC).Add(-time.Millisecond); !candles[0].CloseTime.Equal(want) {
+		t.Errorf("close Januari %s, mau %s", candles[0].CloseTime, want)
+	}
+	if !candles[1].CloseTime.Equal(given) {
+		t.Error("CloseTime dari sumber ditimpa")
+	}
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ================================
+// LLM BACKENDS
+// ================================
+
+// LLMClient sends one prompt to a language model and returns its answer.
+type LLMClient interface {
+	Name() string
+	Complete(ctx context.Context, prompt string) (*LLMResponse, error)
+}
+
+type LLMResponse struct {
+	Content   string
+	Reasoning string
+	Model     string
+	Usage     LLMUsage
+
+	streamed bool // Content sudah tampil di terminal selama streaming
+}
+
+// LLMUsage is the token count reported by the API for one or more calls.
+type LLMUsage struct {
+	InputTokens     int     `json:"input_tokens"`
+	OutputTokens    int     `json:"output_tokens"`
+	ReasoningTokens int     `json:"reasoning_tokens,omitempty"` // part of OutputTokens
+	Calls           int     `json:"calls"`
+	Cost            float64 `json:"cost_usd"` // filled in by the usage meter
+}
+
+// Add sums the usage of another call into u.
+func (u *LLMUsage) Add(o LLMUsage) {
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.ReasoningTokens += o.ReasoningTokens
+	u.Calls += o.Calls
+	u.Cost += o.Cost
+}
+
+func (u LLMUsage) String() string {
+	s := fmt.Sprintf("%d input + %d output token", u.InputTokens, u.OutputTokens)
+	if u.ReasoningTokens > 0 {
+		s += fmt.Sprintf(" (%d reasoning)", u.ReasoningTokens)
+	}
+	if u.Calls > 1 {
+		s += fmt.Sprintf(", %d request", u.Calls)
+	}
+	return s
+}
+
+// LLMConfig configures a backend. Empty fields fall back to the backend
+// defaults and then to <PREFIX>_BASE_URL, <PREFIX>_MODEL and
+// <PREFIX>_API_KEY from the environment (.env).
+type LLMConfig struct {
+	Name         string
+	BaseURL      string
+	APIKey       string
+	Model        string
+	Temperature  *float64
+	MaxTokens    int
+	Timeout      time.Duration
+	SystemPrompt string
+	TraceDir     string // reasoning traces are saved here when set
+	JSONMode     bool   // ask OpenAI-compatible servers for a JSON object
+
+	Retries       int  // extra attempts after a 429 or 5xx answer
+	Stream        bool // print tokens as they arrive (backends that support SSE)
+	ShowReasoning bool // stream reasoning dimmed instead of a progress line
+	ShowContent   bool // stream the answer text too; off for --format json, which is rendered once parsed
+
+	Meter      *usageMeter // prices and records every call when set
+	Symbol, TF string      // labels for the usage ledger and cache
+
+	Cache      *llmCache // answers identical requests from disk when set
+	PromptID   string    // template name@version, part of the cache key
+	CacheUntil time.Time // cached answer expiry, zero = never
+	CacheKey   string    // hashed instead of the prompt for a forming candle, see cacheKeyPrompt
+
+	Facts *PromptData // the data behind the prompt, used by the mock backend
+}
+
+type llmBackend struct {
+	api       string // "openai" chat completions or "anthropic" messages
+	envPrefix string
+	baseURL   string
+	model     string
+	needsKey  bool
+}
+
+var llmBackends = map[string]llmBackend{
+	"deepseek":  {api: "openai", envPrefix: "DEEPSEEK", baseURL: "https://api.deepseek.com", model: "deepseek-chat", needsKey: true},
+	"grok":      {api: "openai", envPrefix: "GROK", baseURL: "https://api.x.ai/v1", model: "grok-beta", needsKey: true},
+	"openai":    {api: "openai", envPrefix: "OPENAI", baseURL: "https://api.openai.com/v1", model: "gpt-4o-mini", needsKey: true},
+	"local":     {api: "openai", envPrefix: "LOCAL_LLM", baseURL: "http://127.0.0.1:11434/v1", model: "llama3.1"},
+	"anthropic": {api: "anthropic", envPrefix: "ANTHROPIC", baseURL: "https://api.anthropic.com", model: "claude-3-5-sonnet-latest", needsKey: true},
+	"mock":      {api: "mock", envPrefix: "MOCK_LLM", model: "indicators"},
+}
+
+var llmAliases = map[string]string{"xai": "grok", "ollama": "local", "llamacpp": "local"}
+
+const (
+	defaultLLMTimeout   = 3 * time.Minute
+	defaultLLMMaxTokens = 4096
+	defaultLLMRetries   = 2
+)
+
+func llmNames() []string {
+	names := make([]string, 0, len(llmBackends))
+	for name := range llmBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveLLMConfig fills the empty fields of cfg for the backend cfg.Name.
+func resolveLLMConfig(cfg LLMConfig) (LLMConfig, error) {
+	cfg.Name = strings.ToLower(strings.TrimSpace(cfg.Name))
+	if alias, ok := llmAliases[cfg.Name]; ok {
+		cfg.Name = alias
+	}
+	backend, ok := llmBackends[cfg.Name]
+	if !ok {
+		return cfg, fmt.Errorf("AI %q tidak dikenal, pilih: %s", cfg.Name, strings.Join(llmNames(), ", "))
+	}
+
+	cfg.BaseURL = strings.TrimRight(orDefault(cfg.BaseURL, orDefault(os.Getenv(backend.envPrefix+"_BASE_URL"), backend.baseURL)), "/")
+	cfg.Model = orDefault(cfg.Model, orDefault(os.Getenv(backend.envPrefix+"_MODEL"), backend.model))
+	cfg.APIKey = orDefault(cfg.APIKey, os.Getenv(backend.envPrefix+"_API_KEY"))
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultLLMTimeout
+	}
+	return cfg, nil
+}
+
+// newLLMClient builds the adapter for a resolved config.
+func newLLMClient(cfg LLMConfig) (LLMClient, error) {
+	cfg, err := resolveLLMConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	backend := llmBackends[cfg.Name]
+	if backend.needsKey && cfg.APIKey == "" {
+		return nil, fmt.Errorf("API key %s kosong, isi %s_API_KEY di .env", cfg.Name, backend.envPrefix)
+	}
+
+	client := &http.Client{Timeout: cfg.Timeout}
+	switch backend.api {
+	case "anthropic":
+		return &anthropicClient{cfg: cfg, client: client}, nil
+	case "mock":
+		return &mockClient{cfg: cfg}, nil
+	}
+	return &openAIClient{cfg: cfg, client: client}, nil
+}
+
+// LLMError is an error answer from an LLM API, with the provider's own
+// message, e.g. OpenAI {"error":{"code":"invalid_api_key","message":...}}.
+type LLMError struct {
+	Backend    string
+	Status     int
+	Code       string
+	Msg        string
+	RetryAfter time.Duration
+}
+
+func (e *LLMError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "HTTP %d", e.Status)
+	if e.Code != "" {
+		fmt.Fprintf(&b, " [%s]", e.Code)
+	}
+	if e.Msg != "" {
+		fmt.Fprintf(&b, ": %s", e.Msg)
+	}
+	return b.String()
+}
+
+// retryable is true for rate limits and server side failures. A 429 for an
+// exhausted quota will not clear by waiting, so it is not retried.
+func (e *LLMError) retryable() bool {
+	if e.Status == http.StatusTooManyRequests {
+		return !strings.Contains(e.Code, "quota")
+	}
+	return e.Status >= 500
+}
+
+// llmErrorBody covers the error shapes of the supported APIs: an object
+// under "error" (OpenAI, DeepSeek, Anthropic, Ollama /v1) or a plain
+// string under "error" with a separate "code" (xAI, Ollama).
+type llmErrorBody struct {
+	Error json.RawMessage `json:"error"`
+	Code  json.RawMessage `json:"code"`
+}
+
+// parseLLMError returns the code and message from an error body.
+func parseLLMError(body []byte) (code, msg string) {
+	var v llmErrorBody
+	if err := json.Unmarshal(body, &v); err != nil || len(v.Error) == 0 || string(v.Error) == "null" {
+		return "", ""
+	}
+	var obj struct {
+		Type    string          `json:"type"`
+		Code    json.RawMessage `json:"code"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(v.Error, &obj); err == nil {
+		return orDefault(rawString(obj.Code), obj.Type), obj.Message
+	}
+	_ = json.Unmarshal(v.Error, &msg)
+	return rawString(v.Code), msg
+}
+
+// rawString reads a JSON string or number as text; null becomes "".
+func rawString(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+// postAI sends payload and returns the 200 response, retrying 429 and 5xx
+// answers with the server's Retry-After or exponential backoff with
+// jitter. Other statuses come back as *LLMError. The caller closes the body.
+func postAI(ctx context.Context, client *http.Client, cfg LLMConfig, url string, header http.Header, payload any) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header = header.Clone()
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		e := &LLMError{Backend: cfg.Name, Status: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+		if e.Code, e.Msg = parseLLMError(raw); e.Msg == "" {
+			e.Msg = strings.TrimSpace(string(raw[:min(len(raw), 256)]))
+		}
+		if !e.retryable() || attempt >= cfg.Retries {
+			return nil, e
+		}
+
+		wait := e.RetryAfter
+		if wait <= 0 {
+			wait = backoff + time.Duration(rand.Int63n(int64(backoff)))
+			backoff *= 2
+		}
+		if wait > maxRetryWait {
+			return nil, e
+		}
+		fmt.Fprintf(os.Stderr, "⏳ %s %v, coba lagi dalam %s (%d/%d)\n", cfg.Name, e, wait.Round(time.Second), attempt+1, cfg.Retries)
+		if err := waitRetry(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitRetry sleeps between attempts unless ctx ends first. Tests swap it
+// to run retries without waiting.
+var waitRetry = func(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// callAI posts payload as JSON and decodes the answer into out. It is the
+// one non-streaming HTTP path every LLM adapter goes through.
+func callAI(ctx context.Context, client *http.Client, cfg LLMConfig, url string, header http.Header, payload, out any) error {
+	resp, err := postAI(ctx, client, cfg, url, header, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("baca response: %w", err)
+	}
+	// Beberapa proxy mengirim error dengan status 200
+	if code, msg := parseLLMError(body); msg != "" {
+		return &LLMError{Backend: cfg.Name, Status: resp.StatusCode, Code: code, Msg: msg}
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// ================================
+// OPENAI-COMPATIBLE CHAT COMPLETIONS
+// ================================
+
+// openAIClient speaks /chat/completions, which DeepSeek, xAI, OpenAI and
+// local Ollama or llama.cpp servers all accept.
+type openAIClient struct {
+	cfg    LLMConfig
+	client *http.Client
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+	StreamOptions  *streamOptions  `json:"stream_options,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// chatUsage is the usage block of OpenAI-compatible APIs. DeepSeek and
+// OpenAI put reasoning tokens under completion_tokens_details.
+type chatUsage struct {
+	PromptTokens            int `json:"prompt_tokens"`
+	CompletionTokens        int `json:"completion_tokens"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+func (u *chatUsage) usage() LLMUsage {
+	if u == nil {
+		return LLMUsage{Calls: 1}
+	}
+	return LLMUsage{InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens, ReasoningTokens: u.CompletionTokensDetails.ReasoningTokens, Calls: 1}
+}
+
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+type chatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage *chatUsage `json:"usage"`
+}
+
+func (c *openAIClient) Name() string { return c.cfg.Name }
+
+func (c *openAIClient) url() string {
+	return strings.TrimRight(c.cfg.BaseURL, "/") + "/chat/completions"
+}
+
+func (c *openAIClient) request(prompt string) (chatRequest, http.Header) {
+	req := chatRequest{Model: c.cfg.Model, Temperature: c.cfg.Temperature, MaxTokens: c.cfg.MaxTokens}
+	if c.cfg.JSONMode {
+		req.ResponseFormat = &responseFormat{Type: "json_object"}
+	}
+	if c.cfg.SystemPrompt != "" {
+		req.Messages = append(req.Messages, chatMessage{Role: "system", Content: c.cfg.SystemPrompt})
+	}
+	req.Messages = append(req.Messages, chatMessage{Role: "user", Content: prompt})
+
+	header := http.Header{}
+	if c.cfg.APIKey != "" {
+		header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	}
+	return req, header
+}
+
+func (c *openAIClient) Complete(ctx context.Context, prompt string) (*LLMResponse, error) {
+	req, header := c.request(prompt)
+	var res chatResponse
+	if err := callAI(ctx, c.client, c.cfg, c.url(), header, req, &res); err != nil {
+		return nil, fmt.Errorf("%s: %w", c.cfg.Name, err)
+	}
+	if len(res.Choices) == 0 {
+		return nil, fmt.Errorf("%s: response tanpa choices", c.cfg.Name)
+	}
+	msg := res.Choices[0].Message
+	return &LLMResponse{Content: msg.Content, Reasoning: msg.ReasoningContent, Model: orDefault(res.Model, c.cfg.Model), Usage: res.Usage.usage()}, nil
+}
+
+// ================================
+// ANTHROPIC MESSAGES
+// ================================
+
+const anthropicVersion = "2023-06-01"
+
+type anthropicClient struct {
+	cfg    LLMConfig
+	client *http.Client
+}
+
+type anthropicRequest struct {
+	Model       string        `json:"model"`
+	System      string        `json:"system,omitempty"`
+	Messages    []chatMessage `json:"messages"`
+	MaxTokens   int           `json:"max_tokens"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	Model   string         `json:"model"`
+	Usage   anthropicUsage `json:"usage"`
+	Content []struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		Thinking string `json:"thinking"`
+	} `json:"content"`
+}
+
+func (c *anthropicClient) Name() string { return c.cfg.Name }
+
+func (c *anthropicClient) url() string {
+	return strings.TrimRight(c.cfg.BaseURL, "/") + "/v1/messages"
+}
+
+func (c *anthropicClient) request(prompt string) (anthropicRequest, http.Header) {
+	req := anthropicRequest{
+		Model:       c.cfg.Model,
+		System:      c.cfg.SystemPrompt,
+		Messages:    []chatMessage{{Role: "user", Content: prompt}},
+		MaxTokens:   c.cfg.MaxTokens,
+		Temperature: c.cfg.Temperature,
+	}
+	// max_tokens wajib di Messages API
+	if req.MaxTokens <= 0 {
+		req.MaxTokens = defaultLLMMaxTokens
+	}
+
+	header := http.Header{}
+	header.Set("x-api-key", c.cfg.APIKey)
+	header.Set("anthropic-version", anthropicVersion)
+	return req, header
+}
+
+func (c *anthropicClient) Complete(ctx context.Context, prompt string) (*LLMResponse, error) {
+	req, header := c.request(prompt)
+	var res anthropicResponse
+	if err := callAI(ctx, c.client, c.cfg, c.url(), header, req, &res); err != nil {
+		return nil, fmt.Errorf("%s: %w", c.cfg.Name, err)
+	}
+
+	out := &LLMResponse{Model: orDefault(res.Model, c.cfg.Model)}
+	out.Usage = LLMUsage{InputTokens: res.Usage.InputTokens, OutputTokens: res.Usage.OutputTokens, Calls: 1}
+	for _, block := range res.Content {
+		switch block.Type {
+		case "text":
+			out.Content += block.Text
+		case "thinking":
+			out.Reasoning += block.Thinking
+		}
+	}
+	if out.Content == "" {
+		return nil, fmt.Errorf("%s: response tanpa text", c.cfg.Name)
+	}
+	return out, nil
+}
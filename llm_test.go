@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// llmStub answers every request with body after letting check inspect it.
+func llmStub(t *testing.T, path, body string, check func(r *http.Request, payload []byte)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != path {
+			t.Errorf("request %s %s, mau POST %s", r.Method, r.URL.Path, path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type %q", ct)
+		}
+		var payload json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("body bukan JSON: %v", err)
+		}
+		check(r, payload)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOpenAIClientRequest(t *testing.T) {
+	temp := 0.2
+	var got chatRequest
+	srv := llmStub(t, "/v1/chat/completions", `{"model":"gpt-test-0613",
+		"choices":[{"message":{"role":"assistant","content":"{\"bias\":\"long\"}","reasoning_content":"mikir dulu"}}],
+		"usage":{"prompt_tokens":120,"completion_tokens":45,"completion_tokens_details":{"reasoning_tokens":30}}}`,
+		func(r *http.Request, payload []byte) {
+			if auth := r.Header.Get("Authorization"); auth != "Bearer sk-test" {
+				t.Errorf("Authorization %q", auth)
+			}
+			if r.Header.Get("x-api-key") != "" {
+				t.Error("header anthropic ikut terkirim")
+			}
+			if err := json.Unmarshal(payload, &got); err != nil {
+				t.Error(err)
+			}
+		})
+
+	client, err := newLLMClient(LLMConfig{Name: "openai", BaseURL: srv.URL + "/v1/", APIKey: "sk-test", Model: "gpt-test",
+		Temperature: &temp, MaxTokens: 512, SystemPrompt: "kamu analis", JSONMode: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Complete(context.Background(), "analisa SOLUSDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Model != "gpt-test" || got.Temperature == nil || *got.Temperature != 0.2 || got.MaxTokens != 512 || got.Stream {
+		t.Errorf("payload model %q temperature %v max_tokens %d stream %v", got.Model, got.Temperature, got.MaxTokens, got.Stream)
+	}
+	want := []chatMessage{{Role: "system", Content: "kamu analis"}, {Role: "user", Content: "analisa SOLUSDT"}}
+	if len(got.Messages) != 2 || got.Messages[0] != want[0] || got.Messages[1] != want[1] {
+		t.Errorf("messages %+v, mau %+v", got.Messages, want)
+	}
+	if got.ResponseFormat == nil || got.ResponseFormat.Type != "json_object" {
+		t.Errorf("JSON mode tidak minta response_format: %+v", got.ResponseFormat)
+	}
+
+	if resp.Content != `{"bias":"long"}` || resp.Reasoning != "mikir dulu" || resp.Model != "gpt-test-0613" {
+		t.Errorf("response %+v", resp)
+	}
+	if u := resp.Usage; u != (LLMUsage{InputTokens: 120, OutputTokens: 45, ReasoningTokens: 30, Calls: 1}) {
+		t.Errorf("usage %+v", u)
+	}
+}
+
+func TestOpenAIClientWithoutKeyOrUsage(t *testing.T) {
+	srv := llmStub(t, "/v1/chat/completions", `{"choices":[{"message":{"content":"halo"}}]}`,
+		func(r *http.Request, payload []byte) {
+			if auth := r.Header.Get("Authorization"); auth != "" {
+				t.Errorf("server lokal tanpa key dapat Authorization %q", auth)
+			}
+			var req map[string]any
+			json.Unmarshal(payload, &req)
+			for _, field := range []string{"temperature", "max_tokens", "response_format"} {
+				if _, ok := req[field]; ok {
+					t.Errorf("field %s terkirim padahal tidak di-set", field)
+				}
+			}
+		})
+
+	client, err := newLLMClient(LLMConfig{Name: "local", BaseURL: srv.URL + "/v1", APIKey: "", Model: "llama3.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Complete(context.Background(), "halo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Model kosong di response jatuh ke model config, usage kosong tetap 1 call
+	if resp.Content != "halo" || resp.Model != "llama3.1" || resp.Usage != (LLMUsage{Calls: 1}) {
+		t.Errorf("response %+v", resp)
+	}
+}
+
+func TestAnthropicClientRequest(t *testing.T) {
+	temp := 0.0
+	var got anthropicRequest
+	srv := llmStub(t, "/v1/messages", `{"model":"claude-test-20250101",
+		"content":[{"type":"thinking","thinking":"cek struktur"},{"type":"text","text":"bias "},{"type":"text","text":"long"}],
+		"usage":{"input_tokens":200,"output_tokens":80}}`,
+		func(r *http.Request, payload []byte) {
+			if key := r.Header.Get("x-api-key"); key != "sk-ant-test" {
+				t.Errorf("x-api-key %q", key)
+			}
+			if v := r.Header.Get("anthropic-version"); v != anthropicVersion {
+				t.Errorf("anthropic-version %q", v)
+			}
+			if auth := r.Header.Get("Authorization"); auth != "" {
+				t.Errorf("Authorization %q ikut terkirim", auth)
+			}
+			if err := json.Unmarshal(payload, &got); err != nil {
+				t.Error(err)
+			}
+		})
+
+	client, err := newLLMClient(LLMConfig{Name: "anthropic", BaseURL: srv.URL, APIKey: "sk-ant-test", Model: "claude-test",
+		Temperature: &temp, SystemPrompt: "kamu analis"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Complete(context.Background(), "analisa SOLUSDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// System prompt di field sendiri, max_tokens wajib jadi pakai default
+	if got.Model != "claude-test" || got.System != "kamu analis" || got.MaxTokens != defaultLLMMaxTokens ||
+		got.Temperature == nil || *got.Temperature != 0 || got.Stream {
+		t.Errorf("payload %+v", got)
+	}
+	if len(got.Messages) != 1 || got.Messages[0] != (chatMessage{Role: "user", Content: "analisa SOLUSDT"}) {
+		t.Errorf("messages %+v", got.Messages)
+	}
+
+	if resp.Content != "bias long" || resp.Reasoning != "cek struktur" || resp.Model != "claude-test-20250101" {
+		t.Errorf("response %+v", resp)
+	}
+	if u := resp.Usage; u != (LLMUsage{InputTokens: 200, OutputTokens: 80, Calls: 1}) {
+		t.Errorf("usage %+v", u)
+	}
+}
+
+func TestAnthropicClientEmptyText(t *testing.T) {
+	srv := llmStub(t, "/v1/messages", `{"content":[{"type":"thinking","thinking":"..."}],"usage":{"input_tokens":5,"output_tokens":5}}`,
+		func(r *http.Request, payload []byte) {
+			var req anthropicRequest
+			json.Unmarshal(payload, &req)
+			if req.MaxTokens != 300 {
+				t.Errorf("max_tokens %d, mau 300 dari config", req.MaxTokens)
+			}
+		})
+	client, err := newLLMClient(LLMConfig{Name: "anthropic", BaseURL: srv.URL, APIKey: "k", Model: "m", MaxTokens: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Complete(context.Background(), "x"); err == nil {
+		t.Error("response tanpa blok text harus error")
+	}
+}
+
+func TestPostAIRetries(t *testing.T) {
+	type reply struct {
+		status     int
+		retryAfter string
+		body       string
+	}
+	ok := reply{200, "", `{"model":"m","choices":[{"message":{"content":"ok"}}],"usage":{"prompt_tokens":10,"completion_tokens":4}}`}
+	tests := []struct {
+		name     string
+		replies  []reply
+		requests int
+		status   int // 0 = sukses
+		code     string
+		msg      string
+		waits    []time.Duration // -1 = backoff dengan jitter
+	}{
+		{
+			name:     "401 key salah tidak diulang",
+			replies:  []reply{{401, "", `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`}},
+			requests: 1, status: 401, code: "invalid_api_key", msg: "Incorrect API key provided",
+		},
+		{
+			name:     "429 quota habis tidak diulang",
+			replies:  []reply{{429, "1", `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`}},
+			requests: 1, status: 429, code: "insufficient_quota", msg: "You exceeded your current quota",
+		},
+		{
+			name:     "503 lalu 200 diulang dengan Retry-After",
+			replies:  []reply{{503, "3", `{"error":{"message":"overloaded","type":"overloaded_error"}}`}, ok},
+			requests: 2, waits: []time.Duration{3 * time.Second},
+		},
+		{
+			name:     "429 rate limit tanpa Retry-After pakai backoff",
+			replies:  []reply{{429, "", `{"error":"rate limited","code":"rate_limit"}`}, {429, "", `{"error":"rate limited","code":"rate_limit"}`}, ok},
+			requests: 3, waits: []time.Duration{-1, -1},
+		},
+		{
+			name:     "retry habis",
+			replies:  []reply{{500, "", `{}`}, {500, "", `{}`}, {500, "", `{}`}},
+			requests: 3, status: 500, msg: "{}", waits: []time.Duration{-1, -1},
+		},
+		{
+			name:     "Retry-After lebih dari maxRetryWait",
+			replies:  []reply{{429, "600", `{"error":{"message":"slow down"}}`}},
+			requests: 1, status: 429, msg: "slow down",
+		},
+		{
+			name:     "body error bukan JSON",
+			replies:  []reply{{403, "", "<html><body>403 Forbidden</body></html>\n"}},
+			requests: 1, status: 403, msg: "<html><body>403 Forbidden</body></html>",
+		},
+		{
+			name:     "200 dengan error dari proxy",
+			replies:  []reply{{200, "", `{"error":{"message":"model not found","code":"model_not_found"}}`}},
+			requests: 1, status: 200, code: "model_not_found", msg: "model not found",
+		},
+		{name: "200 dengan usage", replies: []reply{ok}, requests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var waits []time.Duration
+			orig := waitRetry
+			waitRetry = func(ctx context.Context, d time.Duration) error { waits = append(waits, d); return nil }
+			defer func() { waitRetry = orig }()
+
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rep := tt.replies[min(requests, len(tt.replies)-1)]
+				requests++
+				if rep.retryAfter != "" {
+					w.Header().Set("Retry-After", rep.retryAfter)
+				}
+				w.WriteHeader(rep.status)
+				w.Write([]byte(rep.body))
+			}))
+			defer srv.Close()
+
+			cfg := LLMConfig{Name: "openai", Retries: 2}
+			var res chatResponse
+			err := callAI(context.Background(), srv.Client(), cfg, srv.URL, http.Header{}, chatRequest{Model: "m"}, &res)
+			if requests != tt.requests {
+				t.Errorf("request = %d, mau %d", requests, tt.requests)
+			}
+			if len(waits) != len(tt.waits) {
+				t.Fatalf("tunggu %v, mau %d kali", waits, len(tt.waits))
+			}
+			for i, w := range tt.waits {
+				// backoff pertama 1s + jitter < 1s, lalu dobel
+				if w < 0 && (waits[i] < time.Second<<i || waits[i] >= 2*time.Second<<i) || w >= 0 && waits[i] != w {
+					t.Errorf("tunggu ke-%d %s", i+1, waits[i])
+				}
+			}
+
+			if tt.status == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(res.Choices) == 0 || res.Choices[0].Message.Content != "ok" || res.Usage.usage() != (LLMUsage{InputTokens: 10, OutputTokens: 4, Calls: 1}) {
+					t.Errorf("response %+v", res)
+				}
+				return
+			}
+			var le *LLMError
+			if !errors.As(err, &le) {
+				t.Fatalf("err %v bukan *LLMError", err)
+			}
+			if le.Status != tt.status || le.Code != tt.code || le.Msg != tt.msg || le.Backend != "openai" {
+				t.Errorf("LLMError %+v, mau status %d code %q msg %q", le, tt.status, tt.code, tt.msg)
+			}
+		})
+	}
+}
+
+func TestParseLLMError(t *testing.T) {
+	tests := []struct {
+		body      string
+		code, msg string
+	}{
+		{`{"error":{"message":"Incorrect API key","type":"invalid_request_error","code":"invalid_api_key"}}`, "invalid_api_key", "Incorrect API key"},
+		{`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, "overloaded_error", "Overloaded"},
+		{`{"error":{"message":"bad","code":400}}`, "400", "bad"},
+		{`{"error":"model 'llama9' not found","code":404}`, "404", "model 'llama9' not found"},
+		{`{"error":"Incorrect API key","code":"invalid key"}`, "invalid key", "Incorrect API key"},
+		{`{"error":null}`, "", ""},
+		{`{"choices":[]}`, "", ""},
+		{`<html>502</html>`, "", ""},
+	}
+	for _, tt := range tests {
+		if code, msg := parseLLMError([]byte(tt.body)); code != tt.code || msg != tt.msg {
+			t.Errorf("parseLLMError(%s) = %q, %q; mau %q, %q", tt.body, code, msg, tt.code, tt.msg)
+		}
+	}
+}
+
+func TestLLMErrorRetryable(t *testing.T) {
+	tests := []struct {
+		err  LLMError
+		want bool
+	}{
+		{LLMError{Status: 429, Code: "rate_limit_exceeded"}, true},
+		{LLMError{Status: 429, Code: "insufficient_quota"}, false},
+		{LLMError{Status: 500}, true},
+		{LLMError{Status: 503, Code: "overloaded_error"}, true},
+		{LLMError{Status: 400}, false},
+		{LLMError{Status: 401, Code: "invalid_api_key"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.err.retryable(); got != tt.want {
+			t.Errorf("%v retryable = %v, mau %v", &tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ================================
+// LLM RESPONSE CACHE
+// ================================
+
+// llmCache stores answers under the hash of everything that shapes them,
+// so re-running the same symbol and timeframe inside one candle does not
+// pay for the same analysis twice.
+type llmCache struct {
+	dir     string
+	refresh bool // skip lookups but still store the fresh answer
+
+	fixtures bool // entries never expire (--ai-record / --ai-replay)
+	replay   bool // misses are errors, the real backend is never called
+}
+
+// cacheEntry is one file in the cache. Expires is zero for answers about
+// closed candles only; that data never changes, so neither does the key.
+type cacheEntry struct {
+	Key      string      `json:"key"`
+	Backend  string      `json:"backend"`
+	Model    string      `json:"model"`
+	PromptID string      `json:"prompt_id"`
+	Symbol   string      `json:"symbol,omitempty"`
+	TF       string      `json:"timeframe,omitempty"`
+	Created  time.Time   `json:"created"`
+	Expires  time.Time   `json:"expires,omitempty"`
+	Response LLMResponse `json:"response"`
+}
+
+func defaultLLMCacheDir() string {
+	return filepath.Join(filepath.Dir(defaultStoreDir()), "llm")
+}
+
+func newLLMCache(dir string, refresh bool) *llmCache {
+	return &llmCache{dir: dir, refresh: refresh}
+}
+
+// newFixtureCache uses the cache format for test fixtures: --ai-record
+// always asks the backend and stores the answer, --ai-replay only reads.
+// Prompts are stamped with the last candle's time, so a fixed candle file
+// renders the same prompt, and the same key, on every run.
+func newFixtureCache(dir string, replay bool) *llmCache {
+	return &llmCache{dir: dir, refresh: !replay, fixtures: true, replay: replay}
+}
+
+// cacheKey hashes the resolved model, the prompt template version and the
+// rendered prompt together with the settings that change the answer. A
+// CacheKey stands in for the prompt (see cacheKeyPrompt).
+func cacheKey(cfg LLMConfig, prompt string) string {
+	if cfg.CacheKey != "" {
+		prompt = cfg.CacheKey
+	}
+	h := sha256.New()
+	parts := []any{cfg.Name, cfg.BaseURL, cfg.Model, cfg.PromptID, cfg.SystemPrompt, cfg.JSONMode, cfg.Temperature, cfg.MaxTokens, prompt}
+	for _, p := range parts {
+		b, _ := json.Marshal(p)
+		h.Write(b)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *llmCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+func (c *llmCache) get(key string, now time.Time) (*cacheEntry, error) {
+	data, err := os.ReadFile(c.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, nil // file rusak dianggap miss, nanti ditimpa
+	}
+	if !e.Expires.IsZero() && !now.Before(e.Expires) {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+func (c *llmCache) put(e cacheEntry) error {
+	path := c.path(e.Key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// cacheExpiry is when an answer about candles stops being current: the
+// close of the last candle while it is still forming, never otherwise.
+func cacheExpiry(candles []Candle, tf string, now time.Time) time.Time {
+	if len(candles) == 0 {
+		return now
+	}
+	last := candles[len(candles)-1]
+	end := last.CloseTime
+	if end.IsZero() {
+		end = last.Time.Add(mustInterval(tf).Duration())
+	}
+	if end.After(now) {
+		return end
+	}
+	return time.Time{}
+}
+
+// cacheKeyPrompt stands in for the prompt in the cache key while the last
+// candle is still forming. Its price, volume and the indicators read from
+// it move on every run, so hashing the real prompt would miss the cache for
+// the very candle the entry is kept for. Instead the prompt is rendered
+// again from the closed candles only, with the live MTF prices masked, and
+// prefixed with what the prompt does not show: the data source and the
+// detector and calibration settings. Empty when every candle is closed; the
+// prompt is stable then and stays the key (fixtures rely on that).
+func cacheKeyPrompt(opts cliOptions, candles []Candle, symbol, tf string, srLevels []SupportResistance, patterns []Pattern, mtf *MultiTimeframe, jsonOutput bool, now time.Time) string {
+	if len(candles) < 2 || cacheExpiry(candles, tf, now).IsZero() {
+		return ""
+	}
+	iv, err := ParseInterval(tf)
+	if err != nil {
+		return ""
+	}
+	var masked *MultiTimeframe
+	if mtf != nil {
+		m := *mtf
+		m.Views = append([]TimeframeView(nil), mtf.Views...)
+		for i := range m.Views {
+			m.Views[i].Price = 0
+		}
+		masked = &m
+	}
+	closed := buildSeries(candles[:len(candles)-1], iv)
+	_, prompt, err := buildPrompt(opts.prompt, closed, symbol, tf, srLevels, patterns, masked, jsonOutput)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("provider=%s url=%s detectors=%s calibration=%s\n%s",
+		opts.Provider, opts.ProviderURL, opts.params.config(symbol, tf).hash(), opts.calibration.id(), prompt)
+}
+
+// cachedClient answers from the cache when it can. It wraps the metered
+// client, so hits are neither billed nor counted against the budget.
+type cachedClient struct {
+	LLMClient
+	cache *llmCache
+	cfg   LLMConfig
+}
+
+func (c *llmCache) wrap(client LLMClient, cfg LLMConfig) LLMClient {
+	if resolved, err := resolveLLMConfig(cfg); err == nil {
+		cfg = resolved
+	}
+	return &cachedClient{LLMClient: client, cache: c, cfg: cfg}
+}
+
+func (c *cachedClient) Complete(ctx context.Context, prompt string) (*LLMResponse, error) {
+	return c.lookup(prompt, func() (*LLMResponse, error) { return c.LLMClient.Complete(ctx, prompt) })
+}
+
+func (c *cachedClient) Stream(ctx context.Context, prompt string, onDelta func(LLMDelta)) (*LLMResponse, error) {
+	sc, ok := c.LLMClient.(streamingClient)
+	if !ok {
+		return c.Complete(ctx, prompt)
+	}
+	return c.lookup(prompt, func() (*LLMResponse, error) { return sc.Stream(ctx, prompt, onDelta) })
+}
+
+func (c *cachedClient) lookup(prompt string, call func() (*LLMResponse, error)) (*LLMResponse, error) {
+	key := cacheKey(c.cfg, prompt)
+	now := time.Now()
+	if !c.cache.refresh {
+		e, err := c.cache.get(key, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Gagal baca cache AI: %v\n", err)
+		}
+		if e != nil {
+			until := "selamanya"
+			if !e.Expires.IsZero() {
+				until = "s/d " + e.Expires.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Printf("♻️  %s: jawaban dari cache (%s, berlaku %s)\n", c.cfg.Name, e.Created.Local().Format("15:04:05"), until)
+			resp := e.Response
+			resp.Usage = LLMUsage{} // tidak ada biaya baru
+			return &resp, nil
+		}
+	}
+
+	resp, err := call()
+	if err != nil {
+		return nil, err
+	}
+	entry := cacheEntry{
+		Key: key, Backend: c.cfg.Name, Model: orDefault(resp.Model, c.cfg.Model), PromptID: c.cfg.PromptID,
+		Symbol: c.cfg.Symbol, TF: c.cfg.TF, Created: now, Expires: c.cfg.CacheUntil, Response: *resp,
+	}
+	if c.cache.fixtures {
+		entry.Expires = time.Time{}
+	}
+	if err := c.cache.put(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Gagal simpan cache AI: %v\n", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// waveCandles builds n hourly candles ending with the one that opens at
+// last, oscillating enough for levels and patterns to show up.
+func waveCandles(n int, last time.Time) []Candle {
+	candles := make([]Candle, n)
+	for i := range candles {
+		mid := 100 + 8*math.Sin(float64(i)/6) + float64(i)*0.05
+		open := last.Add(time.Duration(i-n+1) * time.Hour)
+		candles[i] = Candle{
+			Time: open, CloseTime: open.Add(time.Hour - time.Millisecond),
+			Open: decimal.NewFromFloat(mid - 0.3), High: decimal.NewFromFloat(mid + 1),
+			Low: decimal.NewFromFloat(mid - 1), Close: decimal.NewFromFloat(mid + 0.3),
+			Volume: decimal.NewFromInt(1000 + int64(i)),
+		}
+	}
+	return candles
+}
+
+func TestCacheHitsInsideFormingCandle(t *testing.T) {
+	prompt, err := loadPrompt(defaultPrompt, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	opts := cliOptions{AI: "mock", Format: "json", prompt: prompt, LLM: LLMConfig{Name: "mock", Cache: newLLMCache(dir, false)}}
+	iv := mustInterval("1h")
+
+	analyze := func(opts cliOptions, candles []Candle) (AIAnalysis, string) {
+		series := buildSeries(candles, iv)
+		levels := detectSupportResistance(candles, defaultDetectorConfig.SR)
+		patterns := detectPatterns(candles, defaultDetectorConfig.Patterns)
+		_, rendered, err := buildPrompt(prompt, series, "SOLUSDT", "1h", levels, patterns, nil, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return callSelectedAI(context.Background(), opts, candles, series, "SOLUSDT", "1h", levels, patterns, nil), rendered
+	}
+	entries := func() int {
+		files, _ := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+		return len(files)
+	}
+
+	// Candle terakhir masih berjalan: open di awal jam ini
+	candles := waveCandles(150, time.Now().Truncate(time.Hour))
+	first, firstPrompt := analyze(opts, candles)
+	if first.Plan == nil {
+		t.Fatalf("mock tidak memberi plan: %v", first.Problems)
+	}
+
+	// Run kedua di candle yang sama, harga live sudah bergerak
+	forming := &candles[len(candles)-1]
+	forming.Close = forming.Close.Add(decimal.NewFromFloat(1.7))
+	forming.High = forming.High.Add(decimal.NewFromFloat(2))
+	forming.Volume = forming.Volume.Mul(decimal.NewFromInt(3))
+	second, secondPrompt := analyze(opts, candles)
+	if firstPrompt == secondPrompt {
+		t.Fatal("prompt tidak berubah, test tidak membuktikan apa-apa")
+	}
+	if n := entries(); n != 1 {
+		t.Fatalf("cache entries = %d, mau 1 (run kedua harus hit)", n)
+	}
+	if !reflect.DeepEqual(first.Plan, second.Plan) {
+		t.Errorf("run kedua tidak dari cache:\n%+v\n%+v", first.Plan, second.Plan)
+	}
+
+	// Sumber data, params detector atau kalibrasi lain: jawaban Binance
+	// tidak boleh dipakai ulang
+	tuned := defaultDetectorConfig
+	tuned.SR.Tolerance = 0.03
+	others := map[string]func(o *cliOptions){
+		"provider": func(o *cliOptions) { o.Provider = "bybit-linear" },
+		"params": func(o *cliOptions) {
+			o.params = &detectorParams{Sets: map[string]map[string]*paramSet{"SOLUSDT": {"1h": {Config: tuned}}}}
+		},
+		"calibration": func(o *cliOptions) { o.calibration = &patternCalibration{Horizon: 10, Source: "eval"} },
+	}
+	want := 1
+	for _, name := range []string{"provider", "params", "calibration"} {
+		o := opts
+		others[name](&o)
+		analyze(o, candles)
+		want++
+		if n := entries(); n != want {
+			t.Errorf("%s beda: cache entries = %d, mau %d (harus miss)", name, n, want)
+		}
+	}
+
+	// Candle berikutnya: candle tadi sudah close, harus tanya ulang
+	analyze(opts, waveCandles(150, time.Now().Truncate(time.Hour).Add(time.Hour)))
+	if n := entries(); n != want+1 {
+		t.Errorf("cache entries = %d setelah candle baru, mau %d", n, want+1)
+	}
+}
+
+func TestCacheKeyPrompt(t *testing.T) {
+	prompt, err := loadPrompt(defaultPrompt, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := cliOptions{Provider: "binance", prompt: prompt}
+	now := time.Date(2024, 5, 1, 10, 20, 0, 0, time.UTC)
+	forming := waveCandles(50, now.Truncate(time.Hour))
+	closed := waveCandles(50, now.Truncate(time.Hour).Add(-time.Hour))
+	mtf := &MultiTimeframe{Views: []TimeframeView{{TF: "1h", Price: 101}, {TF: "4h", Price: 101}}}
+	key := func(o cliOptions, candles []Candle, mtf *MultiTimeframe) string {
+		return cacheKeyPrompt(o, candles, "SOLUSDT", "1h", nil, nil, mtf, true, now)
+	}
+
+	if got := key(opts, closed, nil); got != "" {
+		t.Errorf("semua candle close, key = %q, mau kosong", got)
+	}
+	base := key(opts, forming, mtf)
+	if !strings.HasPrefix(base, "provider=binance url= detectors="+defaultDetectorConfig.hash()+" calibration=none\n") {
+		t.Errorf("key tanpa sumber/detector/kalibrasi: %q", strings.SplitN(base, "\n", 2)[0])
+	}
+
+	// Harga live di candle forming dan di view MTF tidak ikut key
+	moved := append([]Candle(nil), forming...)
+	moved[len(moved)-1].Close = moved[len(moved)-1].Close.Add(decimal.NewFromInt(3))
+	live := &MultiTimeframe{Views: []TimeframeView{{TF: "1h", Price: 104}, {TF: "4h", Price: 104}}}
+	if got := key(opts, moved, live); got != base {
+		t.Error("key berubah karena harga candle forming")
+	}
+	if mtf.Views[0].Price != 101 {
+		t.Error("masking mengubah MTF asli")
+	}
+
+	// Candle yang sudah close berubah: key harus ikut berubah
+	edited := append([]Candle(nil), forming...)
+	edited[len(edited)-2].Close = edited[len(edited)-2].Close.Add(decimal.NewFromInt(1))
+	if key(opts, edited, mtf) == base {
+		t.Error("close candle sebelumnya berubah tapi key sama")
+	}
+	other := opts
+	other.ProviderURL = "http://127.0.0.1:9000"
+	if key(other, forming, mtf) == base {
+		t.Error("provider URL lain tapi key sama")
+	}
+}
@@ -2,13 +2,13 @@ package main
 
 import (
 	"bufio"
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math"
-	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
@@ -21,21 +21,17 @@ import (
 	"github.com/shopspring/decimal"
 )
 
-var (
-	deepseekKey string
-	grokKey     string
-)
-
 type Candle struct {
-	Time                           time.Time
+	Time                           time.Time // open time
+	CloseTime                      time.Time
 	Open, High, Low, Close, Volume decimal.Decimal
 }
 
 type SupportResistance struct {
-	Price    float64
-	Strength int
-	Type     string // "support" or "resistance"
-	Touches  int
+	Price    float64 `json:"price"`
+	Strength int     `json:"strength"`
+	Type     string  `json:"type"` // "support" or "resistance"
+	Touches  int     `json:"touches"`
 }
 
 type cluster struct {
@@ -45,57 +41,30 @@ type cluster struct {
 }
 
 type Pattern struct {
-	Name        string
-	Type        string // "bullish", "bearish", "continuation"
-	Confidence  float64
-	Description string
-	Breakout    bool
+	Name        string  `json:"name"`
+	Type        string  `json:"type"` // "bullish", "bearish", "continuation"
+	Confidence  float64 `json:"confidence"`
+	Description string  `json:"description"`
+	Breakout    bool    `json:"breakout"`
+
+	// Hit rate historis dari file kalibrasi (ai-trade eval --save)
+	Probability float64 `json:"probability,omitempty"`
+	Samples     int     `json:"samples,omitempty"`
+	Horizon     int     `json:"horizon,omitempty"`
 }
 
 func main() {
 	godotenv.Load()
-	deepseekKey = os.Getenv("DEEPSEEK_API_KEY")
-	grokKey = os.Getenv("GROK_API_KEY")
-
-	if deepseekKey == "" && grokKey == "" {
-		log.Fatal("⚠️  Isi minimal satu API key di .env!")
-	}
-
-	coinInput := askInput("Type coin name (contoh: sol, btc, eth): ")
-	symbol := strings.ToUpper(strings.TrimSpace(coinInput)) + "USDT"
 
-	tf := askInput("Input timeframe (15m / 1h / 4h / 1d): ")
-	tf = strings.TrimSpace(tf)
-	validTF := map[string]bool{"15m": true, "1h": true, "4h": true, "1d": true, "5m": true, "30m": true, "2h": true, "6h": true, "12h": true}
-	if !validTF[tf] {
-		log.Fatal("Timeframe tidak valid! Pilih: 15m, 1h, 4h, 1d, dll")
+	// Tanpa argumen → mode interaktif seperti biasa
+	if len(os.Args) < 2 {
+		runInteractive()
+		return
 	}
 
-	ai := strings.ToLower(askInput("Choose AI (deepseek / grok): "))
-	if ai != "deepseek" && ai != "grok" {
-		log.Fatal("Pilih deepseek atau grok!")
+	if err := runCommand(os.Args[1:]); err != nil {
+		log.Fatal(err)
 	}
-
-	fmt.Printf("\n🔥 Mengambil %s %s + analisa pakai %s...\n\n", symbol, tf, strings.ToUpper(ai))
-
-	candles, series := fetchData(symbol, tf)
-	
-	// Tambahan: Deteksi Support/Resistance dan Patterns
-	srLevels := detectSupportResistance(candles)
-	patterns := detectPatterns(candles)
-	
-	generateTradingChart(candles, series, symbol, tf, srLevels, patterns)
-
-	var analysis string
-	if ai == "deepseek" && deepseekKey != "" {
-		analysis = callDeepSeekWithDeepThink(candles, series, symbol, tf, srLevels, patterns) // Ganti ke DeepThink
-	} else if grokKey != "" {
-		analysis = callGrok(candles, series, symbol, tf, srLevels, patterns)
-	} else {
-		log.Fatal("API key untuk AI yang dipilih kosong!")
-	}
-
-	printBeautifulAnalysis(analysis, symbol, tf, srLevels, patterns)
 }
 
 func askInput(prompt string) string {
@@ -105,70 +74,140 @@ func askInput(prompt string) string {
 	return scanner.Text()
 }
 
-func fetchData(symbol, interval string) ([]Candle, *techan.TimeSeries) {
-	url := fmt.Sprintf("https://api.binance.com/api/v3/klines?symbol=%s&interval=%s&limit=300", symbol, interval)
-	resp, err := http.Get(url)
+func fetchData(provider CandleProvider, symbol, interval string, limit int) ([]Candle, *techan.TimeSeries, error) {
+	iv, err := ParseInterval(interval)
 	if err != nil {
-		log.Fatal("Gagal ambil data Binance:", err)
+		return nil, nil, err
 	}
-	defer resp.Body.Close()
-
-	var raw [][]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
-		log.Fatal("Error decode JSON:", err)
+	candles, err := fetchLatest(provider, symbol, interval, limit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gagal ambil data %s: %w", provider.Name(), err)
+	}
+	if len(candles) == 0 {
+		return nil, nil, &MarketError{Kind: ErrBadPayload, Provider: provider.Name(), Msg: "tidak ada candle untuk " + symbol + " " + interval}
 	}
+	return candles, buildSeries(candles, iv), nil
+}
 
+func buildSeries(candles []Candle, iv Interval) *techan.TimeSeries {
 	series := techan.NewTimeSeries()
-	var candles []Candle
-	for _, k := range raw {
-		ts := time.Unix(int64(k[0].(float64))/1000, 0)
-		o, _ := decimal.NewFromString(k[1].(string))
-		h, _ := decimal.NewFromString(k[2].(string))
-		l, _ := decimal.NewFromString(k[3].(string))
-		c, _ := decimal.NewFromString(k[4].(string))
-		v, _ := decimal.NewFromString(k[5].(string))
-
-		candles = append(candles, Candle{ts, o, h, l, c, v})
-
-		tc := techan.NewCandle(techan.TimePeriod{Start: ts, End: ts.Add(time.Hour)})
-		tc.OpenPrice, tc.HighPrice, tc.LowPrice, tc.ClosePrice, tc.Volume = o, h, l, c, v
+	for _, c := range candles {
+		end := c.CloseTime
+		if end.IsZero() {
+			end = iv.CloseTime(c.Time)
+		}
+		tc := techan.NewCandle(techan.TimePeriod{Start: c.Time, End: end})
+		tc.OpenPrice, tc.HighPrice, tc.LowPrice, tc.ClosePrice, tc.Volume = c.Open, c.High, c.Low, c.Close, c.Volume
 		series.AddCandle(tc)
 	}
-	return candles, series
+	return series
+}
+
+// ================================
+// DETECTOR CONFIG
+// ================================
+
+// srConfig holds the support/resistance detector thresholds.
+type srConfig struct {
+	SwingRadius int     `json:"swing_radius"`      // candle di tiap sisi yang harus dilewati swing
+	Tolerance   float64 `json:"cluster_tolerance"` // swing sejauh ini (fraksi) digabung jadi satu level
+}
+
+// patternConfig holds the chart pattern detector thresholds. Slopes are in
+// price per candle, so a useful SlopeCutoff depends on the price level.
+type patternConfig struct {
+	SwingRadius       int     `json:"swing_radius"`
+	HSWindow          int     `json:"hs_window"`
+	ShoulderTolerance float64 `json:"shoulder_tolerance"`
+	DoubleWindow      int     `json:"double_window"`
+	DoubleTolerance   float64 `json:"double_tolerance"`
+	TriangleWindow    int     `json:"triangle_window"`
+	SlopeCutoff       float64 `json:"slope_cutoff"`
+}
+
+type detectorConfig struct {
+	SR       srConfig      `json:"sr"`
+	Patterns patternConfig `json:"patterns"`
+}
+
+var defaultDetectorConfig = detectorConfig{
+	SR: srConfig{SwingRadius: 2, Tolerance: 0.02},
+	Patterns: patternConfig{
+		SwingRadius:       2,
+		HSWindow:          20,
+		ShoulderTolerance: 0.03,
+		DoubleWindow:      15,
+		DoubleTolerance:   0.02,
+		TriangleWindow:    10,
+		SlopeCutoff:       0.001,
+	},
+}
+
+// window is the most history any pattern detector looks at.
+func (c patternConfig) window() int {
+	return max(c.HSWindow, c.DoubleWindow, c.TriangleWindow)
+}
+
+func (c detectorConfig) validate() error {
+	p := c.Patterns
+	switch {
+	case c.SR.SwingRadius < 1 || p.SwingRadius < 1:
+		return errors.New("swing_radius minimal 1")
+	case c.SR.Tolerance <= 0 || p.ShoulderTolerance <= 0 || p.DoubleTolerance <= 0 || p.SlopeCutoff <= 0:
+		return errors.New("tolerance dan slope_cutoff harus lebih dari 0")
+	case p.HSWindow < 4*p.SwingRadius+3 || p.DoubleWindow < 3*p.SwingRadius+2:
+		return errors.New("window pattern terlalu pendek untuk swing_radius")
+	case p.TriangleWindow < 3:
+		return errors.New("triangle_window minimal 3")
+	}
+	return nil
+}
+
+// isSwingHigh reports whether candle i has a higher high than the radius
+// candles on each side. The caller keeps i at least radius from both ends.
+func isSwingHigh(candles []Candle, i, radius int) bool {
+	high := candles[i].High.InexactFloat64()
+	for j := i - radius; j <= i+radius; j++ {
+		if j != i && high <= candles[j].High.InexactFloat64() {
+			return false
+		}
+	}
+	return true
+}
+
+func isSwingLow(candles []Candle, i, radius int) bool {
+	low := candles[i].Low.InexactFloat64()
+	for j := i - radius; j <= i+radius; j++ {
+		if j != i && low >= candles[j].Low.InexactFloat64() {
+			return false
+		}
+	}
+	return true
 }
 
 // ================================
 // SUPPORT/RESISTANCE DETECTION
 // ================================
 
-func detectSupportResistance(candles []Candle) []SupportResistance {
+func detectSupportResistance(candles []Candle, cfg srConfig) []SupportResistance {
 	var swingHighs, swingLows []float64
 	
 	// Deteksi Swing Highs dan Swing Lows
-	for i := 2; i < len(candles)-2; i++ {
-		high := candles[i].High.InexactFloat64()
-		low := candles[i].Low.InexactFloat64()
-		
+	for i := cfg.SwingRadius; i < len(candles)-cfg.SwingRadius; i++ {
 		// Swing High: higher than neighbors
-		if high > candles[i-2].High.InexactFloat64() && 
-		   high > candles[i-1].High.InexactFloat64() &&
-		   high > candles[i+1].High.InexactFloat64() &&
-		   high > candles[i+2].High.InexactFloat64() {
-			swingHighs = append(swingHighs, high)
+		if isSwingHigh(candles, i, cfg.SwingRadius) {
+			swingHighs = append(swingHighs, candles[i].High.InexactFloat64())
 		}
 		
 		// Swing Low: lower than neighbors
-		if low < candles[i-2].Low.InexactFloat64() && 
-		   low < candles[i-1].Low.InexactFloat64() &&
-		   low < candles[i+1].Low.InexactFloat64() &&
-		   low < candles[i+2].Low.InexactFloat64() {
-			swingLows = append(swingLows, low)
+		if isSwingLow(candles, i, cfg.SwingRadius) {
+			swingLows = append(swingLows, candles[i].Low.InexactFloat64())
 		}
 	}
 	
-	// Clustering levels dengan tolerance 2%
-	resistanceLevels := clusterLevels(swingHighs, 0.02)
-	supportLevels := clusterLevels(swingLows, 0.02)
+	// Clustering levels dengan tolerance (default 2%)
+	resistanceLevels := clusterLevels(swingHighs, cfg.Tolerance)
+	supportLevels := clusterLevels(swingLows, cfg.Tolerance)
 	
 	var srLevels []SupportResistance
 	
@@ -258,29 +297,29 @@ func clusterLevels(prices []float64, tolerance float64) []cluster {
 // PATTERN RECOGNITION
 // ================================
 
-func detectPatterns(candles []Candle) []Pattern {
+func detectPatterns(candles []Candle, cfg patternConfig) []Pattern {
 	var patterns []Pattern
 	
 	// Deteksi semua pattern
-	if hs := detectHeadAndShoulders(candles); hs.Confidence > 0 {
+	if hs := detectHeadAndShoulders(candles, cfg); hs.Confidence > 0 {
 		patterns = append(patterns, hs)
 	}
-	if ihs := detectInverseHeadAndShoulders(candles); ihs.Confidence > 0 {
+	if ihs := detectInverseHeadAndShoulders(candles, cfg); ihs.Confidence > 0 {
 		patterns = append(patterns, ihs)
 	}
-	if dt := detectDoubleTop(candles); dt.Confidence > 0 {
+	if dt := detectDoubleTop(candles, cfg); dt.Confidence > 0 {
 		patterns = append(patterns, dt)
 	}
-	if db := detectDoubleBottom(candles); db.Confidence > 0 {
+	if db := detectDoubleBottom(candles, cfg); db.Confidence > 0 {
 		patterns = append(patterns, db)
 	}
-	if at := detectAscendingTriangle(candles); at.Confidence > 0 {
+	if at := detectAscendingTriangle(candles, cfg); at.Confidence > 0 {
 		patterns = append(patterns, at)
 	}
-	if dtri := detectDescendingTriangle(candles); dtri.Confidence > 0 {
+	if dtri := detectDescendingTriangle(candles, cfg); dtri.Confidence > 0 {
 		patterns = append(patterns, dtri)
 	}
-	if st := detectSymmetricalTriangle(candles); st.Confidence > 0 {
+	if st := detectSymmetricalTriangle(candles, cfg); st.Confidence > 0 {
 		patterns = append(patterns, st)
 	}
 	
@@ -292,13 +331,13 @@ func detectPatterns(candles []Candle) []Pattern {
 	return patterns
 }
 
-func detectHeadAndShoulders(candles []Candle) Pattern {
-	if len(candles) < 20 {
+func detectHeadAndShoulders(candles []Candle, cfg patternConfig) Pattern {
+	if len(candles) < cfg.HSWindow {
 		return Pattern{Confidence: 0}
 	}
 	
-	// Cari pattern dalam window 20 candles terakhir
-	recent := candles[len(candles)-20:]
+	// Cari pattern dalam window candles terakhir (default 20)
+	recent := candles[len(candles)-cfg.HSWindow:]
 	
 	var peaks []struct {
 		Index int
@@ -306,11 +345,8 @@ func detectHeadAndShoulders(candles []Candle) Pattern {
 	}
 	
 	// Deteksi peaks
-	for i := 2; i < len(recent)-2; i++ {
-		if recent[i].High.InexactFloat64() > recent[i-1].High.InexactFloat64() &&
-		   recent[i].High.InexactFloat64() > recent[i-2].High.InexactFloat64() &&
-		   recent[i].High.InexactFloat64() > recent[i+1].High.InexactFloat64() &&
-		   recent[i].High.InexactFloat64() > recent[i+2].High.InexactFloat64() {
+	for i := cfg.SwingRadius; i < len(recent)-cfg.SwingRadius; i++ {
+		if isSwingHigh(recent, i, cfg.SwingRadius) {
 			peaks = append(peaks, struct {
 				Index int
 				High  float64
@@ -328,9 +364,9 @@ func detectHeadAndShoulders(candles []Candle) Pattern {
 		
 		// Head harus lebih tinggi dari shoulders
 		if head.High > left.High && head.High > right.High {
-			// Shoulders harus memiliki tinggi yang seimbang (dalam tolerance 3%)
+			// Shoulders harus memiliki tinggi yang seimbang (dalam tolerance, default 3%)
 			shoulderDiff := math.Abs(left.High-right.High) / math.Max(left.High, right.High)
-			if shoulderDiff <= 0.03 {
+			if shoulderDiff <= cfg.ShoulderTolerance {
 				conf := 0.7 + (0.3 * (1 - shoulderDiff)) // Confidence berdasarkan symmetry
 				return Pattern{
 					Name:        "Head and Shoulders",
@@ -346,12 +382,12 @@ func detectHeadAndShoulders(candles []Candle) Pattern {
 	return Pattern{Confidence: 0}
 }
 
-func detectInverseHeadAndShoulders(candles []Candle) Pattern {
-	if len(candles) < 20 {
+func detectInverseHeadAndShoulders(candles []Candle, cfg patternConfig) Pattern {
+	if len(candles) < cfg.HSWindow {
 		return Pattern{Confidence: 0}
 	}
 	
-	recent := candles[len(candles)-20:]
+	recent := candles[len(candles)-cfg.HSWindow:]
 	
 	var troughs []struct {
 		Index int
@@ -359,11 +395,8 @@ func detectInverseHeadAndShoulders(candles []Candle) Pattern {
 	}
 	
 	// Deteksi troughs (lows)
-	for i := 2; i < len(recent)-2; i++ {
-		if recent[i].Low.InexactFloat64() < recent[i-1].Low.InexactFloat64() &&
-		   recent[i].Low.InexactFloat64() < recent[i-2].Low.InexactFloat64() &&
-		   recent[i].Low.InexactFloat64() < recent[i+1].Low.InexactFloat64() &&
-		   recent[i].Low.InexactFloat64() < recent[i+2].Low.InexactFloat64() {
+	for i := cfg.SwingRadius; i < len(recent)-cfg.SwingRadius; i++ {
+		if isSwingLow(recent, i, cfg.SwingRadius) {
 			troughs = append(troughs, struct {
 				Index int
 				Low   float64
@@ -381,9 +414,9 @@ func detectInverseHeadAndShoulders(candles []Candle) Pattern {
 		
 		// Head harus lebih rendah dari shoulders
 		if head.Low < left.Low && head.Low < right.Low {
-			// Shoulders harus memiliki rendah yang seimbang (dalam tolerance 3%)
+			// Shoulders harus memiliki rendah yang seimbang (dalam tolerance, default 3%)
 			shoulderDiff := math.Abs(left.Low-right.Low) / math.Max(left.Low, right.Low)
-			if shoulderDiff <= 0.03 {
+			if shoulderDiff <= cfg.ShoulderTolerance {
 				conf := 0.7 + (0.3 * (1 - shoulderDiff))
 				return Pattern{
 					Name:        "Inverse Head and Shoulders",
@@ -399,12 +432,12 @@ func detectInverseHeadAndShoulders(candles []Candle) Pattern {
 	return Pattern{Confidence: 0}
 }
 
-func detectDoubleTop(candles []Candle) Pattern {
-	if len(candles) < 15 {
+func detectDoubleTop(candles []Candle, cfg patternConfig) Pattern {
+	if len(candles) < cfg.DoubleWindow {
 		return Pattern{Confidence: 0}
 	}
 	
-	recent := candles[len(candles)-15:]
+	recent := candles[len(candles)-cfg.DoubleWindow:]
 	
 	var peaks []struct {
 		Index int
@@ -412,11 +445,8 @@ func detectDoubleTop(candles []Candle) Pattern {
 	}
 	
 	// Deteksi peaks
-	for i := 2; i < len(recent)-2; i++ {
-		if recent[i].High.InexactFloat64() > recent[i-1].High.InexactFloat64() &&
-		   recent[i].High.InexactFloat64() > recent[i-2].High.InexactFloat64() &&
-		   recent[i].High.InexactFloat64() > recent[i+1].High.InexactFloat64() &&
-		   recent[i].High.InexactFloat64() > recent[i+2].High.InexactFloat64() {
+	for i := cfg.SwingRadius; i < len(recent)-cfg.SwingRadius; i++ {
+		if isSwingHigh(recent, i, cfg.SwingRadius) {
 			peaks = append(peaks, struct {
 				Index int
 				High  float64
@@ -432,7 +462,7 @@ func detectDoubleTop(candles []Candle) Pattern {
 	for i := 0; i < len(peaks)-1; i++ {
 		for j := i + 1; j < len(peaks); j++ {
 			diff := math.Abs(peaks[i].High-peaks[j].High) / math.Max(peaks[i].High, peaks[j].High)
-			if diff <= 0.02 { // 2% tolerance
+			if diff <= cfg.DoubleTolerance { // default 2% tolerance
 				conf := 0.8 * (1 - diff)
 				return Pattern{
 					Name:        "Double Top",
@@ -448,12 +478,12 @@ func detectDoubleTop(candles []Candle) Pattern {
 	return Pattern{Confidence: 0}
 }
 
-func detectDoubleBottom(candles []Candle) Pattern {
-	if len(candles) < 15 {
+func detectDoubleBottom(candles []Candle, cfg patternConfig) Pattern {
+	if len(candles) < cfg.DoubleWindow {
 		return Pattern{Confidence: 0}
 	}
 	
-	recent := candles[len(candles)-15:]
+	recent := candles[len(candles)-cfg.DoubleWindow:]
 	
 	var troughs []struct {
 		Index int
@@ -461,11 +491,8 @@ func detectDoubleBottom(candles []Candle) Pattern {
 	}
 	
 	// Deteksi troughs
-	for i := 2; i < len(recent)-2; i++ {
-		if recent[i].Low.InexactFloat64() < recent[i-1].Low.InexactFloat64() &&
-		   recent[i].Low.InexactFloat64() < recent[i-2].Low.InexactFloat64() &&
-		   recent[i].Low.InexactFloat64() < recent[i+1].Low.InexactFloat64() &&
-		   recent[i].Low.InexactFloat64() < recent[i+2].Low.InexactFloat64() {
+	for i := cfg.SwingRadius; i < len(recent)-cfg.SwingRadius; i++ {
+		if isSwingLow(recent, i, cfg.SwingRadius) {
 			troughs = append(troughs, struct {
 				Index int
 				Low   float64
@@ -481,7 +508,7 @@ func detectDoubleBottom(candles []Candle) Pattern {
 	for i := 0; i < len(troughs)-1; i++ {
 		for j := i + 1; j < len(troughs); j++ {
 			diff := math.Abs(troughs[i].Low-troughs[j].Low) / math.Max(troughs[i].Low, troughs[j].Low)
-			if diff <= 0.02 { // 2% tolerance
+			if diff <= cfg.DoubleTolerance { // default 2% tolerance
 				conf := 0.8 * (1 - diff)
 				return Pattern{
 					Name:        "Double Bottom",
@@ -497,19 +524,19 @@ func detectDoubleBottom(candles []Candle) Pattern {
 	return Pattern{Confidence: 0}
 }
 
-func detectAscendingTriangle(candles []Candle) Pattern {
-	if len(candles) < 10 {
+func detectAscendingTriangle(candles []Candle, cfg patternConfig) Pattern {
+	if len(candles) < cfg.TriangleWindow {
 		return Pattern{Confidence: 0}
 	}
 	
-	recent := candles[len(candles)-10:]
+	recent := candles[len(candles)-cfg.TriangleWindow:]
 	
 	// Hitung slope untuk highs dan lows
 	highSlope := calculateSlope(recent, true)
 	lowSlope := calculateSlope(recent, false)
 	
 	// Ascending triangle: horizontal resistance, rising support
-	if highSlope < 0.001 && lowSlope > 0.001 { // Highs flat, lows rising
+	if highSlope < cfg.SlopeCutoff && lowSlope > cfg.SlopeCutoff { // Highs flat, lows rising
 		conf := 0.6 + 0.4*math.Min(math.Abs(lowSlope), 0.01)/0.01
 		return Pattern{
 			Name:        "Ascending Triangle",
@@ -523,19 +550,19 @@ func detectAscendingTriangle(candles []Candle) Pattern {
 	return Pattern{Confidence: 0}
 }
 
-func detectDescendingTriangle(candles []Candle) Pattern {
-	if len(candles) < 10 {
+func detectDescendingTriangle(candles []Candle, cfg patternConfig) Pattern {
+	if len(candles) < cfg.TriangleWindow {
 		return Pattern{Confidence: 0}
 	}
 	
-	recent := candles[len(candles)-10:]
+	recent := candles[len(candles)-cfg.TriangleWindow:]
 	
 	// Hitung slope untuk highs dan lows
 	highSlope := calculateSlope(recent, true)
 	lowSlope := calculateSlope(recent, false)
 	
 	// Descending triangle: horizontal support, falling resistance
-	if lowSlope > -0.001 && highSlope < -0.001 { // Lows flat, highs falling
+	if lowSlope > -cfg.SlopeCutoff && highSlope < -cfg.SlopeCutoff { // Lows flat, highs falling
 		conf := 0.6 + 0.4*math.Min(math.Abs(highSlope), 0.01)/0.01
 		return Pattern{
 			Name:        "Descending Triangle",
@@ -549,19 +576,19 @@ func detectDescendingTriangle(candles []Candle) Pattern {
 	return Pattern{Confidence: 0}
 }
 
-func detectSymmetricalTriangle(candles []Candle) Pattern {
-	if len(candles) < 10 {
+func detectSymmetricalTriangle(candles []Candle, cfg patternConfig) Pattern {
+	if len(candles) < cfg.TriangleWindow {
 		return Pattern{Confidence: 0}
 	}
 	
-	recent := candles[len(candles)-10:]
+	recent := candles[len(candles)-cfg.TriangleWindow:]
 	
 	// Hitung slope untuk highs dan lows
 	highSlope := calculateSlope(recent, true)
 	lowSlope := calculateSlope(recent, false)
 	
 	// Symmetrical triangle: converging highs and lows
-	if highSlope < -0.001 && lowSlope > 0.001 && math.Abs(highSlope-lowSlope) < 0.002 {
+	if highSlope < -cfg.SlopeCutoff && lowSlope > cfg.SlopeCutoff && math.Abs(highSlope-lowSlope) < 2*cfg.SlopeCutoff {
 		conf := 0.5 + 0.5*math.Min(math.Abs(highSlope)+math.Abs(lowSlope), 0.02)/0.02
 		return Pattern{
 			Name:        "Symmetrical Triangle",
@@ -600,7 +627,7 @@ func calculateSlope(candles []Candle, useHigh bool) float64 {
 }
 
 // PROFESSIONAL TRADING CHART dengan Support/Resistance dan Patterns
-func generateTradingChart(candles []Candle, series *techan.TimeSeries, symbol, tf string, srLevels []SupportResistance, patterns []Pattern) {
+func generateTradingChart(candles []Candle, series *techan.TimeSeries, symbol, tf string, srLevels []SupportResistance, patterns []Pattern) (string, error) {
 	close := techan.NewClosePriceIndicator(series)
 	ema5 := techan.NewEMAIndicator(close, 5)
 	ema10 := techan.NewEMAIndicator(close, 10)
@@ -621,8 +648,13 @@ func generateTradingChart(candles []Candle, series *techan.TimeSeries, symbol, t
 	// Tambahkan data untuk Support/Resistance lines
 	var supportLines, resistanceLines []opts.LineData
 
+	axisLayout := "01-02 15:04"
+	if iv, err := ParseInterval(tf); err == nil {
+		axisLayout = iv.AxisLayout()
+	}
+
 	for i, c := range candles {
-		xAxis = append(xAxis, c.Time.Format("01-02 15:04"))
+		xAxis = append(xAxis, c.Time.Format(axisLayout))
 		
 		klineData = append(klineData, opts.KlineData{
 			Value: [4]float64{
@@ -889,9 +921,14 @@ func generateTradingChart(candles []Candle, series *techan.TimeSeries, symbol, t
 	page.AddCharts(kline, volume, rsiChart, macdChart)
 
 	filename := fmt.Sprintf("%s_%s.html", symbol, tf)
-	f, _ := os.Create(filename)
+	f, err := os.Create(filename)
+	if err != nil {
+		return "", fmt.Errorf("buat chart %s: %w", filename, err)
+	}
 	defer f.Close()
-	page.Render(f)
+	if err := page.Render(f); err != nil {
+		return "", fmt.Errorf("render chart %s: %w", filename, err)
+	}
 
 	fmt.Printf("✅ Chart disimpan → %s\n", filename)
 	
@@ -909,314 +946,7 @@ func generateTradingChart(candles []Candle, series *techan.TimeSeries, symbol, t
 				emoji, pattern.Name, pattern.Confidence*100, pattern.Description)
 		}
 	}
-}
-
-func buildPrompt(series *techan.TimeSeries, symbol, tf string, srLevels []SupportResistance, patterns []Pattern) string {
-	close := techan.NewClosePriceIndicator(series)
-	ema5 := techan.NewEMAIndicator(close, 5)
-	ema10 := techan.NewEMAIndicator(close, 10)
-	ema30 := techan.NewEMAIndicator(close, 30)
-	bb := techan.NewBollingerBandIndicator(close, 20, 2.0)
-	rsi := techan.NewRSIIndicator(close, 14)
-	macd := techan.NewMACDIndicator(close, 12, 26)
-	signal := techan.NewEMAIndicator(macd, 9)
-
-	last := series.LastIndex()
-	macdVal := macd.Calculate(last).InexactFloat64()
-	signalVal := signal.Calculate(last).InexactFloat64()
-	
-	// Build support/resistance string
-	var srInfo strings.Builder
-	if len(srLevels) > 0 {
-		srInfo.WriteString("\n\n🎯 SUPPORT/RESISTANCE LEVELS:")
-		for _, level := range srLevels {
-			emoji := "🟢"
-			if level.Type == "resistance" {
-				emoji = "🔴"
-			}
-			srInfo.WriteString(fmt.Sprintf("\n%s %s: $%.4f (Strength: %d touches)", 
-				emoji, strings.ToUpper(level.Type), level.Price, level.Touches))
-		}
-	}
-	
-	// Build patterns string
-	var patternsInfo strings.Builder
-	if len(patterns) > 0 {
-		patternsInfo.WriteString("\n\n🎭 DETECTED PATTERNS:")
-		for _, pattern := range patterns {
-			emoji := "🟢"
-			if pattern.Type == "bearish" {
-				emoji = "🔴"
-			} else if pattern.Type == "continuation" {
-				emoji = "🟡"
-			}
-			patternsInfo.WriteString(fmt.Sprintf("\n%s %s (%s, %.0f%% confidence) - %s", 
-				emoji, pattern.Name, pattern.Type, pattern.Confidence*100, pattern.Description))
-		}
-	}
-	
-	data := map[string]string{
-		"coin":          symbol,
-		"timeframe":     tf,
-		"price":         close.Calculate(last).StringFixed(4),
-		"ema5":          ema5.Calculate(last).StringFixed(4),
-		"ema10":         ema10.Calculate(last).StringFixed(4),
-		"ema30":         ema30.Calculate(last).StringFixed(4),
-		"bb_upper":      bb.UpperBand(last).StringFixed(4),
-		"bb_middle":     bb.MiddleBand(last).StringFixed(4),
-		"bb_lower":      bb.LowerBand(last).StringFixed(4),
-		"rsi":           rsi.Calculate(last).StringFixed(2),
-		"macd":          fmt.Sprintf("%.4f", macdVal),
-		"macd_signal":   fmt.Sprintf("%.4f", signalVal),
-		"macd_hist":     fmt.Sprintf("%.4f", macdVal-signalVal),
-	}
-	jsonData, _ := json.MarshalIndent(data, "", "  ")
-
-	return fmt.Sprintf(`Analisa %s timeframe %s.
-
-DATA TEKNIKAL:
-%s
-%s
-%s
-
-Jawab DALAM FORMAT INI SAJA:
-
-📊 %s ANALISA (%s)
-
-📈 Trend Saat Ini: ...
-🔥 Kekuatan Trend: Strong / Medium / Weak
-🎯 Support Kuat: ...
-🛡️ Resistance Kuat: ...
-
-⚡ ENTRY ZONE / ORDER SETUP
-├ Buy Zone: $xxx - $xxx
-func buildPrompt(series *techan.TimeSeries, symbol, tf string, srLevels []SupportResistance, patterns []Pattern) string {
-	close := techan.NewClosePriceIndicator(series)
-	ema5 := techan.NewEMAIndicator(close, 5)
-	ema10 := techan.NewEMAIndicator(close, 10)
-	ema30 := techan.NewEMAIndicator(close, 30)
-	ema50 := techan.NewEMAIndicator(close, 50)
-	ema200 := techan.NewEMAIndicator(close, 200)
-	bb := techan.NewBollingerBandIndicator(close, 20, 2.0)
-	rsi := techan.NewRSIIndicator(close, 14)
-	macd := techan.NewMACDIndicator(close, 12, 26)
-	signal := techan.NewEMAIndicator(macd, 9)
-	
-	// Additional indicators for deeper analysis
-	volume := techan.NewVolumeIndicator(series)
-	atr := techan.NewAverageTrueRangeIndicator(series, 14)
-	
-	last := series.LastIndex()
-	prev := last - 1
-
-	// Calculate price changes
-	currentPrice := close.Calculate(last).InexactFloat64()
-	prevPrice := close.Calculate(prev).InexactFloat64()
-	priceChange := ((currentPrice - prevPrice) / prevPrice) * 100
-
-	// Calculate trend strength
-	trendStrength := calculateTrendStrength(series)
-	volatility := calculateVolatility(series)
-
-	// Build comprehensive technical data
-	technicalData := map[string]interface{}{
-		"symbol":            symbol,
-		"timeframe":         tf,
-		"current_price":     fmt.Sprintf("%.4f", currentPrice),
-		"price_change_24h":  fmt.Sprintf("%.2f%%", priceChange),
-		"trend_strength":    trendStrength,
-		"volatility":        fmt.Sprintf("%.2f%%", volatility*100),
-		
-		// Moving Averages
-		"ema_5":    ema5.Calculate(last).StringFixed(4),
-		"ema_10":   ema10.Calculate(last).StringFixed(4),
-		"ema_30":   ema30.Calculate(last).StringFixed(4),
-		"ema_50":   ema50.Calculate(last).StringFixed(4),
-		"ema_200":  ema200.Calculate(last).StringFixed(4),
-		
-		// EMA Alignment
-		"ema_alignment": getEMAAlignment(ema5, ema10, ema30, ema50, ema200, last),
-		
-		// Bollinger Bands
-		"bb_upper":      bb.UpperBand(last).StringFixed(4),
-		"bb_middle":     bb.MiddleBand(last).StringFixed(4),
-		"bb_lower":      bb.LowerBand(last).StringFixed(4),
-		"bb_position":   getBBPosition(close, bb, last),
-		"bb_squeeze":    isBBSqueeze(bb, last),
-		
-		// Oscillators
-		"rsi":          rsi.Calculate(last).StringFixed(2),
-		"rsi_trend":    getRSITrend(rsi, last),
-		"macd":         fmt.Sprintf("%.4f", macd.Calculate(last).InexactFloat64()),
-		"macd_signal":  fmt.Sprintf("%.4f", signal.Calculate(last).InexactFloat64()),
-		"macd_hist":    fmt.Sprintf("%.4f", macd.Calculate(last).InexactFloat64()-signal.Calculate(last).InexactFloat64()),
-		"macd_trend":   getMACDTrend(macd, signal, last),
-		
-		// Volume Analysis
-		"volume":          volume.Calculate(last).StringFixed(2),
-		"volume_trend":    getVolumeTrend(volume, last),
-		"volume_vs_avg":   getVolumeVsAverage(volume, last),
-		
-		// Volatility
-		"atr":          atr.Calculate(last).StringFixed(4),
-		"atr_percent":  fmt.Sprintf("%.2f%%", (atr.Calculate(last).InexactFloat64()/currentPrice)*100),
-	}
-
-	// Build Support/Resistance levels with strength analysis
-	var srLevelsInfo []map[string]interface{}
-	for _, level := range srLevels {
-		distance := ((currentPrice - level.Price) / currentPrice) * 100
-		srLevelsInfo = append(srLevelsInfo, map[string]interface{}{
-			"type":      level.Type,
-			"price":     fmt.Sprintf("%.4f", level.Price),
-			"strength":  level.Strength,
-			"distance":  fmt.Sprintf("%.2f%%", math.Abs(distance)),
-			"direction": getDirectionFromPrice(currentPrice, level.Price),
-		})
-	}
-
-	// Build Patterns with confidence and implications
-	var patternsInfo []map[string]interface{}
-	for _, pattern := range patterns {
-		patternsInfo = append(patternsInfo, map[string]interface{}{
-			"name":        pattern.Name,
-			"type":        pattern.Type,
-			"confidence":  fmt.Sprintf("%.0f%%", pattern.Confidence*100),
-			"description": pattern.Description,
-			"implication": getPatternImplication(pattern),
-			"timeframe":   getPatternTimeframeImplication(pattern, tf),
-		})
-	}
-
-	// Build market structure analysis
-	marketStructure := analyzeMarketStructure(series, srLevels)
-
-	// Compile all data
-	analysisData := map[string]interface{}{
-		"technical_indicators": technicalData,
-		"support_resistance":   srLevelsInfo,
-		"patterns":             patternsInfo,
-		"market_structure":     marketStructure,
-		"time_analysis":        getTimeAnalysis(tf),
-	}
-
-	jsonData, _ := json.MarshalIndent(analysisData, "", "  ")
-
-	// Enhanced prompt template for DeepThink
-	prompt := fmt.Sprintf(`# CRYPTO TRADING DEEP ANALYSIS REQUEST
-
-## ASSET: %s (%s)
-## ANALYSIS TYPE: DEEPTHINK TECHNICAL ANALYSIS
-## TIMESTAMP: %s
-
-## RAW TECHNICAL DATA:
-%s
-
-## ANALYSIS INSTRUCTIONS:
-
-### 1. MARKET STRUCTURE ANALYSIS
-- Identify primary trend (bullish/bearish/neutral)
-- Analyze market cycle position (accumulation/uptrend/distribution/downtrend)
-- Assess trend strength and sustainability
-- Identify key market structure levels
-
-### 2. MULTI-TIMEFRAME CONTEXT
-- Implicit higher timeframe analysis (even though data is %s)
-- Consider where this fits in daily/weekly context
-- Analyze momentum across different time perspectives
-
-### 3. PROBABILITY-WEIGHTED SCENARIOS
-Develop 3 main scenarios with probability estimates:
-
-#### 🟢 BULLISH SCENARIO (X%% probability)
-- Trigger conditions
-- Price targets
-- Confirmation signals
-- Risk factors
-
-#### 🟡 NEUTRAL/RANGE SCENARIO (X%% probability)  
-- Range boundaries
-- Accumulation zones
-- Breakout triggers
-- Time decay considerations
-
-#### 🔴 BEARISH SCENARIO (X%% probability)
-- Breakdown levels
-- Target projections
-- Warning signs
-- Capitulation points
-
-### 4. TRADING SETUP IDENTIFICATION
-- Optimal entry zones with confluence
-- High-probability triggers (price action + volume)
-- Stop loss placement with technical justification
-- Take profit targets (multi-level)
-- Position sizing recommendations
-
-### 5. RISK MANAGEMENT FRAMEWORK
-- Key risk factors (market-wide and asset-specific)
-- Early warning signals for each scenario
-- Contingency plans for scenario invalidation
-- Risk-reward assessment for each setup
-
-### 6. CONFIDENCE METRICS
-- Setup quality score (1-10)
-- Risk-reward ratio calculation
-- Market alignment score
-- Timing confidence
-
-## REQUIRED OUTPUT FORMAT:
-
-📊 [SYMBOL] DEEPTHINK ANALYSIS ([TIMEFRAME])
-
-🎯 MARKET STRUCTURE & CONTEXT
-• Primary Trend: ...
-• Market Cycle: ...
-• Key MS Levels: ...
-• Trend Strength: X/10
-
-🔍 TECHNICAL POSITIONING  
-• Price vs EMAs: ...
-• RSI Momentum: ...
-• Volume Profile: ...
-• Volatility State: ...
-
-📈 PROBABILITY SCENARIOS
-├ 🟢 Bullish (XX%%) - [Brief description]
-├ 🟡 Neutral (XX%%) - [Brief description] 
-└ 🔴 Bearish (XX%%) - [Brief description]
-
-⚡ HIGH-CONVICTION SETUP
-├ Optimal Entry: $XXX - $XXX
-├ Trigger: [Specific price action/pattern]
-├ TP1: $XXX (X.X%% gain)
-├ TP2: $XXX (X.X%% gain)
-├ TP3: $XXX (X.X%% gain)
-└ SL: $XXX (X.X%% risk) | RR: 1:X.X
-
-🎚️ RISK PARAMETERS
-• Max Position Size: X%% portfolio
-• Key Risk: [Main risk factor]
-• Early Warning: [First sign of invalidation]
-• Hedge Consideration: [If applicable]
-
-📊 CONFIDENCE METRICS
-• Setup Quality: X/10
-• Risk-Reward: 1:X.X
-• Market Alignment: X/10
-• Timing Score: X/10
-
-💡 STRATEGIC INSIGHTS
-• Market Sentiment Alignment: ...
-• Catalyst Watch: ...
-• Alternative Scenarios: ...
-
-⚠️ RISK DISCLAIMER
-This analysis is for educational purposes. Always do your own research and manage risk appropriately.`,
-		symbol, tf, time.Now().Format("2006-01-02 15:04:05"), 
-		jsonData, tf)
-
-	return prompt
+	return filename, nil
 }
 
 // ================================
@@ -1307,7 +1037,6 @@ func getBBPosition(close techan.Indicator, bb techan.BollingerBandIndicator, idx
 	price := close.Calculate(idx).InexactFloat64()
 	upper := bb.UpperBand(idx).InexactFloat64()
 	lower := bb.LowerBand(idx).InexactFloat64()
-	middle := bb.MiddleBand(idx).InexactFloat64()
 
 	position := (price - lower) / (upper - lower) * 100
 
@@ -1380,7 +1109,6 @@ func getVolumeTrend(volume techan.Indicator, idx int) string {
 	}
 
 	current := volume.Calculate(idx).InexactFloat64()
-	prev := volume.Calculate(idx - 1).InexactFloat64()
 	avg := 0.0
 
 	for i := 0; i < 5; i++ {
@@ -1447,11 +1175,13 @@ func getPatternImplication(pattern Pattern) string {
 
 func getPatternTimeframeImplication(pattern Pattern, tf string) string {
 	baseImplication := "Short-term"
-	switch tf {
-	case "1d", "12h":
-		baseImplication = "Medium-term"
-	case "3d", "1w":
-		baseImplication = "Long-term"
+	if iv, err := ParseInterval(tf); err == nil {
+		switch d := iv.Duration(); {
+		case d >= 72*time.Hour:
+			baseImplication = "Long-term"
+		case d >= 12*time.Hour:
+			baseImplication = "Medium-term"
+		}
 	}
 
 	if strings.Contains(pattern.Name, "Head and Shoulders") || strings.Contains(pattern.Name, "Double") {
@@ -1461,25 +1191,22 @@ func getPatternTimeframeImplication(pattern Pattern, tf string) string {
 	}
 }
 
-func analyzeMarketStructure(series *techan.TimeSeries, srLevels []SupportResistance) map[string]interface{} {
+// MarketStructure locates the price between the nearest S/R levels.
+type MarketStructure struct {
+	NearestSupport    string `json:"nearest_support"`
+	NearestResistance string `json:"nearest_resistance"`
+	PricePosition     string `json:"price_position"`
+	KeyLevelsQuality  int    `json:"key_levels_quality"`
+	MarketBalance     string `json:"market_balance"`
+}
+
+func analyzeMarketStructure(series *techan.TimeSeries, srLevels []SupportResistance) MarketStructure {
 	close := techan.NewClosePriceIndicator(series)
 	last := series.LastIndex()
 	currentPrice := close.Calculate(last).InexactFloat64()
 
 	// Find nearest support and resistance
-	var nearestSupport, nearestResistance *SupportResistance
-	minSupportDist, minResistanceDist := math.MaxFloat64, math.MaxFloat64
-
-	for _, level := range srLevels {
-		dist := math.Abs(level.Price - currentPrice)
-		if level.Type == "support" && dist < minSupportDist {
-			minSupportDist = dist
-			nearestSupport = &level
-		} else if level.Type == "resistance" && dist < minResistanceDist {
-			minResistanceDist = dist
-			nearestResistance = &level
-		}
-	}
+	nearestSupport, nearestResistance := nearestLevels(srLevels, currentPrice)
 
 	supportDist := "N/A"
 	resistanceDist := "N/A"
@@ -1491,13 +1218,29 @@ func analyzeMarketStructure(series *techan.TimeSeries, srLevels []SupportResista
 		resistanceDist = fmt.Sprintf("%.2f%%", (nearestResistance.Price-currentPrice)/currentPrice*100)
 	}
 
-	return map[string]interface{}{
-		"nearest_support":       supportDist,
-		"nearest_resistance":    resistanceDist,
-		"price_position":        getPricePositionInRange(nearestSupport, nearestResistance, currentPrice),
-		"key_levels_quality":    len(srLevels),
-		"market_balance":        assessMarketBalance(series, srLevels),
+	return MarketStructure{
+		NearestSupport:    supportDist,
+		NearestResistance: resistanceDist,
+		PricePosition:     getPricePositionInRange(nearestSupport, nearestResistance, currentPrice),
+		KeyLevelsQuality:  len(srLevels),
+		MarketBalance:     assessMarketBalance(series, srLevels),
+	}
+}
+
+// nearestLevels returns the support and resistance closest to price.
+func nearestLevels(srLevels []SupportResistance, price float64) (support, resistance *SupportResistance) {
+	minSupportDist, minResistanceDist := math.MaxFloat64, math.MaxFloat64
+	for i, level := range srLevels {
+		dist := math.Abs(level.Price - price)
+		if level.Type == "support" && dist < minSupportDist {
+			minSupportDist = dist
+			support = &srLevels[i]
+		} else if level.Type == "resistance" && dist < minResistanceDist {
+			minResistanceDist = dist
+			resistance = &srLevels[i]
+		}
 	}
+	return support, resistance
 }
 
 func getPricePositionInRange(support, resistance *SupportResistance, currentPrice float64) string {
@@ -1542,29 +1285,40 @@ func assessMarketBalance(series *techan.TimeSeries, srLevels []SupportResistance
 	}
 }
 
-func getTimeAnalysis(tf string) map[string]interface{} {
-	now := time.Now()
+type TimeAnalysis struct {
+	Timeframe      string `json:"timeframe"`
+	AnalysisPeriod string `json:"analysis_period"`
+	WeekOfYear     int    `json:"week_of_year"`
+	Session        string `json:"session"`
+	OptimalHours   string `json:"optimal_hours"`
+}
+
+func getTimeAnalysis(tf string, now time.Time) TimeAnalysis {
 	_, week := now.ISOWeek()
-	
-	return map[string]interface{}{
-		"timeframe":        tf,
-		"analysis_period":  getAnalysisPeriod(tf),
-		"week_of_year":     week,
-		"session":          getTradingSession(now),
-		"optimal_hours":    getOptimalTradingHours(tf),
+
+	return TimeAnalysis{
+		Timeframe:      tf,
+		AnalysisPeriod: getAnalysisPeriod(tf),
+		WeekOfYear:     week,
+		Session:        getTradingSession(now),
+		OptimalHours:   getOptimalTradingHours(tf),
 	}
 }
 
 func getAnalysisPeriod(tf string) string {
-	switch tf {
-	case "15m", "5m":
+	iv, err := ParseInterval(tf)
+	if err != nil {
+		return "Variable"
+	}
+	switch d := iv.Duration(); {
+	case d <= 15*time.Minute:
 		return "Intraday (1-3 days)"
-	case "1h", "4h":
+	case d <= 4*time.Hour:
 		return "Short-term (1-2 weeks)"
-	case "1d":
+	case d <= 24*time.Hour:
 		return "Medium-term (2-4 weeks)"
 	default:
-		return "Variable"
+		return "Long-term (1-3 months)"
 	}
 }
 
@@ -1594,77 +1348,144 @@ func getOptimalTradingHours(tf string) string {
 		return "Monitor key level breaks"
 	}
 }
-func callDeepSeek(candles []Candle, series *techan.TimeSeries, symbol, tf string, srLevels []SupportResistance, patterns []Pattern) string {
-	prompt := buildPrompt(series, symbol, tf, srLevels, patterns)
-	payload := map[string]any{
-		"model":    "deepseek-chat",
-		"messages": []map[string]string{{"role": "user", "content": prompt}},
+// callLLM runs a rendered prompt through the configured backend, streaming
+// it to the terminal when cfg.Stream is set. Failures come back as text so
+// the report still prints.
+func callLLM(ctx context.Context, cfg LLMConfig, prompt string) LLMResponse {
+	var client LLMClient = replayClient{name: cfg.Name}
+	if cfg.Cache == nil || !cfg.Cache.replay {
+		var err error
+		if client, err = newLLMClient(cfg); err != nil {
+			return LLMResponse{Content: "Error API: " + err.Error()}
+		}
+	}
+	if cfg.Meter != nil {
+		client = cfg.Meter.wrap(client, cfg)
 	}
-	return callAI("https://api.deepseek.com/chat/completions", deepseekKey, payload)
+	if cfg.Cache != nil {
+		client = cfg.Cache.wrap(client, cfg)
+	}
+
+	var resp *LLMResponse
+	var err error
+	if sc, ok := client.(streamingClient); ok && cfg.Stream {
+		// Ikut --format, bukan JSONMode: DeepThink mematikan JSONMode di wire
+		// tapi jawabannya tetap JSON mentah yang dirender setelah selesai
+		printer := newStreamPrinter(cfg.ShowReasoning, cfg.ShowContent)
+		resp, err = sc.Stream(ctx, prompt, printer.Delta)
+		printer.Done()
+		if err == nil {
+			resp.streamed = printer.Printed()
+		}
+	} else {
+		resp, err = client.Complete(ctx, prompt)
+	}
+	if ctx.Err() != nil {
+		return LLMResponse{Content: "Error API: dibatalkan"}
+	}
+	if err != nil {
+		return LLMResponse{Content: "Error API: " + err.Error()}
+	}
+	return *resp
 }
 
-func callGrok(candles []Candle, series *techan.TimeSeries, symbol, tf string, srLevels []SupportResistance, patterns []Pattern) string {
-	prompt := buildPrompt(series, symbol, tf, srLevels, patterns)
-	payload := map[string]any{
-		"model":    "grok-beta",
-		"messages": []map[string]string{{"role": "user", "content": prompt}},
+// callDeepSeek is the fast mode: deepseek-chat, no reasoning trace.
+func callDeepSeek(ctx context.Context, cfg LLMConfig, prompt string) LLMResponse {
+	cfg.Name = "deepseek"
+	return callLLM(ctx, cfg, prompt)
+}
+
+const deepseekReasonerModel = "deepseek-reasoner"
+
+// callDeepSeekWithDeepThink runs the prompt on deepseek-reasoner. The chain
+// of thought comes back as reasoning_content next to the final answer and
+// is saved to cfg.TraceDir when set. The reasoner ignores temperature.
+func callDeepSeekWithDeepThink(ctx context.Context, cfg LLMConfig, symbol, tf, prompt string) LLMResponse {
+	cfg.Name = "deepseek"
+	cfg.Model = orDefault(cfg.Model, deepseekReasonerModel)
+	cfg.Temperature = nil
+	cfg.JSONMode = false // belum didukung deepseek-reasoner
+
+	resp := callLLM(ctx, cfg, prompt)
+	if cfg.TraceDir != "" && resp.Reasoning != "" {
+		path, err := saveReasoningTrace(cfg.TraceDir, symbol, tf, resp)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Gagal simpan reasoning: %v\n", err)
+		} else {
+			fmt.Printf("🧠 Reasoning trace disimpan → %s\n", path)
+		}
 	}
-	return callAI("https://api.x.ai/v1/chat/completions", grokKey, payload)
+	return resp
 }
 
-func callAI(url, key string, payload map[string]any) string {
-	body, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(body))
-	req.Header.Set("Authorization", "Bearer "+key)
-	req.Header.Set("Content-Type", "application/json")
+func saveReasoningTrace(dir, symbol, tf string, resp LLMResponse) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	path := filepath.Join(dir, fmt.Sprintf("%s_%s_%s_reasoning.md", symbol, tf, now.Format("20060102-150405")))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s %s DeepThink reasoning\n\n", symbol, tf)
+	fmt.Fprintf(&b, "- Model: %s\n- Time: %s\n\n## Reasoning\n\n", resp.Model, now.Format(time.RFC3339))
+	b.WriteString(resp.Reasoning)
+	b.WriteString("\n\n## Answer\n\n")
+	b.WriteString(resp.Content)
+	b.WriteString("\n")
+	return path, os.WriteFile(path, []byte(b.String()), 0o644)
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "Error API: " + err.Error()
+// printReasoningSummary shows how long the model thought and its last
+// paragraph, which is usually where it settles on a bias.
+func printReasoningSummary(reasoning string) {
+	reasoning = strings.TrimSpace(reasoning)
+	if reasoning == "" {
+		return
+	}
+	paragraphs := strings.Split(reasoning, "\n\n")
+	conclusion := strings.Join(strings.Fields(paragraphs[len(paragraphs)-1]), " ")
+	if r := []rune(conclusion); len(r) > 280 {
+		conclusion = string(r[:280]) + "…"
 	}
-	defer resp.Body.Close()
 
-	var res map[string]any
-	json.NewDecoder(resp.Body).Decode(&res)
-	choices := res["choices"].([]any)
-	msg := choices[0].(map[string]any)["message"].(map[string]any)
-	return msg["content"].(string)
+	fmt.Printf("🧠 DEEPTHINK: %d kata penalaran, %d paragraf\n", len(strings.Fields(reasoning)), len(paragraphs))
+	fmt.Printf("   Kesimpulan: %s\n\n", conclusion)
 }
 
-func printBeautifulAnalysis(text, symbol, tf string, srLevels []SupportResistance, patterns []Pattern) {
+// printBeautifulAnalysis prints the analysis; chart is the HTML chart of
+// this run, empty when none was written (scan, watch, paper).
+func printBeautifulAnalysis(analysis AIAnalysis, symbol, tf, chart string, srLevels []SupportResistance, patterns []Pattern, mtf *MultiTimeframe) {
 	fmt.Println("\n════════════════════════════════════════════════")
 	fmt.Printf("       %s ANALISA (%s)\n", symbol, tf)
-	fmt.Println("════════════════════════════════════════════════\n")
+	fmt.Println("════════════════════════════════════════════════")
+	fmt.Println()
 	
 	// Print Support/Resistance summary
 	if len(srLevels) > 0 {
-		fmt.Println("🎯 KEY LEVELS:")
-		for _, level := range srLevels {
-			emoji := "🟢"
-			if level.Type == "resistance" {
-				emoji = "🔴"
-			}
-			fmt.Printf("%s %s: $%.4f (Strength: %d)\n", emoji, strings.ToUpper(level.Type), level.Price, level.Strength)
-		}
+		printLevels(srLevels)
 		fmt.Println()
 	}
 	
 	// Print Patterns summary
 	if len(patterns) > 0 {
-		fmt.Println("🎭 PATTERNS DETECTED:")
-		for _, pattern := range patterns {
-			emoji := "🟢"
-			if pattern.Type == "bearish" {
-				emoji = "🔴"
-			} else if pattern.Type == "continuation" {
-				emoji = "🟡"
-			}
-			fmt.Printf("%s %s (%.0f%% confidence)\n", emoji, pattern.Name, pattern.Confidence*100)
-		}
+		printPatterns(patterns)
+		fmt.Println()
+	}
+
+	if mtf != nil {
+		printMultiTimeframe(mtf)
 		fmt.Println()
 	}
 	
-	fmt.Println(text)
-	fmt.Printf("\n✅ Chart disimpan → %s_%s.html\n", symbol, tf)
+	printReasoningSummary(analysis.Reasoning)
+	if !analysis.streamed {
+		fmt.Println(analysis.Content)
+	}
+	printUsageTotals(analysis.Usage, analysis.meter)
+	if chart != "" {
+		fmt.Printf("\n✅ Chart disimpan → %s\n", chart)
+	} else {
+		fmt.Println()
+	}
 	fmt.Println("   Good luck trading, bossku! 🚀🚀🚀")
 }
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// ================================
+// MOCK LLM (--ai mock)
+// ================================
+
+// mockClient answers without network so the analysis path runs in CI. The
+// default model "indicators" builds a plan from the same data the prompt
+// was rendered from; "script:<file>" returns the file verbatim.
+type mockClient struct {
+	cfg LLMConfig
+}
+
+const mockScriptPrefix = "script:"
+
+func (c *mockClient) Name() string { return c.cfg.Name }
+
+func (c *mockClient) Complete(ctx context.Context, prompt string) (*LLMResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var content, reasoning string
+	if path, ok := strings.CutPrefix(c.cfg.Model, mockScriptPrefix); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.cfg.Name, err)
+		}
+		content = string(data)
+	} else {
+		if c.cfg.Facts == nil {
+			return nil, fmt.Errorf("%s: tidak ada data analisa untuk dijadikan jawaban", c.cfg.Name)
+		}
+		plan, why := mockPlan(*c.cfg.Facts)
+		reasoning = why
+		if c.cfg.Facts.JSONOutput {
+			b, err := json.MarshalIndent(plan, "", "  ")
+			if err != nil {
+				return nil, err
+			}
+			content = string(b)
+		} else {
+			content = renderPlan(plan)
+		}
+	}
+
+	// Kira-kira 4 karakter per token, cukup untuk menguji ledger
+	usage := LLMUsage{InputTokens: len(prompt) / 4, OutputTokens: len(content) / 4, Calls: 1}
+	return &LLMResponse{Content: content, Reasoning: reasoning, Model: c.cfg.Model, Usage: usage}, nil
+}
+
+// mockPlan scores the indicator and pattern signals and turns the score
+// into a valid TradePlan: a long or short at the current price with the SL
+// behind the nearest level and targets at fixed R multiples.
+func mockPlan(d PromptData) (*TradePlan, string) {
+	ind := d.Indicators
+	score := 0
+	var signals []string
+	vote := func(text string, bull, bear bool, weight int) {
+		switch {
+		case bull:
+			score += weight
+			signals = append(signals, "+"+text)
+		case bear:
+			score -= weight
+			signals = append(signals, "-"+text)
+		}
+	}
+	trendWeight := 1
+	if strings.HasPrefix(ind.TrendStrength, "Strong") {
+		trendWeight = 2
+	}
+	vote("trend "+ind.TrendStrength, strings.Contains(ind.TrendStrength, "Bullish"), strings.Contains(ind.TrendStrength, "Bearish"), trendWeight)
+	vote("EMA "+ind.EMAAlignment, strings.Contains(ind.EMAAlignment, "Bullish"), strings.Contains(ind.EMAAlignment, "Bearish"), 1)
+	vote("MACD "+ind.MACDTrend, strings.Contains(ind.MACDTrend, "Bullish"), strings.Contains(ind.MACDTrend, "Bearish"), 1)
+	vote("RSI "+ind.RSITrend, ind.RSITrend == "Oversold" || ind.RSITrend == "Bullish Momentum", ind.RSITrend == "Overbought" || ind.RSITrend == "Bearish Momentum", 1)
+	for _, p := range d.Patterns {
+		if p.Value >= 0.6 {
+			vote(p.Name, p.Type == "bullish", p.Type == "bearish", 1)
+		}
+	}
+
+	price, atr := ind.Price, ind.ATRValue
+	if atr <= 0 {
+		atr = price * 0.01
+	}
+	var support, resistance *PromptLevel
+	for i, l := range d.Levels {
+		if l.Value < price && (support == nil || l.Value > support.Value) {
+			support = &d.Levels[i]
+		}
+		if l.Value > price && (resistance == nil || l.Value < resistance.Value) {
+			resistance = &d.Levels[i]
+		}
+	}
+
+	plan := &TradePlan{Symbol: d.Symbol, Timeframe: d.Timeframe}
+	plan.Trend = PlanTrend{Direction: "neutral", Strength: clampInt(3+abs(score), 1, 10), Summary: fmt.Sprintf("mock score %+d", score)}
+	switch {
+	case score > 0:
+		plan.Trend.Direction = "bullish"
+	case score < 0:
+		plan.Trend.Direction = "bearish"
+	}
+
+	bull := clampFloat(34+float64(score)*8, 5, 85)
+	bear := clampFloat(33-float64(score)*8, 5, 85)
+	bullTarget, bearTarget := price+2*atr, price-2*atr
+	if resistance != nil {
+		bullTarget = resistance.Value
+	}
+	if support != nil {
+		bearTarget = support.Value
+	}
+	plan.Scenarios = []PlanScenario{
+		{Name: "bullish", Probability: bull, Trigger: "close di atas resistance terdekat", Target: round4(bullTarget)},
+		{Name: "neutral", Probability: 100 - bull - bear, Trigger: "tetap di range", Target: round4(price)},
+		{Name: "bearish", Probability: bear, Trigger: "close di bawah support terdekat", Target: round4(bearTarget)},
+	}
+
+	s := TradeSetup{Direction: "none"}
+	switch {
+	case score >= 2:
+		s = TradeSetup{Direction: "long", EntryLow: price - 0.3*atr, EntryHigh: price, Trigger: "pullback ke entry zone"}
+		s.SL = s.EntryLow - 1.2*atr
+		if support != nil && support.Value < s.EntryLow && support.Value > s.SL-atr {
+			s.SL = support.Value - 0.3*atr
+		}
+	case score <= -2:
+		s = TradeSetup{Direction: "short", EntryLow: price, EntryHigh: price + 0.3*atr, Trigger: "pullback ke entry zone"}
+		s.SL = s.EntryHigh + 1.2*atr
+		if resistance != nil && resistance.Value > s.EntryHigh && resistance.Value < s.SL+atr {
+			s.SL = resistance.Value + 0.3*atr
+		}
+	}
+	if s.Direction != "none" {
+		s.EntryLow, s.EntryHigh, s.SL = round4(s.EntryLow), round4(s.EntryHigh), round4(s.SL)
+		mid, risk := s.EntryMid(), math.Abs(s.EntryMid()-s.SL)
+		sign := 1.0
+		if s.Direction == "short" {
+			sign = -1
+		}
+		s.TP1, s.TP2, s.TP3 = round4(mid+sign*1.5*risk), round4(mid+sign*2.5*risk), round4(mid+sign*4*risk)
+		s.RR = math.Round(s.ComputedRR()*100) / 100
+	}
+	plan.Setup = s
+
+	alignment := 5
+	if d.MTF != nil && strings.HasPrefix(d.MTF.Alignment, "Fully") {
+		alignment = 8
+	}
+	plan.Confidence = PlanConfidence{SetupQuality: clampInt(4+abs(score), 1, 10), MarketAlignment: alignment, Timing: 5}
+	plan.KeyRisk = "Jawaban mock dari indikator, bukan analisa model"
+	plan.Insights = signals
+	if len(plan.Insights) == 0 {
+		plan.Insights = []string{"tidak ada sinyal searah"}
+	}
+	return plan, "mock: " + strings.Join(signals, ", ")
+}
+
+func round4(v float64) float64 { return math.Round(v*1e4) / 1e4 }
+
+func clampInt(v, lo, hi int) int { return max(lo, min(hi, v)) }
+
+func clampFloat(v, lo, hi float64) float64 { return math.Max(lo, math.Min(hi, v)) }
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// replayClient stands in for the real backend under --ai-replay. Every
+// answer must come from a fixture, so reaching it means one is missing.
+type replayClient struct {
+	name string
+}
+
+func (c replayClient) Name() string { return c.name }
+
+func (c replayClient) Complete(context.Context, string) (*LLMResponse, error) {
+	return nil, errors.New("fixture untuk prompt ini tidak ada, rekam dulu dengan --ai-record")
+}
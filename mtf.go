@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ================================
+// MULTI-TIMEFRAME ANALYSIS
+// ================================
+
+// confluenceTolerance is how close (relative) levels from different
+// timeframes must be to count as the same zone.
+const confluenceTolerance = 0.01
+
+// TimeframeView is the per-timeframe slice of a MultiTimeframe stack.
+type TimeframeView struct {
+	TF           string              `json:"timeframe"`
+	Candles      int                 `json:"candles"`
+	Price        float64             `json:"price"`
+	Trend        string              `json:"trend"`
+	EMAAlignment string              `json:"ema_alignment"`
+	RSITrend     string              `json:"rsi_trend"`
+	MACDTrend    string              `json:"macd_trend"`
+	Levels       []SupportResistance `json:"support_resistance"`
+	Patterns     []Pattern           `json:"patterns"`
+}
+
+// Confluence is a price zone found as S/R on more than one timeframe.
+type Confluence struct {
+	Type       string   `json:"type"`
+	Price      float64  `json:"price"`
+	Timeframes []string `json:"timeframes"`
+	Touches    int      `json:"touches"`
+}
+
+func (c Confluence) String() string {
+	return fmt.Sprintf("%s di %.4f muncul di %s", c.Type, c.Price, strings.Join(c.Timeframes, " dan "))
+}
+
+// MultiTimeframe is the --tfs stack for one symbol, lowest timeframe first.
+type MultiTimeframe struct {
+	Symbol     string          `json:"symbol"`
+	Views      []TimeframeView `json:"timeframes"`
+	Confluence []Confluence    `json:"confluence"`
+	Alignment  string          `json:"trend_alignment"`
+}
+
+// parseTimeframes reads "15m,1h,4h,1d", makes sure the primary timeframe is
+// part of the stack and orders it from lowest to highest.
+func parseTimeframes(s, primary string) ([]string, error) {
+	seen := map[string]bool{}
+	var ivs []Interval
+	for _, tf := range append(strings.Split(s, ","), primary) {
+		tf = strings.TrimSpace(tf)
+		if tf == "" || seen[tf] {
+			continue
+		}
+		iv, err := ParseInterval(tf)
+		if err != nil {
+			return nil, err
+		}
+		seen[tf] = true
+		ivs = append(ivs, iv)
+	}
+	sort.Slice(ivs, func(i, j int) bool { return ivs[i].Duration() < ivs[j].Duration() })
+
+	tfs := make([]string, len(ivs))
+	for i, iv := range ivs {
+		tfs[i] = iv.Code
+	}
+	return tfs, nil
+}
+
+// analyzeTimeframes loads n candles per timeframe (ending at to when set)
+// and computes trend, EMA alignment, S/R and patterns on each, with the
+// detector parameters tuned for that timeframe when params has them.
+func analyzeTimeframes(provider CandleProvider, symbol string, tfs []string, n int, to time.Time, params *detectorParams) (*MultiTimeframe, error) {
+	mtf := &MultiTimeframe{Symbol: symbol}
+	for _, tf := range tfs {
+		candles, err := loadWindow(provider, symbol, tf, n, to)
+		if err != nil {
+			return nil, fmt.Errorf("timeframe %s: %w", tf, err)
+		}
+		if len(candles) < 30 {
+			return nil, fmt.Errorf("timeframe %s: cuma dapat %d candle", tf, len(candles))
+		}
+
+		series := buildSeries(candles, mustInterval(tf))
+		summary := summarizeIndicators(series, symbol, tf)
+		detectors := params.config(symbol, tf)
+		mtf.Views = append(mtf.Views, TimeframeView{
+			TF:           tf,
+			Candles:      len(candles),
+			Price:        summary.Price,
+			Trend:        summary.TrendStrength,
+			EMAAlignment: summary.EMAAlignment,
+			RSITrend:     summary.RSITrend,
+			MACDTrend:    summary.MACDTrend,
+			Levels:       detectSupportResistance(candles, detectors.SR),
+			Patterns:     detectPatterns(candles, detectors.Patterns),
+		})
+	}
+
+	price := mtf.Views[0].Price
+	mtf.Confluence = findConfluence(mtf.Views, price)
+	mtf.Alignment = trendAlignment(mtf.Views)
+	return mtf, nil
+}
+
+// findConfluence groups S/R levels of all timeframes that sit within
+// confluenceTolerance of each other and keeps the groups that span at
+// least two timeframes. Zones are typed against the current price.
+func findConfluence(views []TimeframeView, price float64) []Confluence {
+	type entry struct {
+		price   float64
+		tf      int
+		touches int
+	}
+	var entries []entry
+	for i, v := range views {
+		for _, level := range v.Levels {
+			entries = append(entries, entry{price: level.Price, tf: i, touches: level.Touches})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].price < entries[j].price })
+
+	var zones []Confluence
+	for start := 0; start < len(entries); {
+		sum, touches := 0.0, 0
+		inZone := map[int]bool{}
+		end := start
+		for ; end < len(entries); end++ {
+			avg := entries[start].price
+			if end > start {
+				avg = sum / float64(end-start)
+			}
+			if math.Abs(entries[end].price-avg)/avg > confluenceTolerance {
+				break
+			}
+			sum += entries[end].price
+			touches += entries[end].touches
+			inZone[entries[end].tf] = true
+		}
+
+		if len(inZone) >= 2 {
+			zone := Confluence{Type: "support", Price: sum / float64(end-start), Touches: touches}
+			if zone.Price > price {
+				zone.Type = "resistance"
+			}
+			for i, v := range views {
+				if inZone[i] {
+					zone.Timeframes = append(zone.Timeframes, v.TF)
+				}
+			}
+			zones = append(zones, zone)
+		}
+		start = end
+	}
+
+	// Zona yang muncul di lebih banyak timeframe duluan, lalu yang paling dekat harga
+	sort.SliceStable(zones, func(i, j int) bool {
+		if len(zones[i].Timeframes) != len(zones[j].Timeframes) {
+			return len(zones[i].Timeframes) > len(zones[j].Timeframes)
+		}
+		return math.Abs(zones[i].Price-price) < math.Abs(zones[j].Price-price)
+	})
+	return zones
+}
+
+// trendAlignment summarizes how many timeframes agree on direction.
+func trendAlignment(views []TimeframeView) string {
+	var bull, bear []string
+	for _, v := range views {
+		switch {
+		case strings.Contains(v.Trend, "Bullish"):
+			bull = append(bull, v.TF)
+		case strings.Contains(v.Trend, "Bearish"):
+			bear = append(bear, v.TF)
+		}
+	}
+	switch {
+	case len(bull) == len(views):
+		return fmt.Sprintf("Fully Bullish (%d/%d TF)", len(bull), len(views))
+	case len(bear) == len(views):
+		return fmt.Sprintf("Fully Bearish (%d/%d TF)", len(bear), len(views))
+	case len(bull) > len(bear):
+		return fmt.Sprintf("Mostly Bullish (%s)", strings.Join(bull, ", "))
+	case len(bear) > len(bull):
+		return fmt.Sprintf("Mostly Bearish (%s)", strings.Join(bear, ", "))
+	default:
+		return "Mixed/Conflicting"
+	}
+}
+
+func printMultiTimeframe(mtf *MultiTimeframe) {
+	if mtf == nil {
+		return
+	}
+	fmt.Printf("🧭 MULTI-TIMEFRAME (%s):\n", mtf.Alignment)
+	for _, v := range mtf.Views {
+		var names []string
+		for _, p := range v.Patterns {
+			names = append(names, p.Name)
+		}
+		patterns := "-"
+		if len(names) > 0 {
+			patterns = strings.Join(names, ", ")
+		}
+		fmt.Printf("  %-4s %-15s | EMA %-26s | RSI %-17s | MACD %-18s | %s\n",
+			v.TF, v.Trend, v.EMAAlignment, v.RSITrend, v.MACDTrend, patterns)
+	}
+	if len(mtf.Confluence) == 0 {
+		fmt.Println("🔗 Tidak ada level S/R yang sama antar timeframe.")
+		return
+	}
+	fmt.Println("🔗 CONFLUENCE:")
+	for _, c := range mtf.Confluence {
+		emoji := "🟢"
+		if c.Type == "resistance" {
+			emoji = "🔴"
+		}
+		fmt.Printf("%s %s\n", emoji, c)
+	}
+}
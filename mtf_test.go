@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTimeframes(t *testing.T) {
+	tests := []struct {
+		in, primary string
+		want        []string
+		ok          bool
+	}{
+		{"4h,15m,1d", "1h", []string{"15m", "1h", "4h", "1d"}, true},
+		{" 1h , 4h,1h", "1h", []string{"1h", "4h"}, true},
+		{"", "4h", []string{"4h"}, true},
+		{"1w,1M,1d", "1d", []string{"1d", "1w", "1M"}, true},
+		{"1m,1M", "1h", []string{"1m", "1h", "1M"}, true},
+		{"1h,2x", "1h", nil, false},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeframes(tt.in, tt.primary)
+		if (err == nil) != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTimeframes(%q, %q) = %v, %v; mau %v, ok %v", tt.in, tt.primary, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTrendAlignment(t *testing.T) {
+	view := func(tf, trend string) TimeframeView { return TimeframeView{TF: tf, Trend: trend} }
+	tests := []struct {
+		views []TimeframeView
+		want  string
+	}{
+		{[]TimeframeView{view("1h", "Strong Bullish"), view("4h", "Bullish")}, "Fully Bullish (2/2 TF)"},
+		{[]TimeframeView{view("1h", "Bearish"), view("4h", "Strong Bearish"), view("1d", "Bearish")}, "Fully Bearish (3/3 TF)"},
+		{[]TimeframeView{view("1h", "Bullish"), view("4h", "Sideways"), view("1d", "Bullish")}, "Mostly Bullish (1h, 1d)"},
+		{[]TimeframeView{view("1h", "Bearish"), view("4h", "Bearish"), view("1d", "Bullish")}, "Mostly Bearish (1h, 4h)"},
+		{[]TimeframeView{view("1h", "Bullish"), view("4h", "Bearish")}, "Mixed/Conflicting"},
+		{[]TimeframeView{view("1h", "Sideways"), view("4h", "Sideways")}, "Mixed/Conflicting"},
+	}
+	for _, tt := range tests {
+		if got := trendAlignment(tt.views); got != tt.want {
+			t.Errorf("trendAlignment(%v) = %q, mau %q", tt.views, got, tt.want)
+		}
+	}
+}
+
+func TestFindConfluence(t *testing.T) {
+	level := func(price float64, touches int) SupportResistance {
+		return SupportResistance{Price: price, Touches: touches}
+	}
+	views := []TimeframeView{
+		{TF: "1h", Levels: []SupportResistance{level(100, 2), level(110, 2), level(130, 4)}},
+		{TF: "4h", Levels: []SupportResistance{level(100.5, 3), level(109.8, 1), level(125, 5)}},
+		{TF: "1d", Levels: []SupportResistance{level(99.8, 1)}},
+	}
+	got := findConfluence(views, 105)
+	if len(got) != 2 {
+		t.Fatalf("zona = %+v, mau 2 (130 dan 125 terlalu jauh)", got)
+	}
+
+	// Zona tiga timeframe duluan walau lebih jauh dari harga
+	z := got[0]
+	if z.Type != "support" || !reflect.DeepEqual(z.Timeframes, []string{"1h", "4h", "1d"}) || z.Touches != 6 {
+		t.Errorf("zona pertama %+v", z)
+	}
+	if want := (100 + 100.5 + 99.8) / 3; z.Price < want-1e-9 || z.Price > want+1e-9 {
+		t.Errorf("harga zona %.4f, mau rata-rata %.4f", z.Price, want)
+	}
+	if z := got[1]; z.Type != "resistance" || !reflect.DeepEqual(z.Timeframes, []string{"1h", "4h"}) || z.Touches != 3 {
+		t.Errorf("zona kedua %+v", z)
+	}
+
+	// Level berdekatan dari satu timeframe saja bukan confluence
+	single := []TimeframeView{{TF: "1h", Levels: []SupportResistance{level(100, 2), level(100.3, 2)}}, {TF: "4h"}}
+	if got := findConfluence(single, 105); len(got) != 0 {
+		t.Errorf("satu timeframe jadi confluence: %+v", got)
+	}
+}
+
+// TestLoadWindowDropsUnclosedAtTo checks that --to never sees the candle
+// that opened before it but closed after it.
+func TestLoadWindowDropsUnclosedAtTo(t *testing.T) {
+	provider := &fileProvider{path: "testdata/SOLUSDT_1h.csv"}
+	to := time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)
+
+	candles, err := loadWindow(provider, "SOLUSDT", "1h", 50, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := candles[len(candles)-1]
+	if want := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC); !last.Time.Equal(want) {
+		t.Errorf("candle terakhir open %s, mau %s (candle 10:00 belum close di --to)", last.Time.UTC(), want)
+	}
+
+	mtf, err := analyzeTimeframes(provider, "SOLUSDT", []string{"1h"}, 50, to, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price := last.Close.InexactFloat64(); mtf.Views[0].Price != price {
+		t.Errorf("harga view %.4f, mau close candle 09:00 %.4f", mtf.Views[0].Price, price)
+	}
+}
@@ -0,0 +1,637 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// ================================
+// DETECTOR PARAMETER OPTIMIZATION
+// ================================
+
+// defaultOptimizeLong uses a level on purpose: rules that only read
+// indicators do not change when the detector thresholds do.
+const defaultOptimizeLong = "near-support+trend-up"
+
+// maxGridSize keeps --search grid from running for hours.
+const maxGridSize = 2000
+
+// detectorParams is what optimize --save writes and --params reads: the
+// tuned detector config per symbol and timeframe. Anything missing runs on
+// defaultDetectorConfig.
+type detectorParams struct {
+	Updated time.Time                       `json:"updated"`
+	Sets    map[string]map[string]*paramSet `json:"sets"` // symbol → timeframe
+}
+
+// paramSet is one tuned config together with the walk-forward evidence
+// it was accepted on.
+type paramSet struct {
+	Config     detectorConfig `json:"config"`
+	Metric     string         `json:"metric"`
+	Long       []string       `json:"long,omitempty"`
+	Short      []string       `json:"short,omitempty"`
+	Score      float64        `json:"score"`             // metric di window terakhir (in-sample)
+	OOS        float64        `json:"oos_score"`         // rata-rata metric di fold test
+	DefaultOOS float64        `json:"default_oos_score"` // config default di fold test yang sama
+	Folds      int            `json:"folds"`
+	From       time.Time      `json:"from"`
+	To         time.Time      `json:"to"`
+	Created    time.Time      `json:"created"`
+}
+
+func defaultParamsFile() string {
+	if v := os.Getenv("DETECTOR_PARAMS"); v != "" {
+		return v
+	}
+	return filepath.Join(filepath.Dir(defaultUsageLedger()), "params.json")
+}
+
+// loadParams reads path; a missing file means defaults everywhere.
+func loadParams(path string) (*detectorParams, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p detectorParams
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for symbol, tfs := range p.Sets {
+		for tf, set := range tfs {
+			if err := set.Config.validate(); err != nil {
+				return nil, fmt.Errorf("%s %s %s: %w", path, symbol, tf, err)
+			}
+		}
+	}
+	return &p, nil
+}
+
+// config is the tuned config for symbol on tf, or the defaults. A nil
+// receiver is fine, it is what --params "" and a missing file give.
+func (p *detectorParams) config(symbol, tf string) detectorConfig {
+	if p == nil {
+		return defaultDetectorConfig
+	}
+	if set := p.Sets[symbol][tf]; set != nil {
+		return set.Config
+	}
+	return defaultDetectorConfig
+}
+
+// ruleConfig is config for a run that trades entry rules (backtest, paper).
+// A set is tuned for the --long/--short it was optimized with, so other
+// rules run on the defaults; skipped reports that a set was left out.
+func (p *detectorParams) ruleConfig(symbol, tf string, long, short []rankTerm) (cfg detectorConfig, skipped bool) {
+	if p == nil || p.Sets[symbol][tf] == nil {
+		return defaultDetectorConfig, false
+	}
+	set := p.Sets[symbol][tf]
+	if strings.Join(set.Long, "+") != strings.Join(termNames(long), "+") || strings.Join(set.Short, "+") != strings.Join(termNames(short), "+") {
+		return defaultDetectorConfig, true
+	}
+	return set.Config, false
+}
+
+func (p *detectorParams) set(symbol, tf string, set *paramSet) {
+	if p.Sets == nil {
+		p.Sets = map[string]map[string]*paramSet{}
+	}
+	if p.Sets[symbol] == nil {
+		p.Sets[symbol] = map[string]*paramSet{}
+	}
+	p.Sets[symbol][tf] = set
+	p.Updated = set.Created
+}
+
+// paramSpec is one tunable threshold and the values the search tries.
+type paramSpec struct {
+	name   string
+	desc   string
+	values func(candles []Candle) []float64
+	set    func(c *detectorConfig, v float64)
+}
+
+func fixedValues(vs ...float64) func([]Candle) []float64 {
+	return func([]Candle) []float64 { return vs }
+}
+
+var paramSpace = []paramSpec{
+	{"sr-swing", "swing radius support/resistance", fixedValues(1, 2, 3, 4), func(c *detectorConfig, v float64) { c.SR.SwingRadius = int(v) }},
+	{"sr-tolerance", "tolerance cluster level", fixedValues(0.005, 0.01, 0.02, 0.03), func(c *detectorConfig, v float64) { c.SR.Tolerance = v }},
+	{"pattern-swing", "swing radius peak/trough pattern", fixedValues(1, 2, 3), func(c *detectorConfig, v float64) { c.Patterns.SwingRadius = int(v) }},
+	{"hs-window", "window head and shoulders", fixedValues(20, 30, 40), func(c *detectorConfig, v float64) { c.Patterns.HSWindow = int(v) }},
+	{"shoulder-tolerance", "selisih tinggi shoulder", fixedValues(0.015, 0.03, 0.05), func(c *detectorConfig, v float64) { c.Patterns.ShoulderTolerance = v }},
+	{"double-window", "window double top/bottom", fixedValues(15, 25, 35), func(c *detectorConfig, v float64) { c.Patterns.DoubleWindow = int(v) }},
+	{"double-tolerance", "selisih dua puncak/lembah", fixedValues(0.01, 0.02, 0.03), func(c *detectorConfig, v float64) { c.Patterns.DoubleTolerance = v }},
+	{"triangle-window", "window triangle", fixedValues(10, 15, 20), func(c *detectorConfig, v float64) { c.Patterns.TriangleWindow = int(v) }},
+	{"slope-cutoff", "slope datar triangle (harga per candle)", slopeValues, func(c *detectorConfig, v float64) { c.Patterns.SlopeCutoff = v }},
+}
+
+// slopeValues scales the slope cutoff to the median close: 0.001 per
+// candle is flat for BTC and steep for a coin priced in fractions of a
+// cent. The default stays in so the search can keep it.
+func slopeValues(candles []Candle) []float64 {
+	closes := make([]float64, len(candles))
+	for i, c := range candles {
+		closes[i] = c.Close.InexactFloat64()
+	}
+	sort.Float64s(closes)
+	values := []float64{defaultDetectorConfig.Patterns.SlopeCutoff}
+	if len(closes) == 0 {
+		return values
+	}
+	median := closes[len(closes)/2]
+	for _, f := range []float64{0.0001, 0.0005, 0.001} {
+		v, _ := strconv.ParseFloat(strconv.FormatFloat(median*f, 'g', 3, 64), 64)
+		if v > 0 && v != values[0] {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func paramHelp() string {
+	var b strings.Builder
+	b.WriteString("Parameter --tune (pisah koma):\n")
+	for _, p := range paramSpace {
+		fmt.Fprintf(&b, "  %-19s %s\n", p.name, p.desc)
+	}
+	return b.String()
+}
+
+func parseTune(s string) ([]paramSpec, error) {
+	if strings.TrimSpace(s) == "" {
+		return paramSpace, nil
+	}
+	var specs []paramSpec
+next:
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for _, p := range paramSpace {
+			if p.name == name {
+				specs = append(specs, p)
+				continue next
+			}
+		}
+		return nil, fmt.Errorf("parameter %q tidak dikenal\n\n%s", name, paramHelp())
+	}
+	return specs, nil
+}
+
+// candidateConfigs lists the configs to try, defaults first. Grid takes
+// every combination of the tuned values, random draws trials of them.
+// Combinations the detectors cannot work with are dropped.
+func candidateConfigs(specs []paramSpec, candles []Candle, search string, trials int, rng *rand.Rand) ([]detectorConfig, error) {
+	values := make([][]float64, len(specs))
+	size := 1
+	for i, p := range specs {
+		values[i] = p.values(candles)
+		size *= len(values[i])
+	}
+
+	seen := map[detectorConfig]bool{defaultDetectorConfig: true}
+	configs := []detectorConfig{defaultDetectorConfig}
+	add := func(pick func(i int) float64) {
+		c := defaultDetectorConfig
+		for i, p := range specs {
+			p.set(&c, pick(i))
+		}
+		if !seen[c] && c.validate() == nil {
+			seen[c] = true
+			configs = append(configs, c)
+		}
+	}
+
+	if search == "grid" {
+		if size > maxGridSize {
+			return nil, fmt.Errorf("grid %d kombinasi, maksimal %d: persempit --tune atau pakai --search random", size, maxGridSize)
+		}
+		idx := make([]int, len(specs))
+		for n := 0; n < size; n++ {
+			rest := n
+			for i := range specs {
+				idx[i] = rest % len(values[i])
+				rest /= len(values[i])
+			}
+			add(func(i int) float64 { return values[i][idx[i]] })
+		}
+		return configs, nil
+	}
+	for attempt := 0; len(configs) <= trials && attempt < trials*20; attempt++ {
+		add(func(i int) float64 { return values[i][rng.Intn(len(values[i]))] })
+	}
+	return configs, nil
+}
+
+// optimizeMetrics are the backtest numbers a config can be tuned for.
+var optimizeMetrics = map[string]func(BacktestMetrics) float64{
+	"sharpe":       func(m BacktestMetrics) float64 { return m.Sharpe },
+	"return":       func(m BacktestMetrics) float64 { return m.Return },
+	"expectancy-r": func(m BacktestMetrics) float64 { return m.ExpectancyR },
+	"calmar":       func(m BacktestMetrics) float64 { return m.Return / math.Max(m.MaxDrawdown, 1) },
+}
+
+// detectorTerms are the scan criteria that read levels or patterns.
+var detectorTerms = map[string]bool{"near-support": true, "near-resistance": true, "bullish-pattern": true, "bearish-pattern": true}
+
+type optimizeOptions struct {
+	backtestOptions
+	Symbols   []string
+	Metric    string
+	Search    string
+	Trials    int
+	Folds     int
+	Train     int // panjang window train dalam kelipatan window test
+	MinTrades int
+	Seed      int64
+	Workers   int
+	Save      bool
+
+	tune  []paramSpec
+	score func(BacktestMetrics) float64
+}
+
+// OptimizeReport is the walk-forward result for one symbol and timeframe.
+type OptimizeReport struct {
+	Symbol        string            `json:"symbol"`
+	Timeframe     string            `json:"timeframe"`
+	Metric        string            `json:"metric"`
+	Search        string            `json:"search"`
+	Candidates    int               `json:"candidates"`
+	Folds         []WalkForwardFold `json:"folds"`
+	OOS           float64           `json:"oos_score"`
+	DefaultOOS    float64           `json:"default_oos_score"`
+	Trades        int               `json:"oos_trades"`
+	DefaultTrades int               `json:"default_oos_trades"`
+	Best          detectorConfig    `json:"best"`
+	BestScore     float64           `json:"best_score"`
+	Saved         bool              `json:"saved"`
+}
+
+// WalkForwardFold is one train window and the test window right after it.
+type WalkForwardFold struct {
+	TrainFrom    time.Time       `json:"train_from"`
+	TrainTo      time.Time       `json:"train_to"`
+	TestFrom     time.Time       `json:"test_from"`
+	TestTo       time.Time       `json:"test_to"`
+	Config       detectorConfig  `json:"config"`
+	TrainScore   float64         `json:"train_score"`
+	Test         BacktestMetrics `json:"test"`
+	TestScore    float64         `json:"test_score"`
+	Default      BacktestMetrics `json:"default"`
+	DefaultScore float64         `json:"default_score"`
+}
+
+func parseOptimizeFlags(args []string) (optimizeOptions, error) {
+	var opts optimizeOptions
+	fs := flag.NewFlagSet("optimize", flag.ContinueOnError)
+	symbols := fs.String("symbols", "", "symbol dipisah koma, contoh: SOL,BTC,ETH")
+	finish := dataFlags(fs, &opts.cliOptions)
+	tfs := fs.String("tfs", "", "timeframe tambahan yang dioptimasi, mis. 1h,4h (--tf selalu ikut)")
+	finishRules := ruleFlags(fs, &opts.backtestOptions, defaultOptimizeLong)
+	fs.StringVar(&opts.Metric, "metric", "sharpe", "metric backtest yang dimaksimalkan: sharpe, return, expectancy-r, calmar")
+	fs.StringVar(&opts.Search, "search", "random", "grid (semua kombinasi) atau random")
+	fs.IntVar(&opts.Trials, "trials", 40, "jumlah kandidat untuk --search random")
+	tune := fs.String("tune", "", "parameter yang dioptimasi (default: semua), sisanya tetap default")
+	fs.IntVar(&opts.Folds, "folds", 4, "jumlah fold walk-forward")
+	fs.IntVar(&opts.Train, "train", 3, "panjang window train, kelipatan panjang window test")
+	fs.IntVar(&opts.MinTrades, "min-trades", 5, "trade minimal di window train supaya kandidat dinilai")
+	fs.Int64Var(&opts.Seed, "seed", 1, "seed --search random, hasil bisa diulang")
+	fs.IntVar(&opts.Workers, "workers", runtime.NumCPU(), "backtest paralel")
+	fs.BoolVar(&opts.Save, "save", false, "simpan parameter terbaik ke --params, dipakai analyze, scan, watch dan backtest")
+	fs.StringVar(&opts.Out, "out", "", "tulis hasil walk-forward JSON ke file ini")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of optimize:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\n%s\n%s", paramHelp(), criteriaHelp())
+	}
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("argumen tidak dikenal: %s", strings.Join(fs.Args(), " "))
+	}
+	if err := finish(); err != nil {
+		return opts, err
+	}
+	if err := finishRules(); err != nil {
+		return opts, err
+	}
+
+	for _, s := range strings.Split(*symbols, ",") {
+		if strings.TrimSpace(s) != "" {
+			opts.Symbols = append(opts.Symbols, normalizeSymbol(s))
+		}
+	}
+	if len(opts.Symbols) == 0 {
+		return opts, errors.New("optimize butuh --symbols")
+	}
+	opts.TFs = []string{opts.TF}
+	var err error
+	if *tfs != "" {
+		if opts.TFs, err = parseTimeframes(*tfs, opts.TF); err != nil {
+			return opts, err
+		}
+	}
+	if opts.tune, err = parseTune(*tune); err != nil {
+		return opts, err
+	}
+	if opts.score = optimizeMetrics[opts.Metric]; opts.score == nil {
+		return opts, fmt.Errorf("--metric %q tidak dikenal (sharpe, return, expectancy-r, calmar)", opts.Metric)
+	}
+
+	usesDetectors := opts.SLLevel
+	for _, t := range append(append([]rankTerm{}, opts.Long...), opts.Short...) {
+		usesDetectors = usesDetectors || detectorTerms[t.Name]
+	}
+	switch {
+	case !usesDetectors:
+		return opts, errors.New("rule --long/--short tidak memakai level atau pattern, parameter detector tidak berpengaruh: tambah near-support, bullish-pattern dan sejenisnya, atau --sl-level")
+	case opts.Search != "grid" && opts.Search != "random":
+		return opts, fmt.Errorf("--search %q tidak dikenal (grid, random)", opts.Search)
+	case opts.Trials <= 0 || opts.Folds <= 0 || opts.Train <= 0 || opts.Workers <= 0:
+		return opts, errors.New("--trials, --folds, --train dan --workers harus lebih dari 0")
+	case opts.MinTrades < 0:
+		return opts, errors.New("--min-trades tidak boleh negatif")
+	case opts.Save && opts.Params == "":
+		return opts, errors.New("--save butuh --params")
+	}
+	return opts, nil
+}
+
+// runOptimize tunes the detector thresholds per symbol and timeframe with
+// walk-forward validation and, with --save, stores the sets that beat the
+// defaults out of sample.
+func runOptimize(args []string) error {
+	opts, err := parseOptimizeFlags(args)
+	if err != nil {
+		return err
+	}
+	provider, err := openProvider(opts.cliOptions)
+	if err != nil {
+		return err
+	}
+
+	params := opts.params
+	if params == nil {
+		params = &detectorParams{}
+	}
+	var reports []*OptimizeReport
+	saved := 0
+	for _, symbol := range opts.Symbols {
+		for _, tf := range opts.TFs {
+			var candles []Candle
+			if opts.From.IsZero() {
+				candles, err = fetchLatest(provider, symbol, tf, opts.Candles)
+			} else {
+				candles, err = backfillCandles(provider, symbol, tf, opts.From, opts.To)
+			}
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", symbol, tf, err)
+			}
+			candles = closedOnly(candles, time.Now())
+
+			o := opts
+			o.Symbol, o.TF = symbol, tf
+			report, set, err := o.optimize(candles)
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", symbol, tf, err)
+			}
+			printOptimize(report)
+			if opts.Save && set != nil {
+				params.set(symbol, tf, set)
+				report.Saved = true
+				saved++
+			}
+			reports = append(reports, report)
+		}
+	}
+
+	if opts.Save && saved > 0 {
+		if err := writeJSONFile(opts.Params, params); err != nil {
+			return fmt.Errorf("simpan parameter: %w", err)
+		}
+		fmt.Printf("✅ %d set parameter disimpan → %s (dipakai analyze, scan, watch dan eval; backtest dan paper hanya dengan --long/--short yang sama)\n", saved, opts.Params)
+	}
+	if opts.Out != "" {
+		if err := writeJSONFile(opts.Out, reports); err != nil {
+			return fmt.Errorf("tulis hasil optimize: %w", err)
+		}
+		fmt.Printf("✅ Hasil walk-forward disimpan → %s\n", opts.Out)
+	}
+	return nil
+}
+
+// optimize splits candles into Train+Folds equal chunks. Fold k picks the
+// best candidate on chunks k..k+Train-1 and trades it, next to the
+// defaults, on the chunk after. The set to keep is then picked on the
+// latest Train chunks, and is only returned when the tuned configs beat
+// the defaults on the test chunks.
+func (o optimizeOptions) optimize(candles []Candle) (*OptimizeReport, *paramSet, error) {
+	chunk := len(candles) / (o.Train + o.Folds)
+	if chunk < 50 {
+		return nil, nil, fmt.Errorf("%d candle terlalu sedikit untuk %d fold dan --train %d (butuh minimal 50 candle per window test), tambah --candles atau --from", len(candles), o.Folds, o.Train)
+	}
+	rng := rand.New(rand.NewSource(o.Seed))
+	candidates, err := candidateConfigs(o.tune, candles, o.Search, o.Trials, rng)
+	if err != nil {
+		return nil, nil, err
+	}
+	report := &OptimizeReport{Symbol: o.Symbol, Timeframe: o.TF, Metric: o.Metric, Search: o.Search, Candidates: len(candidates)}
+	fmt.Printf("🔧 %s %s: %d candle, %d kandidat x %d fold\n", o.Symbol, o.TF, len(candles), len(candidates), o.Folds)
+	summaries := o.summaries(candles)
+
+	offset := len(candles) - chunk*(o.Train+o.Folds)
+	var oos, defaultOOS float64
+	for k := 0; k < o.Folds; k++ {
+		trainFrom := offset + k*chunk
+		testFrom := trainFrom + o.Train*chunk
+		testTo := testFrom + chunk
+		best, trainScore := o.best(candles, trainFrom, testFrom, candidates, summaries)
+		fold := WalkForwardFold{
+			TrainFrom: candles[trainFrom].Time, TrainTo: candles[testFrom-1].Time,
+			TestFrom: candles[testFrom].Time, TestTo: candles[testTo-1].Time,
+			Config: best, TrainScore: trainScore,
+			Test:    o.run(candles, testFrom, testTo, best, summaries),
+			Default: o.run(candles, testFrom, testTo, defaultDetectorConfig, summaries),
+		}
+		fold.TestScore, fold.DefaultScore = o.score(fold.Test), o.score(fold.Default)
+		oos += fold.TestScore
+		defaultOOS += fold.DefaultScore
+		report.Trades += fold.Test.Trades
+		report.DefaultTrades += fold.Default.Trades
+		report.Folds = append(report.Folds, fold)
+		fmt.Printf("   fold %d/%d: test %s → %s, %s %.2f (default %.2f)\n", k+1, o.Folds,
+			fold.TestFrom.Format("2006-01-02"), fold.TestTo.Format("2006-01-02"), o.Metric, fold.TestScore, fold.DefaultScore)
+	}
+	report.OOS, report.DefaultOOS = oos/float64(o.Folds), defaultOOS/float64(o.Folds)
+
+	latest := len(candles) - o.Train*chunk
+	report.Best, report.BestScore = o.best(candles, latest, len(candles), candidates, summaries)
+	if report.OOS <= report.DefaultOOS || report.Best == defaultDetectorConfig {
+		return report, nil, nil
+	}
+	return report, &paramSet{
+		Config: report.Best, Metric: o.Metric, Long: termNames(o.Long), Short: termNames(o.Short),
+		Score: report.BestScore, OOS: report.OOS, DefaultOOS: report.DefaultOOS, Folds: o.Folds,
+		From: candles[offset].Time, To: candles[len(candles)-1].Time, Created: time.Now().UTC(),
+	}, nil
+}
+
+// summaries computes the indicators once per bar. They do not depend on
+// the detector thresholds, so every candidate shares them and a backtest
+// run only pays for the detectors.
+func (o optimizeOptions) summaries(candles []Candle) map[time.Time]IndicatorSummary {
+	iv := mustInterval(o.TF)
+	out := make([]IndicatorSummary, len(candles))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < o.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				window := candles[max(0, i+1-o.Lookback) : i+1]
+				out[i] = summarizeIndicators(buildSeries(window, iv), o.Symbol, o.TF)
+			}
+		}()
+	}
+	for i := min(30, len(candles)-1); i < len(candles); i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	byTime := make(map[time.Time]IndicatorSummary, len(candles))
+	for i, s := range out {
+		byTime[candles[i].Time] = s
+	}
+	return byTime
+}
+
+// run backtests cfg on bars [from, to). The Lookback candles before from
+// come along as history, so every window matches what live analysis at
+// that bar would have seen.
+func (o optimizeOptions) run(candles []Candle, from, to int, cfg detectorConfig, summaries map[time.Time]IndicatorSummary) BacktestMetrics {
+	bt := o.backtestOptions
+	bt.detectors = cfg
+	bt.Start = candles[from].Time
+	signal := func(window []Candle) *btSignal {
+		r := &scanResult{Symbol: bt.Symbol, candles: window, Summary: summaries[window[len(window)-1].Time]}
+		r.detect(bt.detectors)
+		return bt.decide(r)
+	}
+	return backtest(candles[max(0, from-bt.Lookback):to], bt, signal).Metrics
+}
+
+// best backtests every candidate on bars [from, to) and returns the one
+// with the highest metric. Candidates with fewer than MinTrades trades are
+// not scored; when none qualifies the defaults stay.
+func (o optimizeOptions) best(candles []Candle, from, to int, candidates []detectorConfig, summaries map[time.Time]IndicatorSummary) (detectorConfig, float64) {
+	scores := make([]float64, len(candidates))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < o.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				m := o.run(candles, from, to, candidates[i], summaries)
+				scores[i] = math.Inf(-1)
+				if s := o.score(m); m.Trades >= o.MinTrades && !math.IsNaN(s) {
+					scores[i] = s
+				}
+			}
+		}()
+	}
+	for i := range candidates {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	best := 0
+	for i, s := range scores {
+		if s > scores[best] {
+			best = i
+		}
+	}
+	if math.IsInf(scores[best], -1) {
+		return defaultDetectorConfig, 0
+	}
+	return candidates[best], scores[best]
+}
+
+func printOptimize(r *OptimizeReport) {
+	fmt.Printf("\n🎯 WALK-FORWARD %s %s (%s, %s, %d kandidat)\n\n", r.Symbol, r.Timeframe, r.Metric, r.Search, r.Candidates)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "WINDOW TEST\tSKOR TRAIN\tSKOR TEST\tTRADES\tDEFAULT\tTRADES\tCONFIG")
+	for _, f := range r.Folds {
+		fmt.Fprintf(tw, "%s → %s\t%.2f\t%.2f\t%d\t%.2f\t%d\t%s\n",
+			f.TestFrom.Format("2006-01-02"), f.TestTo.Format("2006-01-02"), f.TrainScore,
+			f.TestScore, f.Test.Trades, f.DefaultScore, f.Default.Trades, f.Config.diff())
+	}
+	fmt.Fprintf(tw, "rata-rata\t\t%.2f\t%d\t%.2f\t%d\t\n", r.OOS, r.Trades, r.DefaultOOS, r.DefaultTrades)
+	tw.Flush()
+
+	fmt.Printf("\nTerbaik di window terakhir: %s (%s %.2f)\n", r.Best.diff(), r.Metric, r.BestScore)
+	switch {
+	case r.Best == defaultDetectorConfig:
+		fmt.Println("➖ Default tetap yang terbaik, tidak ada yang perlu disimpan")
+	case r.OOS <= r.DefaultOOS:
+		fmt.Println("⚠️  Hasil tuning kalah dari default di fold test (overfit), tetap pakai default")
+	default:
+		fmt.Printf("✅ Tuning mengalahkan default out-of-sample (%.2f vs %.2f)\n", r.OOS, r.DefaultOOS)
+	}
+	fmt.Println()
+}
+
+// hash identifies the S/R and pattern thresholds in cache keys.
+func (c detectorConfig) hash() string {
+	return shortHash(c)
+}
+
+// diff lists the thresholds that differ from the defaults.
+func (c detectorConfig) diff() string {
+	d := defaultDetectorConfig
+	var parts []string
+	add := func(name string, v, def float64) {
+		if v != def {
+			parts = append(parts, fmt.Sprintf("%s=%g", name, v))
+		}
+	}
+	add("sr-swing", float64(c.SR.SwingRadius), float64(d.SR.SwingRadius))
+	add("sr-tolerance", c.SR.Tolerance, d.SR.Tolerance)
+	add("pattern-swing", float64(c.Patterns.SwingRadius), float64(d.Patterns.SwingRadius))
+	add("hs-window", float64(c.Patterns.HSWindow), float64(d.Patterns.HSWindow))
+	add("shoulder-tolerance", c.Patterns.ShoulderTolerance, d.Patterns.ShoulderTolerance)
+	add("double-window", float64(c.Patterns.DoubleWindow), float64(d.Patterns.DoubleWindow))
+	add("double-tolerance", c.Patterns.DoubleTolerance, d.Patterns.DoubleTolerance)
+	add("triangle-window", float64(c.Patterns.TriangleWindow), float64(d.Patterns.TriangleWindow))
+	add("slope-cutoff", c.Patterns.SlopeCutoff, d.Patterns.SlopeCutoff)
+	if len(parts) == 0 {
+		return "default"
+	}
+	return strings.Join(parts, " ")
+}
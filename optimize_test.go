@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestRuleConfigMatchesTunedRules(t *testing.T) {
+	tuned := defaultDetectorConfig
+	tuned.SR.SwingRadius = 4
+	params := &detectorParams{}
+	params.set("SOLUSDT", "1h", &paramSet{Config: tuned, Long: []string{"near-support", "trend-up"}})
+
+	rules := func(s string) []rankTerm {
+		terms, err := parseRank(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return terms
+	}
+	tests := []struct {
+		name        string
+		symbol      string
+		long, short []rankTerm
+		want        detectorConfig
+		skipped     bool
+	}{
+		{"rule sama", "SOLUSDT", rules("near-support+trend-up"), nil, tuned, false},
+		{"long beda", "SOLUSDT", rules("macd-bull-cross+trend-up"), nil, defaultDetectorConfig, true},
+		{"tambah short", "SOLUSDT", rules("near-support+trend-up"), rules("near-resistance"), defaultDetectorConfig, true},
+		{"tanpa set", "BTCUSDT", rules("near-support+trend-up"), nil, defaultDetectorConfig, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, skipped := params.ruleConfig(tt.symbol, "1h", tt.long, tt.short)
+			if got != tt.want || skipped != tt.skipped {
+				t.Errorf("ruleConfig = %+v, skipped %v; mau %+v, %v", got, skipped, tt.want, tt.skipped)
+			}
+		})
+	}
+
+	// Tanpa rule (analyze, scan, watch) set tetap dipakai
+	if got := params.config("SOLUSDT", "1h"); got != tuned {
+		t.Errorf("config = %+v, mau set yang di-tune", got)
+	}
+}
@@ -0,0 +1,876 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// ================================
+// PAPER TRADING
+// ================================
+
+// paperEventLimit is how many events the state file keeps; the trade log
+// itself is never trimmed.
+const paperEventLimit = 500
+
+// paperOptions reuse the backtest fill model: rule entries, SL/TP, trailing,
+// fee, slippage and risk sizing behave exactly like in backtest.
+type paperOptions struct {
+	backtestOptions
+	Symbols   []string
+	State     string
+	WSURL     string
+	Cooldown  time.Duration
+	AIOnStart bool
+	Strict    bool // mode --ai: hanya verdict PASS
+	Reset     bool
+}
+
+// PaperAccount is the paper state on disk. It is rewritten after every
+// closed candle, so a restart picks up where the last run stopped.
+type PaperAccount struct {
+	Timeframe string               `json:"timeframe"`
+	Capital   float64              `json:"capital"`
+	Balance   float64              `json:"balance"` // modal + PnL realized, sudah dipotong fee
+	Peak      float64              `json:"peak"`
+	Created   time.Time            `json:"created"`
+	Updated   time.Time            `json:"updated"`
+	Marks     map[string]PaperMark `json:"marks"` // candle terakhir yang sudah diproses per symbol
+	Orders    []*PaperOrder        `json:"orders"`
+	Positions []*PaperPosition     `json:"positions"`
+	Trades    []BacktestTrade      `json:"trades"`
+	Events    []PaperEvent         `json:"events"`
+	Equity    []EquityPoint        `json:"equity_curve"`
+}
+
+type PaperMark struct {
+	Time  time.Time `json:"time"`
+	Price float64   `json:"price"`
+}
+
+// PaperOrder is an entry waiting for its fill. A rule signal fills at the
+// next open; an AI setup waits at its entry zone for up to --expire
+// candles (see fillSetup).
+type PaperOrder struct {
+	Symbol   string      `json:"symbol"`
+	Source   string      `json:"source"` // "rules" atau nama AI
+	Placed   time.Time   `json:"placed"`
+	Bars     int         `json:"bars"`
+	Signal   *btSignal   `json:"signal,omitempty"`
+	Setup    *TradeSetup `json:"setup,omitempty"`
+	Breakout bool        `json:"breakout,omitempty"`
+	ATR      float64     `json:"atr"`
+	Verdict  string      `json:"verdict,omitempty"`
+}
+
+// PaperPosition is an open trade plus the exit state backtest keeps in
+// btPosition, so both run through the same manage.
+type PaperPosition struct {
+	Symbol   string        `json:"symbol"`
+	Source   string        `json:"source"`
+	Trade    BacktestTrade `json:"trade"`
+	Qty      float64       `json:"qty"` // sisa posisi
+	Stop     float64       `json:"stop"`
+	Sizes    []float64     `json:"sizes"`
+	Next     int           `json:"next_tp"`
+	Risk     float64       `json:"risk"`
+	ATR      float64       `json:"atr"`
+	Best     float64       `json:"best"`
+	Trailing bool          `json:"trailing,omitempty"`
+}
+
+type PaperEvent struct {
+	Time   time.Time `json:"time"`
+	Symbol string    `json:"symbol"`
+	Event  string    `json:"event"`
+	Price  float64   `json:"price,omitempty"`
+	Note   string    `json:"note,omitempty"`
+}
+
+func newPaperPosition(symbol, source string, pos *btPosition) *PaperPosition {
+	p := &PaperPosition{Symbol: symbol, Source: source, Trade: *pos.trade, Risk: pos.trade.risk}
+	p.update(pos)
+	return p
+}
+
+// position hands the stored state to the backtest engine. The returned
+// btPosition points at p.Trade, so fills land on p directly.
+func (p *PaperPosition) position() *btPosition {
+	p.Trade.risk = p.Risk
+	return &btPosition{trade: &p.Trade, sign: p.sign(), qty: p.Qty, stop: p.Stop, sizes: p.Sizes, next: p.Next, atr: p.ATR, best: p.Best, trailing: p.Trailing}
+}
+
+func (p *PaperPosition) update(pos *btPosition) {
+	p.Qty, p.Stop, p.Sizes, p.Next = pos.qty, pos.stop, pos.sizes, pos.next
+	p.ATR, p.Best, p.Trailing = pos.atr, pos.best, pos.trailing
+}
+
+func (p *PaperPosition) sign() float64 {
+	if p.Trade.Side == "short" {
+		return -1
+	}
+	return 1
+}
+
+func (p *PaperPosition) unrealized(price float64) float64 {
+	return p.sign() * (price - p.Trade.Entry) * p.Qty
+}
+
+func defaultPaperState() string {
+	if v := os.Getenv("PAPER_STATE"); v != "" {
+		return v
+	}
+	return filepath.Join(filepath.Dir(defaultUsageLedger()), "paper.json")
+}
+
+func newPaperAccount(tf string, capital float64) *PaperAccount {
+	now := time.Now()
+	return &PaperAccount{Timeframe: tf, Capital: capital, Balance: capital, Peak: capital, Created: now, Updated: now, Marks: map[string]PaperMark{}}
+}
+
+// loadPaperAccount reads path; a missing file returns nil without error.
+func loadPaperAccount(path string) (*PaperAccount, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var a PaperAccount
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("state paper %s rusak: %w", path, err)
+	}
+	if a.Marks == nil {
+		a.Marks = map[string]PaperMark{}
+	}
+	return &a, nil
+}
+
+// save writes the account through a temp file so a crash never leaves a
+// half-written state behind.
+func (a *PaperAccount) save(path string) error {
+	a.Updated = time.Now()
+	if len(a.Events) > paperEventLimit {
+		a.Events = a.Events[len(a.Events)-paperEventLimit:]
+	}
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode state paper: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// equity is the balance plus open positions marked at their last close.
+func (a *PaperAccount) equity() float64 {
+	equity := a.Balance
+	for _, p := range a.Positions {
+		if m, ok := a.Marks[p.Symbol]; ok {
+			equity += p.unrealized(m.Price)
+		}
+	}
+	return equity
+}
+
+// mark appends the equity at a candle close. Symbols close on the same
+// time, so a point at the same time replaces the last one.
+func (a *PaperAccount) mark(at time.Time) {
+	equity := a.equity()
+	a.Peak = math.Max(a.Peak, equity)
+	pt := EquityPoint{Time: at, Equity: equity}
+	if a.Peak > 0 {
+		pt.Drawdown = (a.Peak - equity) / a.Peak * 100
+	}
+	n := len(a.Equity)
+	switch {
+	case n > 0 && at.Equal(a.Equity[n-1].Time):
+		a.Equity[n-1] = pt
+	case n == 0 || at.After(a.Equity[n-1].Time):
+		a.Equity = append(a.Equity, pt)
+	}
+}
+
+func (a *PaperAccount) hasPosition(symbol string) bool {
+	for _, p := range a.Positions {
+		if p.Symbol == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *PaperAccount) hasOrder(symbol string) bool {
+	for _, o := range a.Orders {
+		if o.Symbol == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *PaperAccount) log(e PaperEvent) {
+	a.Events = append(a.Events, e)
+	price := ""
+	if e.Price > 0 {
+		price = fmt.Sprintf(" @ %.4f", e.Price)
+	}
+	fmt.Printf("   💼 %s %s%s %s\n", e.Symbol, strings.ToUpper(e.Event), price, e.Note)
+}
+
+// paperTrader runs closed candles through the account. The caller holds
+// the lock that guards acct.
+type paperTrader struct {
+	opts paperOptions
+	acct *PaperAccount
+}
+
+// onCandle processes one closed candle of symbol: pending orders first,
+// then open positions, then the mark. It returns false for a candle that
+// was already processed, e.g. one seen again after a reconnect.
+func (p *paperTrader) onCandle(symbol string, c Candle) bool {
+	a := p.acct
+	if m, ok := a.Marks[symbol]; ok && !c.Time.After(m.Time) {
+		return false
+	}
+	o := p.opts.backtestOptions
+
+	// Order limit/stop yang terisi di candle ini hanya dicek SL-nya (fillBar)
+	resting := map[*PaperPosition]bool{}
+	var orders []*PaperOrder
+	for _, ord := range a.Orders {
+		if ord.Symbol != symbol {
+			orders = append(orders, ord)
+			continue
+		}
+		pos, cancel := p.fillOrder(ord, c, a.equity())
+		switch {
+		case pos != nil:
+			a.Balance += pos.Trade.PnL // fee entry
+			a.Positions = append(a.Positions, pos)
+			resting[pos] = ord.Signal == nil
+			a.log(PaperEvent{Time: c.Time, Symbol: symbol, Event: "open", Price: pos.Trade.Entry,
+				Note: fmt.Sprintf("%s %.6g | SL %.4f | TP %s (%s)", pos.Trade.Side, pos.Trade.Qty, pos.Trade.SL, joinFloats(roundPrices(pos.Trade.Targets), "/"), pos.Source)})
+		case cancel != "":
+			a.log(PaperEvent{Time: c.Time, Symbol: symbol, Event: cancel, Note: "order " + ord.Source + " dibatalkan"})
+		default:
+			orders = append(orders, ord)
+		}
+	}
+	a.Orders = orders
+
+	var positions []*PaperPosition
+	for _, pp := range a.Positions {
+		if pp.Symbol != symbol {
+			positions = append(positions, pp)
+			continue
+		}
+		pos := pp.position()
+		filled := len(pp.Trade.Exits)
+		if resting[pp] {
+			a.Balance += o.fillBar(pos, c)
+		} else {
+			a.Balance += o.manage(pos, c)
+		}
+		pp.update(pos)
+		for _, f := range pp.Trade.Exits[filled:] {
+			a.log(PaperEvent{Time: c.Time, Symbol: symbol, Event: f.Reason, Price: f.Price, Note: fmt.Sprintf("qty %.6g", f.Qty)})
+		}
+		if pp.Qty > 0 {
+			positions = append(positions, pp)
+			continue
+		}
+		a.Trades = append(a.Trades, pp.Trade)
+		a.log(PaperEvent{Time: c.Time, Symbol: symbol, Event: "closed", Note: fmt.Sprintf("%s PnL %+.2f (%+.2fR)", pp.Trade.Side, pp.Trade.PnL, pp.Trade.R)})
+	}
+	a.Positions = positions
+
+	a.Marks[symbol] = PaperMark{Time: c.Time, Price: c.Close.InexactFloat64()}
+	a.mark(c.Time)
+	return true
+}
+
+// fillOrder checks ord against c. It returns the opened position, or why
+// the order is cancelled; neither means it keeps waiting.
+func (p *paperTrader) fillOrder(ord *PaperOrder, c Candle, equity float64) (*PaperPosition, string) {
+	o := p.opts.backtestOptions
+	if ord.Signal != nil {
+		pos := o.open(ord.Signal, c, equity)
+		if pos == nil {
+			return nil, "unsized"
+		}
+		return newPaperPosition(ord.Symbol, ord.Source, pos), ""
+	}
+
+	if ord.Bars >= o.Expire {
+		return nil, "expired"
+	}
+	ord.Bars++
+	s := *ord.Setup
+	entry, cancel := o.fillSetup(s, ord.Breakout, c)
+	if cancel != "" || entry == 0 {
+		return nil, cancel
+	}
+	sign := 1.0
+	if s.Direction == "short" {
+		sign = -1
+	}
+	dist := sign * (entry - s.SL)
+	if dist <= 0 {
+		return nil, "invalidated" // stop order gap melewati SL
+	}
+	qty := o.size(entry, dist, equity)
+	if qty == 0 {
+		return nil, "unsized"
+	}
+	t := &BacktestTrade{Side: s.Direction, Signal: []string{ord.Source, ord.Verdict}, EntryTime: c.Time, Entry: entry, SL: s.SL, Targets: s.Targets(), Qty: qty, risk: qty * dist}
+	pos := o.fill(t, evenSizes(len(t.Targets)))
+	pos.atr = ord.ATR
+	return newPaperPosition(ord.Symbol, ord.Source, pos), ""
+}
+
+// ruleSignal runs the entry rules on the latest window of sw and queues a
+// market order for the next open, like backtest does.
+func (p *paperTrader) ruleSignal(sw *symbolWatch) {
+	if p.acct.hasPosition(sw.symbol) || p.acct.hasOrder(sw.symbol) {
+		return
+	}
+	o := p.opts.backtestOptions
+	o.Symbol = sw.symbol
+	o.detectors, _ = p.opts.params.ruleConfig(sw.symbol, p.opts.TF, o.Long, o.Short)
+	window := sw.candles[max(0, len(sw.candles)-o.Lookback):]
+	sig := o.signal(window)
+	if sig == nil {
+		return
+	}
+	last := window[len(window)-1]
+	p.acct.Orders = append(p.acct.Orders, &PaperOrder{Symbol: sw.symbol, Source: "rules", Placed: last.Time, Signal: sig, ATR: sig.ATR})
+	p.acct.log(PaperEvent{Time: last.Time, Symbol: sw.symbol, Event: "signal",
+		Note: fmt.Sprintf("%s market di open berikutnya (%s)", sig.Side, strings.Join(sig.Matched, ","))})
+}
+
+// placeSetup turns a validated LLM setup into a pending order. A newer
+// setup replaces the one still waiting for the same symbol.
+func (p *paperTrader) placeSetup(symbol string, analysis AIAnalysis, price, atr float64, at time.Time) {
+	skip := func(note string) {
+		p.acct.log(PaperEvent{Time: at, Symbol: symbol, Event: "skip", Note: note})
+	}
+	switch {
+	case analysis.Plan == nil:
+		skip("jawaban AI bukan plan JSON")
+		return
+	case analysis.Plan.Setup.Direction != "long" && analysis.Plan.Setup.Direction != "short":
+		skip("AI tidak memberi setup")
+		return
+	case analysis.Verdict == nil || analysis.Verdict.Verdict == verdictFail:
+		skip("setup gagal validator")
+		return
+	case p.opts.Strict && analysis.Verdict.Verdict != verdictPass:
+		skip("setup " + analysis.Verdict.Verdict + ", --strict hanya PASS")
+		return
+	case p.acct.hasPosition(symbol):
+		skip("masih ada posisi terbuka")
+		return
+	}
+
+	var orders []*PaperOrder
+	for _, o := range p.acct.Orders {
+		if o.Symbol == symbol {
+			p.acct.log(PaperEvent{Time: at, Symbol: symbol, Event: "replaced", Note: "order " + o.Source + " diganti setup baru"})
+			continue
+		}
+		orders = append(orders, o)
+	}
+
+	setup := analysis.Plan.Setup
+	ord := &PaperOrder{Symbol: symbol, Source: p.opts.AI, Placed: at, Setup: &setup, Breakout: isBreakout(setup, price), ATR: atr, Verdict: analysis.Verdict.Verdict}
+	p.acct.Orders = append(orders, ord)
+	kind := "limit"
+	if ord.Breakout {
+		kind = "stop"
+	}
+	p.acct.log(PaperEvent{Time: at, Symbol: symbol, Event: "order", Price: setup.EntryMid(),
+		Note: fmt.Sprintf("%s %s | SL %.4f | TP %s | %s, batal setelah %d candle", setup.Direction, kind, setup.SL, joinFloats(roundPrices(setup.Targets()), "/"), ord.Verdict, p.opts.Expire)})
+}
+
+// catchUp processes the candles of every symbol that are newer than the
+// account's marks, oldest first across symbols so the equity curve stays in
+// order. It only manages orders and positions; new signals wait for the
+// stream.
+func (p *paperTrader) catchUp(watches map[string]*symbolWatch) int {
+	type pending struct {
+		symbol string
+		candle Candle
+	}
+	var queue []pending
+	for _, sw := range watches {
+		m, ok := p.acct.Marks[sw.symbol]
+		if !ok {
+			continue
+		}
+		for _, c := range sw.candles {
+			if c.Time.After(m.Time) {
+				queue = append(queue, pending{sw.symbol, c})
+			}
+		}
+	}
+	sort.SliceStable(queue, func(i, j int) bool { return queue[i].candle.Time.Before(queue[j].candle.Time) })
+	n := 0
+	for _, q := range queue {
+		if p.onCandle(q.symbol, q.candle) {
+			n++
+		}
+	}
+	return n
+}
+
+func (p *paperTrader) save() {
+	if err := p.acct.save(p.opts.State); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Gagal simpan state paper: %v\n", err)
+	}
+}
+
+func parsePaperFlags(args []string) (paperOptions, error) {
+	var opts paperOptions
+	fs := flag.NewFlagSet("paper", flag.ContinueOnError)
+	symbols := fs.String("symbols", "", "daftar coin dipisah koma, contoh: SOL,BTC,ETH")
+	finish := dataFlags(fs, &opts.cliOptions)
+	finishRules := ruleFlags(fs, &opts.backtestOptions, defaultBacktestLong)
+	fs.StringVar(&opts.AI, "ai", "", "pakai setup AI ("+strings.Join(llmNames(), " / ")+") alih-alih --long/--short, hanya yang lolos validator")
+	aiFlags(fs, &opts.cliOptions)
+	fs.IntVar(&opts.Expire, "expire", 6, "mode --ai: batalkan entry yang belum kena setelah N candle")
+	fs.BoolVar(&opts.Strict, "strict", false, "mode --ai: hanya setup dengan verdict PASS, WARN ikut ditolak")
+	fs.DurationVar(&opts.Cooldown, "ai-cooldown", time.Hour, "jeda minimal antar panggilan AI per symbol")
+	fs.BoolVar(&opts.AIOnStart, "ai-on-start", false, "langsung panggil AI sekali setelah data awal siap")
+	fs.StringVar(&opts.WSURL, "ws-url", "", "base URL WebSocket kline format Binance (default dari --provider; ws://127.0.0.1:9443 untuk ws-stub)")
+	fs.StringVar(&opts.State, "state", defaultPaperState(), "file state akun paper: posisi, order, trade log, equity")
+	fs.BoolVar(&opts.Reset, "reset", false, "mulai akun baru dengan --capital, state lama ditimpa")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of paper (lihat juga: ai-trade paper status -h):\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\n%s", criteriaHelp())
+	}
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("argumen tidak dikenal: %s", strings.Join(fs.Args(), " "))
+	}
+	if err := finish(); err != nil {
+		return opts, err
+	}
+	if err := finishRules(); err != nil {
+		return opts, err
+	}
+	for _, s := range strings.Split(*symbols, ",") {
+		if strings.TrimSpace(s) != "" {
+			opts.Symbols = append(opts.Symbols, normalizeSymbol(s))
+		}
+	}
+	switch {
+	case len(opts.Symbols) == 0:
+		return opts, errors.New("--symbols wajib diisi")
+	case opts.State == "":
+		return opts, errors.New("--state wajib diisi")
+	}
+	wsURL, err := klineWSURL(opts.Provider, opts.WSURL)
+	if err != nil {
+		return opts, err
+	}
+	opts.WSURL = wsURL
+
+	if opts.AI == "" {
+		return opts, nil
+	}
+	if opts.Expire <= 0 {
+		return opts, errors.New("--expire harus lebih dari 0")
+	}
+	opts.LLM.Stream = false
+	if err := validateAI(&opts.cliOptions); err != nil {
+		return opts, err
+	}
+	switch {
+	case len(opts.Models) > 1:
+		return opts, errors.New("paper --ai butuh satu model, ensemble tidak memberi satu setup")
+	case opts.Format != "json":
+		return opts, errors.New("paper --ai butuh --format json")
+	}
+	return opts, checkAIKeys(opts.cliOptions)
+}
+
+func runPaper(args []string) error {
+	if len(args) > 0 && args[0] == "status" {
+		return runPaperStatus(args[1:])
+	}
+	opts, err := parsePaperFlags(args)
+	if err != nil {
+		return err
+	}
+	acct, err := loadPaperAccount(opts.State)
+	if err != nil {
+		return err
+	}
+	switch {
+	case acct == nil || opts.Reset:
+		acct = newPaperAccount(opts.TF, opts.Capital)
+		fmt.Printf("💼 Akun paper baru %s, modal %.2f → %s\n", opts.TF, opts.Capital, opts.State)
+	case acct.Timeframe != opts.TF:
+		return fmt.Errorf("state %s untuk timeframe %s, bukan %s (pakai --state lain atau --reset)", opts.State, acct.Timeframe, opts.TF)
+	default:
+		fmt.Printf("💼 Lanjut akun paper %s: equity %.2f, %d posisi, %d order pending → %s\n", acct.Timeframe, acct.equity(), len(acct.Positions), len(acct.Orders), opts.State)
+	}
+
+	provider, err := openProvider(opts.cliOptions)
+	if err != nil {
+		return err
+	}
+	iv := mustInterval(opts.TF)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	trader := &paperTrader{opts: opts, acct: acct}
+	watches := make(map[string]*symbolWatch)
+	for _, symbol := range opts.Symbols {
+		candles, err := seedCandles(provider, symbol, watchOptions{cliOptions: opts.cliOptions})
+		if err != nil {
+			return err
+		}
+		sw := &symbolWatch{symbol: symbol, candles: candles}
+		sw.state = evaluateWatch(sw, opts.cliOptions)
+		watches[symbol] = sw
+		fmt.Printf("👀 %s %s: %d candle awal, close %s\n", symbol, opts.TF, len(candles), sw.state.Summary.CurrentPrice)
+	}
+	for _, p := range acct.Positions {
+		if watches[p.Symbol] == nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Posisi %s tidak ada di --symbols, tidak di-update\n", p.Symbol)
+		}
+	}
+
+	// Candle yang close selama program mati, lalu symbol baru mulai dari sekarang
+	if n := trader.catchUp(watches); n > 0 {
+		fmt.Printf("⏩ %d candle terlewat diproses, equity %.2f\n", n, acct.equity())
+	}
+	for _, sw := range watches {
+		if _, ok := acct.Marks[sw.symbol]; !ok {
+			last := sw.candles[len(sw.candles)-1]
+			acct.Marks[sw.symbol] = PaperMark{Time: last.Time, Price: last.Close.InexactFloat64()}
+			acct.mark(last.Time)
+		}
+	}
+	trader.save()
+
+	var aiWG sync.WaitGroup
+	defer aiWG.Wait()
+	var mu sync.Mutex
+
+	watchOpts := watchOptions{cliOptions: opts.cliOptions, Cooldown: opts.Cooldown}
+	askAI := func(sw *symbolWatch, reasons []string) {
+		if acct.hasPosition(sw.symbol) {
+			return
+		}
+		triggerPaperAI(ctx, &aiWG, &mu, sw, watchOpts, reasons, trader)
+	}
+	if opts.AI != "" && opts.AIOnStart {
+		mu.Lock()
+		for _, sw := range watches {
+			askAI(sw, []string{"analisa awal"})
+		}
+		mu.Unlock()
+	}
+
+	onClosed := func(c Candle, symbol string) {
+		mu.Lock()
+		defer mu.Unlock()
+		sw, ok := watches[symbol]
+		if !ok {
+			return
+		}
+		sw.candles = trimCandles(mergeCandles(sw.candles, []Candle{c}), true, opts.Candles)
+		prev := sw.state
+		sw.state = evaluateWatch(sw, opts.cliOptions)
+		changes := materialChanges(prev, sw.state)
+		printWatchUpdate(sw, c, iv, changes)
+		if !trader.onCandle(symbol, c) {
+			return
+		}
+		printPaperPositions(acct, symbol)
+
+		if opts.AI == "" {
+			trader.ruleSignal(sw)
+		} else if len(changes) > 0 {
+			askAI(sw, changes)
+		}
+		trader.save()
+	}
+
+	err = followKlines(ctx, opts.WSURL, opts.Symbols, opts.TF, onClosed, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, sw := range watches {
+			if fresh, err := fetchLatest(provider, sw.symbol, opts.TF, 50); err == nil {
+				sw.candles = trimCandles(mergeCandles(sw.candles, closedOnly(fresh, time.Now())), true, opts.Candles)
+				sw.state = evaluateWatch(sw, opts.cliOptions)
+			}
+		}
+		if n := trader.catchUp(watches); n > 0 {
+			fmt.Printf("⏩ %d candle terlewat selama putus diproses\n", n)
+			trader.save()
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	trader.save()
+	mu.Unlock()
+	fmt.Printf("\n👋 Paper dihentikan, state disimpan → %s (ai-trade paper status)\n", opts.State)
+	return nil
+}
+
+// triggerPaperAI is triggerWatchAI that places the answer as an order.
+// mu guards sw and the account and must be held by the caller.
+func triggerPaperAI(ctx context.Context, wg *sync.WaitGroup, mu *sync.Mutex, sw *symbolWatch, opts watchOptions, reasons []string, trader *paperTrader) {
+	if sw.aiBusy || time.Since(sw.lastAI) < opts.Cooldown {
+		return
+	}
+	sw.aiBusy, sw.lastAI = true, time.Now()
+
+	candles := append([]Candle(nil), sw.candles...)
+	levels, patterns := sw.state.Levels, sw.state.Patterns
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		series := buildSeries(candles, mustInterval(opts.TF))
+		analysis := analyzeAI(ctx, opts.cliOptions, candles, series, sw.symbol, opts.TF, levels, patterns, nil)
+
+		mu.Lock()
+		defer mu.Unlock()
+		sw.aiBusy = false
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Printf("\n🤖 %s: analisa ulang karena %s\n", sw.symbol, strings.Join(reasons, "; "))
+		printBeautifulAnalysis(analysis, sw.symbol, opts.TF, "", levels, patterns, nil)
+
+		// Harga bisa sudah jalan selama AI berpikir, order diukur dari close terakhir
+		last := sw.candles[len(sw.candles)-1]
+		trader.placeSetup(sw.symbol, analysis, last.Close.InexactFloat64(), sw.state.Summary.ATRValue, last.Time)
+		trader.save()
+	}()
+}
+
+func printPaperPositions(a *PaperAccount, symbol string) {
+	m := a.Marks[symbol]
+	for _, p := range a.Positions {
+		if p.Symbol != symbol {
+			continue
+		}
+		upnl := p.unrealized(m.Price)
+		r := 0.0
+		if p.Risk > 0 {
+			r = upnl / p.Risk
+		}
+		fmt.Printf("   📌 %s %.6g @ %.4f | SL %.4f | uPnL %+.2f (%+.2fR)\n", p.Trade.Side, p.Qty, p.Trade.Entry, p.Stop, upnl, r)
+	}
+}
+
+func roundPrices(vs []float64) []float64 {
+	out := make([]float64, len(vs))
+	for i, v := range vs {
+		out[i] = math.Round(v*10000) / 10000
+	}
+	return out
+}
+
+// ================================
+// PAPER STATUS
+// ================================
+
+func runPaperStatus(args []string) error {
+	fs := flag.NewFlagSet("paper status", flag.ContinueOnError)
+	state := fs.String("state", defaultPaperState(), "file state akun paper")
+	rows := fs.Int("trades", backtestTradeRows, "jumlah trade terakhir yang ditampilkan")
+	chart := fs.String("chart", "paper_equity.html", "tulis equity curve HTML ke file ini (kosong = tidak)")
+	equityCSV := fs.String("equity", "", "tulis equity curve CSV ke file ini")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("argumen tidak dikenal: %s", strings.Join(fs.Args(), " "))
+	}
+	acct, err := loadPaperAccount(*state)
+	if err != nil {
+		return err
+	}
+	if acct == nil {
+		return fmt.Errorf("belum ada akun paper di %s, jalankan ai-trade paper dulu", *state)
+	}
+
+	printPaperStatus(acct, *rows)
+
+	if *equityCSV != "" {
+		if err := writeEquityCSV(*equityCSV, acct.Equity); err != nil {
+			return fmt.Errorf("tulis equity %s: %w", *equityCSV, err)
+		}
+		fmt.Printf("✅ Equity curve disimpan → %s\n", *equityCSV)
+	}
+	if *chart != "" && len(acct.Equity) > 0 {
+		report := &BacktestReport{Symbol: "paper", Timeframe: acct.Timeframe, From: acct.Equity[0].Time, To: acct.Equity[len(acct.Equity)-1].Time,
+			Metrics: acct.metrics(), Trades: acct.Trades, Equity: acct.Equity}
+		path, err := writeEquityChart(report, *chart)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("📈 Equity chart → %s\n", path)
+	}
+	return nil
+}
+
+// metrics are the backtest metrics of the closed trades, with the final
+// equity marked to the latest closes instead of the last curve point.
+func (a *PaperAccount) metrics() BacktestMetrics {
+	m := backtestMetrics(a.Trades, a.Equity, a.Capital, 0, mustInterval(a.Timeframe))
+	m.FinalEquity = a.equity()
+	m.Return = (m.FinalEquity - a.Capital) / a.Capital * 100
+	return m
+}
+
+func printPaperStatus(a *PaperAccount, rows int) {
+	m := a.metrics()
+	unrealized := m.FinalEquity - a.Balance
+	fmt.Printf("\n💼 PAPER %s (mulai %s, update %s)\n\n", a.Timeframe, a.Created.Format("2006-01-02 15:04"), a.Updated.Format("2006-01-02 15:04"))
+
+	pf := "-"
+	if m.ProfitFactor > 0 {
+		pf = fmt.Sprintf("%.2f", m.ProfitFactor)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Modal awal\t%.2f\n", a.Capital)
+	fmt.Fprintf(tw, "Realized PnL\t%+.2f (%d trade, fee %.2f)\n", a.Balance-a.Capital, m.Trades, m.Fees)
+	fmt.Fprintf(tw, "Unrealized PnL\t%+.2f (%d posisi)\n", unrealized, len(a.Positions))
+	fmt.Fprintf(tw, "Equity\t%.2f (%+.2f%%)\n", m.FinalEquity, m.Return)
+	fmt.Fprintf(tw, "Max drawdown\t%.2f%%\n", m.MaxDrawdown)
+	if m.Trades > 0 {
+		fmt.Fprintf(tw, "Win rate\t%.1f%% (%d menang)\n", m.WinRate, m.Wins)
+		fmt.Fprintf(tw, "Expectancy\t%.2f per trade (%+.2fR)\n", m.Expectancy, m.ExpectancyR)
+		fmt.Fprintf(tw, "Profit factor\t%s\n", pf)
+	}
+	tw.Flush()
+
+	if len(a.Equity) > 1 {
+		values := make([]float64, len(a.Equity))
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for i, p := range a.Equity {
+			values[i] = p.Equity
+			lo, hi = math.Min(lo, p.Equity), math.Max(hi, p.Equity)
+		}
+		fmt.Printf("\nEquity curve (%d candle, %.2f → %.2f):\n  %s\n", len(a.Equity), lo, hi, sparkline(values, 60))
+	}
+
+	if len(a.Positions) > 0 {
+		fmt.Printf("\nPosisi terbuka:\n")
+		tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "SYMBOL\tSIDE\tENTRY\tQTY\tSL\tTP BERIKUTNYA\tHARGA\tUPNL\tR\tSEJAK\tSUMBER")
+		for _, p := range a.Positions {
+			mark := a.Marks[p.Symbol]
+			upnl := p.unrealized(mark.Price)
+			r := 0.0
+			if p.Risk > 0 {
+				r = upnl / p.Risk
+			}
+			next := "-"
+			if p.Next < len(p.Trade.Targets) {
+				next = fmt.Sprintf("tp%d %.4f", p.Next+1, p.Trade.Targets[p.Next])
+			}
+			stop := fmt.Sprintf("%.4f", p.Stop)
+			if p.Trailing {
+				stop += " (trail)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%.4f\t%.6g\t%s\t%s\t%.4f\t%+.2f\t%+.2f\t%s\t%s\n",
+				p.Symbol, p.Trade.Side, p.Trade.Entry, p.Qty, stop, next, mark.Price, upnl, r, p.Trade.EntryTime.Format("2006-01-02 15:04"), p.Source)
+		}
+		tw.Flush()
+	}
+
+	if len(a.Orders) > 0 {
+		fmt.Printf("\nOrder pending:\n")
+		tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "SYMBOL\tSIDE\tTIPE\tENTRY\tSL\tUMUR\tSUMBER")
+		for _, o := range a.Orders {
+			if o.Signal != nil {
+				fmt.Fprintf(tw, "%s\t%s\tmarket\topen berikutnya\t-\t%d\t%s\n", o.Symbol, o.Signal.Side, o.Bars, o.Source)
+				continue
+			}
+			kind := "limit"
+			if o.Breakout {
+				kind = "stop"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%.4f\t%.4f\t%d\t%s %s\n", o.Symbol, o.Setup.Direction, kind, o.Setup.EntryMid(), o.Setup.SL, o.Bars, o.Source, o.Verdict)
+		}
+		tw.Flush()
+	}
+
+	if len(a.Trades) == 0 {
+		fmt.Println("\nBelum ada trade yang selesai")
+		return
+	}
+	trades := a.Trades[max(0, len(a.Trades)-rows):]
+	fmt.Printf("\n%d trade terakhir:\n", len(trades))
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENTRY\tSIDE\tPRICE\tSL\tEXIT\tBARS\tPNL\tR\tSINYAL")
+	for _, t := range trades {
+		fmt.Fprintf(tw, "%s\t%s\t%.4f\t%.4f\t%s\t%d\t%+.2f\t%+.2f\t%s\n",
+			t.EntryTime.Format("2006-01-02 15:04"), t.Side, t.Entry, t.SL, t.Reason, t.Bars, t.PnL, t.R, strings.Join(t.Signal, ","))
+	}
+	tw.Flush()
+	fmt.Println()
+}
+
+// sparkline draws values as at most width block characters, sampling
+// evenly and always keeping the last value.
+func sparkline(values []float64, width int) string {
+	blocks := []rune("▁▂▃▄▅▆▇█")
+	if len(values) > width {
+		sampled := make([]float64, width)
+		for i := range sampled {
+			sampled[i] = values[i*(len(values)-1)/(width-1)]
+		}
+		values = sampled
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if hi > lo {
+			i = int((v-lo)/(hi-lo)*float64(len(blocks)-1) + 0.5)
+		}
+		b.WriteRune(blocks[i])
+	}
+	return b.String()
+}
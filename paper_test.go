@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func testTrader() *paperTrader {
+	o := backtestOptions{SLATR: 1, TPs: []float64{1, 2}, TPSizes: []float64{0.5, 0.5}, Capital: 1000, Risk: 1, TrailATR: 1, Expire: 3}
+	o.TF = "1h"
+	return &paperTrader{opts: paperOptions{backtestOptions: o}, acct: newPaperAccount("1h", 1000)}
+}
+
+func TestPaperAIFillBarOnlyChecksStop(t *testing.T) {
+	p := testTrader()
+	s := TradeSetup{Direction: "long", EntryLow: 99, EntryHigh: 101, SL: 98, TP1: 102, TP2: 104}
+	p.acct.Orders = []*PaperOrder{{Symbol: "X", Source: "mock", Setup: &s, ATR: 1, Verdict: verdictPass}}
+
+	// Buka di bawah limit (terisi di open) lalu sempat ke TP1 di bar yang sama
+	candles := bars([4]float64{99.5, 102.5, 99.2, 101.5}, [4]float64{101.5, 102.2, 101, 102})
+	p.onCandle("X", candles[0])
+	if len(p.acct.Positions) != 1 {
+		t.Fatalf("posisi = %d, mau 1", len(p.acct.Positions))
+	}
+	pos := p.acct.Positions[0]
+	if len(pos.Trade.Exits) != 0 || pos.Next != 0 {
+		t.Fatalf("TP diambil di bar fill: %+v", pos.Trade.Exits)
+	}
+	p.onCandle("X", candles[1])
+	if pos.Next != 1 || len(pos.Trade.Exits) != 1 || !pos.Trade.Exits[0].Time.Equal(candles[1].Time) {
+		t.Fatalf("TP1 harus kena di bar berikutnya: %+v", pos.Trade.Exits)
+	}
+}
+
+func TestPaperRuleOrderTrailsAcrossRestart(t *testing.T) {
+	p := testTrader()
+	p.acct.Marks["X"] = PaperMark{Time: barStart.Add(-1), Price: 100}
+	p.acct.Orders = []*PaperOrder{{Symbol: "X", Source: "rules", Signal: &btSignal{Side: "long", ATR: 1}, ATR: 1}}
+
+	// Market di open 100: SL 99, TP 101/102, qty 10 (risk 1% dari 1000)
+	candles := bars([4]float64{100, 100.5, 99.5, 100.2}, [4]float64{100.2, 101.5, 100.1, 101.2}, [4]float64{101, 101.1, 100, 100.2})
+	if !p.onCandle("X", candles[0]) || p.onCandle("X", candles[0]) {
+		t.Fatal("candle yang sama harus diproses sekali")
+	}
+	p.onCandle("X", candles[1])
+	pos := p.acct.Positions[0]
+	if pos.Next != 1 || pos.Stop != 100.5 || !pos.Trailing {
+		t.Fatalf("setelah TP1 SL harus trailing ke 100.5: %+v", pos)
+	}
+
+	path := filepath.Join(t.TempDir(), "paper.json")
+	if err := p.acct.save(path); err != nil {
+		t.Fatal(err)
+	}
+	acct, err := loadPaperAccount(path)
+	if err != nil || acct == nil {
+		t.Fatalf("load: %v", err)
+	}
+	p.acct = acct
+	p.onCandle("X", candles[2])
+
+	if len(acct.Positions) != 0 || len(acct.Trades) != 1 {
+		t.Fatalf("posisi %d, trade %d", len(acct.Positions), len(acct.Trades))
+	}
+	// TP1 5 x 1 + trail 5 x 0.5 = 7.5, risk 10
+	tr := acct.Trades[0]
+	if tr.Reason != "trail" || tr.R != 0.75 || acct.Balance != 1007.5 {
+		t.Errorf("trade %s R %.2f, balance %.2f; mau trail 0.75, 1007.50", tr.Reason, tr.R, acct.Balance)
+	}
+	if len(acct.Equity) != 3 {
+		t.Errorf("equity point = %d, mau 3", len(acct.Equity))
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ================================
+// STRUCTURED TRADE PLAN
+// ================================
+
+// TradePlan is the JSON answer the LLM must give with --format json (see
+// prompts/partials/json-contract.tmpl). Prices are plain numbers.
+type TradePlan struct {
+	Symbol     string         `json:"symbol"`
+	Timeframe  string         `json:"timeframe"`
+	Trend      PlanTrend      `json:"trend"`
+	Scenarios  []PlanScenario `json:"scenarios"`
+	Setup      TradeSetup     `json:"setup"`
+	Confidence PlanConfidence `json:"confidence"`
+	KeyRisk    string         `json:"key_risk"`
+	Insights   []string       `json:"insights"`
+}
+
+type PlanTrend struct {
+	Direction string `json:"direction"` // bullish, bearish, neutral
+	Strength  int    `json:"strength"`  // 1-10
+	Summary   string `json:"summary"`
+}
+
+type PlanScenario struct {
+	Name        string  `json:"name"` // bullish, neutral, bearish
+	Probability float64 `json:"probability"`
+	Trigger     string  `json:"trigger"`
+	Target      float64 `json:"target"`
+	Description string  `json:"description"`
+}
+
+type TradeSetup struct {
+	Direction string  `json:"direction"` // long, short, none
+	EntryLow  float64 `json:"entry_low"`
+	EntryHigh float64 `json:"entry_high"`
+	Trigger   string  `json:"trigger"`
+	TP1       float64 `json:"tp1"`
+	TP2       float64 `json:"tp2"`
+	TP3       float64 `json:"tp3"`
+	SL        float64 `json:"sl"`
+	RR        float64 `json:"rr"`
+}
+
+type PlanConfidence struct {
+	SetupQuality    int `json:"setup_quality"`
+	MarketAlignment int `json:"market_alignment"`
+	Timing          int `json:"timing"`
+}
+
+const (
+	probabilityTolerance = 5.0  // scenarios must sum to 100 ± this
+	rrTolerance          = 0.15 // or 10% of the computed RR, whichever is larger
+)
+
+// EntryMid is the middle of the entry zone.
+func (s TradeSetup) EntryMid() float64 { return (s.EntryLow + s.EntryHigh) / 2 }
+
+// Target is the price RR is measured to: TP2, or TP1 when there is no TP2.
+func (s TradeSetup) Target() float64 {
+	if s.TP2 > 0 {
+		return s.TP2
+	}
+	return s.TP1
+}
+
+// ComputedRR is reward over risk from the entry mid to Target and SL.
+func (s TradeSetup) ComputedRR() float64 {
+	risk := math.Abs(s.EntryMid() - s.SL)
+	if risk == 0 {
+		return 0
+	}
+	return math.Abs(s.Target()-s.EntryMid()) / risk
+}
+
+// Targets returns the TPs that are set, nearest first.
+func (s TradeSetup) Targets() []float64 {
+	var tps []float64
+	for _, tp := range []float64{s.TP1, s.TP2, s.TP3} {
+		if tp > 0 {
+			tps = append(tps, tp)
+		}
+	}
+	return tps
+}
+
+// parsePlan pulls the JSON object out of an answer (models like to wrap it
+// in ```json fences or add a sentence) and decodes it.
+func parsePlan(content string) (*TradePlan, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("tidak ada objek JSON di jawaban")
+	}
+	var plan TradePlan
+	if err := json.Unmarshal([]byte(content[start:end+1]), &plan); err != nil {
+		return nil, fmt.Errorf("JSON tidak valid: %w", err)
+	}
+	return &plan, nil
+}
+
+// Validate lists every way the plan breaks the contract. An empty list
+// means the plan can be rendered and used downstream.
+func (p *TradePlan) Validate() []string {
+	var problems []string
+	add := func(format string, args ...any) { problems = append(problems, fmt.Sprintf(format, args...)) }
+
+	switch p.Trend.Direction {
+	case "bullish", "bearish", "neutral":
+	default:
+		add("trend.direction %q harus bullish, bearish atau neutral", p.Trend.Direction)
+	}
+	checkScore := func(name string, v int) {
+		if v < 1 || v > 10 {
+			add("%s = %d, harus 1-10", name, v)
+		}
+	}
+	checkScore("trend.strength", p.Trend.Strength)
+	checkScore("confidence.setup_quality", p.Confidence.SetupQuality)
+	checkScore("confidence.market_alignment", p.Confidence.MarketAlignment)
+	checkScore("confidence.timing", p.Confidence.Timing)
+
+	if len(p.Scenarios) == 0 {
+		add("scenarios kosong")
+	}
+	total := 0.0
+	for _, sc := range p.Scenarios {
+		if sc.Probability < 0 || sc.Probability > 100 {
+			add("probability scenario %s = %.1f, harus 0-100", sc.Name, sc.Probability)
+		}
+		total += sc.Probability
+	}
+	if len(p.Scenarios) > 0 && math.Abs(total-100) > probabilityTolerance {
+		add("total probability scenarios = %.1f, harus sekitar 100", total)
+	}
+
+	return append(problems, p.Setup.validate()...)
+}
+
+func (s TradeSetup) validate() []string {
+	var problems []string
+	add := func(format string, args ...any) { problems = append(problems, fmt.Sprintf(format, args...)) }
+
+	if s.Direction == "none" {
+		return nil
+	}
+	if s.Direction != "long" && s.Direction != "short" {
+		return []string{fmt.Sprintf("setup.direction %q harus long, short atau none", s.Direction)}
+	}
+	if s.EntryLow <= 0 || s.EntryHigh <= 0 || s.SL <= 0 || s.TP1 <= 0 {
+		add("entry_low, entry_high, tp1 dan sl wajib diisi (> 0)")
+		return problems
+	}
+	if s.EntryLow > s.EntryHigh {
+		add("entry_low %.4f lebih besar dari entry_high %.4f", s.EntryLow, s.EntryHigh)
+	}
+
+	long := s.Direction == "long"
+	if long && s.SL >= s.EntryLow {
+		add("SL %.4f harus di bawah entry (%.4f) untuk long", s.SL, s.EntryLow)
+	}
+	if !long && s.SL <= s.EntryHigh {
+		add("SL %.4f harus di atas entry (%.4f) untuk short", s.SL, s.EntryHigh)
+	}
+
+	side := "di atas"
+	if !long {
+		side = "di bawah"
+	}
+	prev := s.EntryMid()
+	for i, tp := range s.Targets() {
+		if (long && tp <= prev) || (!long && tp >= prev) {
+			add("TP%d %.4f harus %s entry/TP sebelumnya untuk %s", i+1, tp, side, s.Direction)
+		}
+		prev = tp
+	}
+
+	rr := s.ComputedRR()
+	if tol := math.Max(rrTolerance, rr*0.1); math.Abs(rr-s.RR) > tol {
+		add("rr %.2f tidak cocok dengan hitungan (%.2f dari entry %.4f, target %.4f, SL %.4f)", s.RR, rr, s.EntryMid(), s.Target(), s.SL)
+	}
+	return problems
+}
+
+// correctionPrompt is the single re-ask sent when a plan fails to parse or
+// validate.
+func correctionPrompt(original, answer string, problems []string) string {
+	var b strings.Builder
+	b.WriteString(original)
+	b.WriteString("\n\n## YOUR PREVIOUS ANSWER WAS REJECTED\n")
+	for _, p := range problems {
+		fmt.Fprintf(&b, "- %s\n", p)
+	}
+	b.WriteString("\nPrevious answer:\n")
+	b.WriteString(answer)
+	b.WriteString("\n\nFix every problem above and return ONLY the corrected JSON object, no other text.\n")
+	return b.String()
+}
+
+// renderPlan turns a validated plan into the familiar terminal layout.
+func renderPlan(p *TradePlan) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📊 %s ANALYSIS (%s)\n\n", p.Symbol, p.Timeframe)
+	fmt.Fprintf(&b, "🎯 TREND: %s (%d/10)\n", strings.ToUpper(p.Trend.Direction), p.Trend.Strength)
+	if p.Trend.Summary != "" {
+		fmt.Fprintf(&b, "• %s\n", p.Trend.Summary)
+	}
+
+	b.WriteString("\n📈 PROBABILITY SCENARIOS\n")
+	for i, sc := range p.Scenarios {
+		branch := "├"
+		if i == len(p.Scenarios)-1 {
+			branch = "└"
+		}
+		fmt.Fprintf(&b, "%s %s %s (%.0f%%)", branch, emojiFor(sc.Name), titleCase(sc.Name), sc.Probability)
+		if sc.Target > 0 {
+			fmt.Fprintf(&b, " → $%.4f", sc.Target)
+		}
+		if sc.Description != "" {
+			fmt.Fprintf(&b, " - %s", sc.Description)
+		}
+		b.WriteString("\n")
+		if sc.Trigger != "" {
+			fmt.Fprintf(&b, "│   Trigger: %s\n", sc.Trigger)
+		}
+	}
+
+	s := p.Setup
+	if s.Direction == "none" {
+		b.WriteString("\n⏸️  NO TRADE: tunggu setup yang lebih jelas\n")
+	} else {
+		mid := s.EntryMid()
+		fmt.Fprintf(&b, "\n⚡ SETUP: %s\n", strings.ToUpper(s.Direction))
+		fmt.Fprintf(&b, "├ Entry: $%.4f - $%.4f\n", s.EntryLow, s.EntryHigh)
+		if s.Trigger != "" {
+			fmt.Fprintf(&b, "├ Trigger: %s\n", s.Trigger)
+		}
+		for i, tp := range s.Targets() {
+			fmt.Fprintf(&b, "├ TP%d: $%.4f (%.1f%%)\n", i+1, tp, math.Abs(tp-mid)/mid*100)
+		}
+		fmt.Fprintf(&b, "└ SL: $%.4f (%.1f%% risk) | RR: 1:%.2f\n", s.SL, math.Abs(mid-s.SL)/mid*100, s.RR)
+	}
+
+	c := p.Confidence
+	fmt.Fprintf(&b, "\n📊 CONFIDENCE: setup %d/10 • alignment %d/10 • timing %d/10\n", c.SetupQuality, c.MarketAlignment, c.Timing)
+	if p.KeyRisk != "" {
+		fmt.Fprintf(&b, "⚠️  Key risk: %s\n", p.KeyRisk)
+	}
+	if len(p.Insights) > 0 {
+		b.WriteString("\n💡 INSIGHTS\n")
+		for _, in := range p.Insights {
+			fmt.Fprintf(&b, "• %s\n", in)
+		}
+	}
+	return b.String()
+}
+
+func emojiFor(name string) string {
+	switch name {
+	case "bullish", "long":
+		return "🟢"
+	case "bearish", "short":
+		return "🔴"
+	}
+	return "🟡"
+}
+
+func titleCase(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPlan() TradePlan {
+	return TradePlan{
+		Symbol: "SOLUSDT", Timeframe: "1h",
+		Trend:      PlanTrend{Direction: "bullish", Strength: 7, Summary: "higher low di atas EMA50"},
+		Scenarios:  []PlanScenario{{Name: "bullish", Probability: 60}, {Name: "neutral", Probability: 25}, {Name: "bearish", Probability: 15}},
+		Setup:      TradeSetup{Direction: "long", EntryLow: 99, EntryHigh: 100, TP1: 104, TP2: 107, SL: 96, RR: 2.14},
+		Confidence: PlanConfidence{SetupQuality: 7, MarketAlignment: 6, Timing: 5},
+	}
+}
+
+func TestPlanValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		edit     func(p *TradePlan)
+		problems []string // potongan pesan, urut
+	}{
+		{name: "plan valid"},
+		{
+			name:     "probability total 80",
+			edit:     func(p *TradePlan) { p.Scenarios[1].Probability = 5 },
+			problems: []string{"total probability scenarios = 80.0"},
+		},
+		{
+			name: "probability total 104 masih toleransi",
+			edit: func(p *TradePlan) { p.Scenarios[2].Probability = 19 },
+		},
+		{
+			name: "probability di luar 0-100",
+			edit: func(p *TradePlan) {
+				p.Scenarios = []PlanScenario{{Name: "bullish", Probability: 120}, {Name: "bearish", Probability: -20}}
+			},
+			problems: []string{"scenario bullish = 120.0", "scenario bearish = -20.0"},
+		},
+		{
+			name:     "scenarios kosong",
+			edit:     func(p *TradePlan) { p.Scenarios = nil },
+			problems: []string{"scenarios kosong"},
+		},
+		{
+			name:     "SL long di atas entry",
+			edit:     func(p *TradePlan) { p.Setup.SL, p.Setup.RR = 99.2, 25 },
+			problems: []string{"SL 99.2000 harus di bawah entry (99.0000) untuk long"},
+		},
+		{
+			name: "SL short di bawah entry",
+			edit: func(p *TradePlan) {
+				p.Setup = TradeSetup{Direction: "short", EntryLow: 100, EntryHigh: 101, TP1: 96, SL: 100.8, RR: 14.5}
+			},
+			problems: []string{"SL 100.8000 harus di atas entry (101.0000) untuk short"},
+		},
+		{
+			name:     "RR dari AI beda dengan hitungan",
+			edit:     func(p *TradePlan) { p.Setup.RR = 3 },
+			problems: []string{"rr 3.00 tidak cocok dengan hitungan (2.14"},
+		},
+		{
+			name:     "TP di sisi yang salah",
+			edit:     func(p *TradePlan) { p.Setup.TP1, p.Setup.TP2, p.Setup.RR = 98, 0, 0.43 },
+			problems: []string{"TP1 98.0000 harus di atas entry/TP sebelumnya untuk long"},
+		},
+		{
+			name:     "field wajib kosong",
+			edit:     func(p *TradePlan) { p.Setup.SL = 0 },
+			problems: []string{"entry_low, entry_high, tp1 dan sl wajib diisi"},
+		},
+		{
+			name: "tanpa setup tidak dicek",
+			edit: func(p *TradePlan) { p.Setup = TradeSetup{Direction: "none"} },
+		},
+		{
+			name:     "skor dan arah trend",
+			edit:     func(p *TradePlan) { p.Trend.Direction, p.Trend.Strength, p.Confidence.Timing = "up", 0, 11 },
+			problems: []string{`trend.direction "up"`, "trend.strength = 0", "confidence.timing = 11"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := testPlan()
+			if tt.edit != nil {
+				tt.edit(&plan)
+			}
+			problems := plan.Validate()
+			if len(problems) != len(tt.problems) {
+				t.Fatalf("problems %q, mau %d", problems, len(tt.problems))
+			}
+			for i, want := range tt.problems {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problem %d %q, mau berisi %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePlan(t *testing.T) {
+	raw, err := json.Marshal(testPlan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{name: "JSON polos", content: string(raw)},
+		{name: "code fence", content: "```json\n" + string(raw) + "\n```"},
+		{name: "prosa di depan dan belakang", content: "Berikut analisanya:\n\n" + string(raw) + "\n\nSemoga membantu!"},
+		{name: "tanpa JSON", content: "Maaf, data tidak cukup untuk analisa.", err: "tidak ada objek JSON"},
+		{name: "JSON rusak", content: `{"symbol": "SOLUSDT", "trend": }`, err: "JSON tidak valid"},
+		{name: "kurung tutup sebelum buka", content: "} lalu {", err: "tidak ada objek JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := parsePlan(tt.content)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err %v, mau %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plan.Symbol != "SOLUSDT" || plan.Setup.TP2 != 107 || len(plan.Scenarios) != 3 {
+				t.Errorf("plan %+v", plan)
+			}
+		})
+	}
+}
+
+func TestCorrectionPrompt(t *testing.T) {
+	got := correctionPrompt("PROMPT ASLI", `{"setup":{}}`, []string{"rr 3.00 tidak cocok", "scenarios kosong"})
+	for _, want := range []string{"PROMPT ASLI\n\n## YOUR PREVIOUS ANSWER WAS REJECTED\n", "- rr 3.00 tidak cocok\n- scenarios kosong\n", "Previous answer:\n{\"setup\":{}}\n", "return ONLY the corrected JSON object"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("correction prompt tanpa %q:\n%s", want, got)
+		}
+	}
+}
+
+// askPlan runs callSelectedAI on closed candles, so the cache plays no part.
+func askPlan(t *testing.T, opts cliOptions) AIAnalysis {
+	t.Helper()
+	prompt, err := loadPrompt(defaultPrompt, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.prompt, opts.Format = prompt, "json"
+	candles := waveCandles(150, time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC))
+	series := buildSeries(candles, mustInterval("1h"))
+	levels := detectSupportResistance(candles, defaultDetectorConfig.SR)
+	return callSelectedAI(context.Background(), opts, candles, series, "SOLUSDT", "1h", levels, nil, nil)
+}
+
+func TestInvalidPlanIsReaskedOnce(t *testing.T) {
+	bad := testPlan()
+	bad.Setup.RR = 5
+	raw, _ := json.Marshal(bad)
+	script := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(script, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mock selalu memberi jawaban yang sama: koreksi cuma sekali, lalu menyerah
+	out := askPlan(t, cliOptions{AI: "mock", LLM: LLMConfig{Name: "mock", Model: mockScriptPrefix + script}})
+	if out.Usage.Calls != 2 {
+		t.Errorf("request = %d, mau 2 (satu koreksi)", out.Usage.Calls)
+	}
+	if len(out.Problems) != 1 || !strings.Contains(out.Problems[0], "rr 5.00") {
+		t.Errorf("problems %q", out.Problems)
+	}
+	if !strings.Contains(out.Content, "PLAN MASIH TIDAK VALID") {
+		t.Error("plan yang tetap salah tidak ditandai")
+	}
+}
+
+func TestCorrectionFixesPlan(t *testing.T) {
+	bad, good := testPlan(), testPlan()
+	bad.Scenarios[0].Probability = 30
+	answers := []TradePlan{bad, good}
+
+	var prompts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompts = append(prompts, req.Messages[len(req.Messages)-1].Content)
+		plan, _ := json.Marshal(answers[min(len(prompts), len(answers))-1])
+		json.NewEncoder(w).Encode(map[string]any{"choices": []any{map[string]any{"message": map[string]string{"content": "```json\n" + string(plan) + "\n```"}}}})
+	}))
+	defer srv.Close()
+
+	out := askPlan(t, cliOptions{AI: "local", LLM: LLMConfig{Name: "local", BaseURL: srv.URL, Model: "test"}})
+	if len(prompts) != 2 {
+		t.Fatalf("request = %d, mau 2", len(prompts))
+	}
+	if strings.Contains(prompts[0], "REJECTED") || !strings.HasPrefix(prompts[1], prompts[0]) {
+		t.Error("koreksi harus prompt asli ditambah penolakan")
+	}
+	if !strings.Contains(prompts[1], "- total probability scenarios = 70.0") {
+		t.Errorf("koreksi tanpa daftar masalah:\n%s", prompts[1][len(prompts[0]):])
+	}
+	if out.Plan == nil || len(out.Problems) != 0 || out.Plan.Scenarios[0].Probability != 60 || out.Usage.Calls != 2 {
+		t.Errorf("plan hasil koreksi %+v, problems %q, calls %d", out.Plan, out.Problems, out.Usage.Calls)
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sdcoffey/techan"
+)
+
+// ================================
+// PROMPT TEMPLATES
+// ================================
+
+//go:embed prompts/*.tmpl prompts/partials/*.tmpl
+var embeddedPrompts embed.FS
+
+// jsonContract is the partial that asks for a TradePlan. Templates place it
+// with {{template "json-contract" .}}; it is appended to those that don't.
+const jsonContract = "json-contract"
+
+const defaultPrompt = "deepthink-en"
+
+// Every template starts with {{/* version: N ... */}}. Bump it whenever the
+// wording changes so cached answers for the old wording are not reused.
+var promptVersionRe = regexp.MustCompile(`^\{\{-?\s*/\*\s*version:\s*([^\s*|]+)`)
+
+// PromptData is everything a prompt template can use.
+type PromptData struct {
+	Symbol          string
+	Timeframe       string
+	Timestamp       time.Time
+	Indicators      IndicatorSummary
+	Levels          []PromptLevel
+	Patterns        []PromptPattern
+	MarketStructure MarketStructure
+	TimeAnalysis    TimeAnalysis
+	MTF             *MultiTimeframe
+	JSONOutput      bool // answer must be a TradePlan JSON object
+}
+
+type PromptLevel struct {
+	Type      string  `json:"type"`
+	Price     string  `json:"price"`
+	Strength  int     `json:"strength"`
+	Touches   int     `json:"-"`
+	Distance  string  `json:"distance"`
+	Direction string  `json:"direction"`
+	Value     float64 `json:"-"`
+}
+
+type PromptPattern struct {
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	Confidence  string  `json:"confidence"`
+	Description string  `json:"description"`
+	Implication string  `json:"implication"`
+	Timeframe   string  `json:"timeframe"`
+	Historical  string  `json:"historical_hit_rate,omitempty"`
+	Value       float64 `json:"-"`
+}
+
+// newPromptData collects the analysis for the last candle of series.
+func newPromptData(series *techan.TimeSeries, symbol, tf string, srLevels []SupportResistance, patterns []Pattern, mtf *MultiTimeframe) PromptData {
+	// Timestamp = open time candle terakhir, bukan jam sekarang, supaya prompt
+	// yang sama di candle yang sama bisa diambil dari cache
+	asOf := time.Now()
+	if last := series.LastCandle(); last != nil {
+		asOf = last.Period.Start
+	}
+	data := PromptData{
+		Symbol:          symbol,
+		Timeframe:       tf,
+		Timestamp:       asOf,
+		Indicators:      summarizeIndicators(series, symbol, tf),
+		MarketStructure: analyzeMarketStructure(series, srLevels),
+		MTF:             mtf,
+	}
+	data.TimeAnalysis = getTimeAnalysis(tf, data.Timestamp)
+	currentPrice := data.Indicators.Price
+
+	// Build Support/Resistance levels with strength analysis
+	for _, level := range srLevels {
+		distance := ((currentPrice - level.Price) / currentPrice) * 100
+		data.Levels = append(data.Levels, PromptLevel{
+			Type:      level.Type,
+			Price:     fmt.Sprintf("%.4f", level.Price),
+			Strength:  level.Strength,
+			Touches:   level.Touches,
+			Distance:  fmt.Sprintf("%.2f%%", math.Abs(distance)),
+			Direction: getDirectionFromPrice(currentPrice, level.Price),
+			Value:     level.Price,
+		})
+	}
+
+	// Build Patterns with confidence and implications
+	for _, pattern := range patterns {
+		historical := ""
+		if pattern.Samples > 0 {
+			historical = fmt.Sprintf("%.0f%% within %d candles (n=%d)", pattern.Probability*100, pattern.Horizon, pattern.Samples)
+		}
+		data.Patterns = append(data.Patterns, PromptPattern{
+			Name:        pattern.Name,
+			Type:        pattern.Type,
+			Confidence:  fmt.Sprintf("%.0f%%", pattern.Confidence*100),
+			Description: pattern.Description,
+			Implication: getPatternImplication(pattern),
+			Timeframe:   getPatternTimeframeImplication(pattern, tf),
+			Historical:  historical,
+			Value:       pattern.Confidence,
+		})
+	}
+	return data
+}
+
+// RawJSON is the full data set in the shape the DeepThink prompt has
+// always sent as "RAW TECHNICAL DATA".
+func (d PromptData) RawJSON() string {
+	raw := map[string]interface{}{
+		"technical_indicators": d.Indicators,
+		"support_resistance":   d.Levels,
+		"patterns":             d.Patterns,
+		"market_structure":     d.MarketStructure,
+		"time_analysis":        d.TimeAnalysis,
+	}
+	if d.MTF != nil {
+		raw["multi_timeframe"] = d.MTF
+	}
+	jsonData, _ := json.MarshalIndent(raw, "", "  ")
+	return string(jsonData)
+}
+
+// QuickData is the short indicator set sent by the quick prompt.
+func (d PromptData) QuickData() map[string]string {
+	ind := d.Indicators
+	return map[string]string{
+		"coin":        d.Symbol,
+		"timeframe":   d.Timeframe,
+		"price":       ind.CurrentPrice,
+		"ema5":        ind.EMA5,
+		"ema10":       ind.EMA10,
+		"ema30":       ind.EMA30,
+		"bb_upper":    ind.BBUpper,
+		"bb_middle":   ind.BBMiddle,
+		"bb_lower":    ind.BBLower,
+		"rsi":         ind.RSI,
+		"macd":        ind.MACD,
+		"macd_signal": ind.MACDSignal,
+		"macd_hist":   ind.MACDHist,
+	}
+}
+
+// MTFTimeframes lists the --tfs stack, e.g. "15m, 1h, 4h".
+func (d PromptData) MTFTimeframes() string {
+	if d.MTF == nil {
+		return ""
+	}
+	var tfs []string
+	for _, v := range d.MTF.Views {
+		tfs = append(tfs, v.TF)
+	}
+	return strings.Join(tfs, ", ")
+}
+
+// promptTemplate is a parsed prompt with the version from its header.
+type promptTemplate struct {
+	Name    string
+	Version string
+	Source  string // "embedded" or the override file path
+	tmpl    *template.Template
+
+	placesContract bool
+}
+
+// ID identifies the exact wording, e.g. "deepthink-en@v3".
+func (p *promptTemplate) ID() string { return p.Name + "@v" + p.Version }
+
+var promptFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"json": func(v any) (string, error) {
+		b, err := json.MarshalIndent(v, "", "  ")
+		return string(b), err
+	},
+	"emoji": func(typ string) string {
+		switch typ {
+		case "resistance", "bearish":
+			return "🔴"
+		case "continuation":
+			return "🟡"
+		}
+		return "🟢"
+	},
+}
+
+// loadPrompt finds name in dir first and falls back to the embedded set.
+func loadPrompt(name, dir string) (*promptTemplate, error) {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".tmpl")
+	file := name + ".tmpl"
+
+	var src []byte
+	source := "embedded"
+	if dir != "" {
+		path := filepath.Join(dir, file)
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			src, source = data, path
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, fmt.Errorf("baca prompt %s: %w", path, err)
+		}
+	}
+	if src == nil {
+		data, err := embeddedPrompts.ReadFile("prompts/" + file)
+		if err != nil {
+			names, _ := listPrompts(dir)
+			return nil, fmt.Errorf("prompt %q tidak ada, pilih: %s", name, strings.Join(names, ", "))
+		}
+		src = data
+	}
+
+	m := promptVersionRe.FindSubmatch(bytes.TrimSpace(src))
+	if m == nil {
+		return nil, fmt.Errorf("prompt %s (%s) butuh header {{/* version: N */}}", name, source)
+	}
+	tmpl, err := template.New(name).Funcs(promptFuncs).Option("missingkey=error").Parse(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("parse prompt %s (%s): %w", name, source, err)
+	}
+	contract, err := embeddedPrompts.ReadFile("prompts/partials/" + jsonContract + ".tmpl")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmpl.New(jsonContract).Parse(string(contract)); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", jsonContract, err)
+	}
+
+	return &promptTemplate{
+		Name:           name,
+		Version:        string(m[1]),
+		Source:         source,
+		tmpl:           tmpl,
+		placesContract: bytes.Contains(src, []byte(`template "`+jsonContract+`"`)),
+	}, nil
+}
+
+// listPrompts returns the embedded prompt names plus any in dir.
+func listPrompts(dir string) ([]string, error) {
+	seen := map[string]bool{}
+	entries, _ := fs.ReadDir(embeddedPrompts, "prompts")
+	if dir != "" {
+		local, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, local...)
+	}
+
+	var names []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".tmpl")
+		if ok && !e.IsDir() && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (p *promptTemplate) Render(data PromptData) (string, error) {
+	var b strings.Builder
+	if err := p.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("render prompt %s: %w", p.ID(), err)
+	}
+	if data.JSONOutput && !p.placesContract {
+		b.WriteString("\n\n")
+		if err := p.tmpl.ExecuteTemplate(&b, jsonContract, data); err != nil {
+			return "", fmt.Errorf("render %s: %w", jsonContract, err)
+		}
+	}
+	return strings.TrimSpace(b.String()) + "\n", nil
+}
+
+// buildPrompt renders the selected template for one symbol and timeframe
+// and returns the data it was rendered from.
+func buildPrompt(p *promptTemplate, series *techan.TimeSeries, symbol, tf string, srLevels []SupportResistance, patterns []Pattern, mtf *MultiTimeframe, jsonOutput bool) (*PromptData, string, error) {
+	data := newPromptData(series, symbol, tf, srLevels, patterns, mtf)
+	data.JSONOutput = jsonOutput
+	prompt, err := p.Render(data)
+	return &data, prompt, err
+}
+
+// runPrompts lists the available templates with their version and source.
+func runPrompts(args []string) error {
+	flags := flag.NewFlagSet("prompts", flag.ContinueOnError)
+	dir := flags.String("prompt-dir", os.Getenv("PROMPT_DIR"), "folder template override")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	names, err := listPrompts(*dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		p, err := loadPrompt(name, *dir)
+		if err != nil {
+			fmt.Printf("  %-16s ⚠️  %v\n", name, err)
+			continue
+		}
+		fmt.Printf("  %-16s v%-4s %s\n", p.Name, p.Version, p.Source)
+	}
+	return nil
+}
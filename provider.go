@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ================================
+// MARKET DATA PROVIDERS
+// ================================
+
+// CandleProvider is a source of OHLCV candles. Candles returns at most limit
+// candles for symbol (e.g. "SOLUSDT") and a Binance style interval
+// (e.g. "4h"), oldest first. A zero start or end leaves that side of the
+// range open, so a call with both zero returns the latest candles.
+type CandleProvider interface {
+	Name() string
+	Candles(symbol, interval string, start, end time.Time, limit int) ([]Candle, error)
+}
+
+// symbolLister is implemented by venues that can rank their pairs by 24h
+// quote volume, which scan --top uses to build a watchlist.
+type symbolLister interface {
+	TopSymbols(quote string, n int) ([]string, error)
+}
+
+var providerNames = []string{"binance", "binance-futures", "bybit", "bybit-linear", "okx", "okx-swap", "file"}
+
+var marketHTTP = &http.Client{Timeout: 20 * time.Second}
+
+// newProvider builds the provider selected by --provider. baseURL overrides
+// the venue's REST endpoint (handy for a local fixture server) and file is
+// the path used by the "file" provider.
+func newProvider(name, baseURL, file string) (CandleProvider, error) {
+	baseURL = strings.TrimRight(baseURL, "/") // endpoint path ditempel langsung
+	switch strings.ToLower(name) {
+	case "", "binance":
+		return newBinanceProvider("binance", orDefault(baseURL, "https://api.binance.com"), "/api/v3/klines"), nil
+	case "binance-futures":
+		return newBinanceProvider("binance-futures", orDefault(baseURL, "https://fapi.binance.com"), "/fapi/v1/klines"), nil
+	case "bybit":
+		return &bybitProvider{baseURL: orDefault(baseURL, "https://api.bybit.com"), category: "spot", client: marketHTTP}, nil
+	case "bybit-linear":
+		return &bybitProvider{baseURL: orDefault(baseURL, "https://api.bybit.com"), category: "linear", client: marketHTTP}, nil
+	case "okx":
+		return &okxProvider{baseURL: orDefault(baseURL, "https://www.okx.com"), client: marketHTTP}, nil
+	case "okx-swap":
+		return &okxProvider{baseURL: orDefault(baseURL, "https://www.okx.com"), swap: true, client: marketHTTP}, nil
+	case "file":
+		if file == "" {
+			return nil, fmt.Errorf("provider file butuh --file (CSV atau Parquet)")
+		}
+		return &fileProvider{path: file}, nil
+	default:
+		return nil, fmt.Errorf("provider %q tidak dikenal, pilih: %s", name, strings.Join(providerNames, ", "))
+	}
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// getJSON performs a GET and decodes the JSON body into out, retrying rate
+// limit and network failures. Failures come back as *MarketError. The
+// response headers are returned so providers can read rate limit counters.
+func getJSON(client *http.Client, provider, endpoint string, params url.Values, out interface{}) (http.Header, error) {
+	var header http.Header
+	err := withRetry(func() error {
+		resp, err := client.Get(endpoint + "?" + params.Encode())
+		if err != nil {
+			return &MarketError{Kind: ErrNetwork, Provider: provider, Err: err}
+		}
+		defer resp.Body.Close()
+		header = resp.Header
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+		if err != nil {
+			return &MarketError{Kind: ErrNetwork, Provider: provider, Status: resp.StatusCode, Err: err}
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			e := statusError(provider, resp)
+			e.Code, e.Msg = parseVenueError(body)
+			if e.Kind == ErrBadPayload && e.Msg != "" {
+				e.Kind = venueErrorKind(provider, e.Code, e.Msg)
+			}
+			if e.Msg == "" {
+				e.Msg = strings.TrimSpace(string(body[:min(len(body), 256)]))
+			}
+			return e
+		}
+
+		if err := json.Unmarshal(body, out); err != nil {
+			// Kadang error object datang dengan status 200
+			if code, msg := parseVenueError(body); msg != "" {
+				return venueError(provider, code, msg)
+			}
+			return &MarketError{Kind: ErrBadPayload, Provider: provider, Status: resp.StatusCode, Err: err}
+		}
+		if vs, ok := out.(venueStatus); ok {
+			if code, msg := vs.venueStatus(); code != 0 {
+				return venueError(provider, code, msg)
+			}
+		}
+		return nil
+	})
+	return header, err
+}
+
+// venueStatus is implemented by response envelopes that report errors in a
+// code field of a 200 body (Bybit retCode, OKX code).
+type venueStatus interface {
+	venueStatus() (int, string)
+}
+
+// parseOHLCV turns string fields [openTimeMs, open, high, low, close, volume]
+// into a Candle. Bybit, OKX and the CSV source all use this layout.
+func parseOHLCV(fields []string) (Candle, error) {
+	if len(fields) < 6 {
+		return Candle{}, fmt.Errorf("kline butuh 6 kolom, dapat %d", len(fields))
+	}
+	ts, err := parseTimestamp(fields[0])
+	if err != nil {
+		return Candle{}, err
+	}
+
+	var vals [5]decimal.Decimal
+	for i := range vals {
+		d, err := decimal.NewFromString(strings.TrimSpace(fields[i+1]))
+		if err != nil {
+			return Candle{}, fmt.Errorf("kolom %d: %w", i+1, err)
+		}
+		vals[i] = d
+	}
+	return Candle{Time: ts, Open: vals[0], High: vals[1], Low: vals[2], Close: vals[3], Volume: vals[4]}, nil
+}
+
+// parseTimestamp accepts unix milliseconds or RFC3339.
+func parseTimestamp(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	var ms int64
+	if _, err := fmt.Sscanf(s, "%d", &ms); err == nil && !strings.ContainsAny(s, "-:T") {
+		return time.UnixMilli(ms), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timestamp %q tidak valid", s)
+	}
+	return t, nil
+}
+
+// sortCandles orders candles oldest first; Bybit and OKX reply newest first.
+func sortCandles(candles []Candle) {
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].Time.Before(candles[j].Time)
+	})
+}
+
+// filterRange keeps candles inside [start, end] and trims to limit. With an
+// open start the newest candles are kept, otherwise the oldest.
+func filterRange(candles []Candle, start, end time.Time, limit int) []Candle {
+	var out []Candle
+	for _, c := range candles {
+		if !start.IsZero() && c.Time.Before(start) {
+			continue
+		}
+		if !end.IsZero() && c.Time.After(end) {
+			continue
+		}
+		out = append(out, c)
+	}
+	if limit > 0 && len(out) > limit {
+		if start.IsZero() {
+			out = out[len(out)-limit:]
+		} else {
+			out = out[:limit]
+		}
+	}
+	return out
+}
+
+type symbolVolume struct {
+	Symbol      string
+	QuoteVolume float64
+}
+
+// stableBases are skipped by TopSymbols: USDCUSDT and friends top every
+// volume chart but are useless to scan.
+var stableBases = map[string]bool{"USDC": true, "FDUSD": true, "TUSD": true, "BUSD": true, "DAI": true, "USDP": true, "USDE": true, "EUR": true}
+
+// topByVolume keeps pairs quoted in quote, drops stablecoin and leveraged
+// token pairs and returns the n largest by quote volume.
+func topByVolume(pairs []symbolVolume, quote string, n int) []string {
+	var kept []symbolVolume
+	for _, p := range pairs {
+		base, q := splitQuote(p.Symbol)
+		if q != quote || stableBases[base] {
+			continue
+		}
+		if strings.HasSuffix(base, "UP") || strings.HasSuffix(base, "DOWN") || strings.HasSuffix(base, "BULL") || strings.HasSuffix(base, "BEAR") {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].QuoteVolume > kept[j].QuoteVolume })
+
+	var out []string
+	for i := 0; i < len(kept) && i < n; i++ {
+		out = append(out, kept[i].Symbol)
+	}
+	return out
+}
+
+// splitQuote splits "SOLUSDT" into ("SOL", "USDT").
+func splitQuote(symbol string) (string, string) {
+	for _, q := range []string{"USDT", "USDC", "FDUSD", "BUSD", "BTC", "ETH"} {
+		if strings.HasSuffix(symbol, q) && len(symbol) > len(q) {
+			return strings.TrimSuffix(symbol, q), q
+		}
+	}
+	return symbol, ""
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// binanceProvider serves both spot (/api/v3/klines) and USDT-M futures
+// (/fapi/v1/klines); the two endpoints share the same kline layout.
+type binanceProvider struct {
+	name    string
+	baseURL string
+	path    string
+	client  *http.Client
+	weight  *binanceWeight
+}
+
+func newBinanceProvider(name, baseURL, path string) *binanceProvider {
+	maxWeight := 6000 // spot REQUEST_WEIGHT per menit
+	if name == "binance-futures" {
+		maxWeight = 2400
+	}
+	return &binanceProvider{name: name, baseURL: baseURL, path: path, client: marketHTTP, weight: &binanceWeight{max: maxWeight}}
+}
+
+func (b *binanceProvider) Name() string { return b.name }
+
+func (b *binanceProvider) PageLimit() int { return 1000 }
+
+func (b *binanceProvider) PageDelay() time.Duration { return 0 }
+
+// klineWeight is the request weight Binance charges for one klines call.
+func (b *binanceProvider) klineWeight(limit int) int {
+	if b.name != "binance-futures" {
+		return 2
+	}
+	switch {
+	case limit < 100:
+		return 1
+	case limit < 500:
+		return 2
+	case limit <= 1000:
+		return 5
+	default:
+		return 10
+	}
+}
+
+// binanceWeight tracks X-MBX-USED-WEIGHT-1M so long backfills pause before
+// hitting the per-minute limit instead of getting a 429 (or a 418 ban).
+type binanceWeight struct {
+	mu   sync.Mutex
+	used int
+	max  int
+	at   time.Time
+}
+
+func (w *binanceWeight) wait(cost int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Counter Binance reset tiap awal menit
+	if time.Now().Truncate(time.Minute).After(w.at) {
+		w.used = 0
+	}
+	if w.used+cost > w.max*9/10 {
+		time.Sleep(time.Until(time.Now().Truncate(time.Minute).Add(time.Minute)))
+		w.used = 0
+	}
+	w.used += cost
+	w.at = time.Now()
+}
+
+func (w *binanceWeight) update(h http.Header) {
+	if h == nil {
+		return
+	}
+	used, err := strconv.Atoi(h.Get("X-MBX-USED-WEIGHT-1M"))
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	w.used, w.at = used, time.Now()
+	w.mu.Unlock()
+}
+
+func (b *binanceProvider) Candles(symbol, interval string, start, end time.Time, limit int) ([]Candle, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("interval", interval)
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	if !start.IsZero() {
+		params.Set("startTime", strconv.FormatInt(start.UnixMilli(), 10))
+	}
+	if !end.IsZero() {
+		params.Set("endTime", strconv.FormatInt(end.UnixMilli(), 10))
+	}
+
+	b.weight.wait(b.klineWeight(limit))
+	var raw [][]interface{}
+	header, err := getJSON(b.client, b.name, b.baseURL+b.path, params, &raw)
+	b.weight.update(header)
+	if err != nil {
+		return nil, err
+	}
+
+	candles := make([]Candle, 0, len(raw))
+	for i, k := range raw {
+		c, err := decodeBinanceKline(k)
+		if err != nil {
+			return nil, &MarketError{Kind: ErrBadPayload, Provider: b.name, Err: fmt.Errorf("kline #%d: %w", i, err)}
+		}
+		candles = append(candles, c)
+	}
+	return candles, nil
+}
+
+func (b *binanceProvider) TopSymbols(quote string, n int) ([]string, error) {
+	path := "/api/v3/ticker/24hr"
+	if b.name == "binance-futures" {
+		path = "/fapi/v1/ticker/24hr"
+	}
+
+	var tickers []struct {
+		Symbol      string `json:"symbol"`
+		QuoteVolume string `json:"quoteVolume"`
+	}
+	b.weight.wait(80)
+	header, err := getJSON(b.client, b.name, b.baseURL+path, url.Values{}, &tickers)
+	b.weight.update(header)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]symbolVolume, 0, len(tickers))
+	for _, t := range tickers {
+		vol, _ := strconv.ParseFloat(t.QuoteVolume, 64)
+		pairs = append(pairs, symbolVolume{Symbol: t.Symbol, QuoteVolume: vol})
+	}
+	return topByVolume(pairs, quote, n), nil
+}
+
+// decodeBinanceKline checks one kline row:
+// [openTime, "open", "high", "low", "close", "volume", closeTime, ...]
+func decodeBinanceKline(k []interface{}) (Candle, error) {
+	if len(k) < 7 {
+		return Candle{}, fmt.Errorf("butuh minimal 7 kolom, dapat %d", len(k))
+	}
+	openTime, ok := k[0].(float64)
+	if !ok {
+		return Candle{}, fmt.Errorf("open time bukan angka: %v", k[0])
+	}
+	closeTime, ok := k[6].(float64)
+	if !ok {
+		return Candle{}, fmt.Errorf("close time bukan angka: %v", k[6])
+	}
+
+	var vals [5]decimal.Decimal
+	for i := range vals {
+		str, ok := k[i+1].(string)
+		if !ok {
+			return Candle{}, fmt.Errorf("kolom %d bukan string: %v", i+1, k[i+1])
+		}
+		d, err := decimal.NewFromString(str)
+		if err != nil {
+			return Candle{}, fmt.Errorf("kolom %d: %w", i+1, err)
+		}
+		vals[i] = d
+	}
+	return Candle{
+		Time:      time.UnixMilli(int64(openTime)),
+		CloseTime: time.UnixMilli(int64(closeTime)),
+		Open:      vals[0],
+		High:      vals[1],
+		Low:       vals[2],
+		Close:     vals[3],
+		Volume:    vals[4],
+	}, nil
+}
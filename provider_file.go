@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/shopspring/decimal"
+)
+
+// fileProvider reads candles from a local CSV or Parquet file. The path may
+// contain {symbol} and {interval} placeholders, e.g. "data/{symbol}_{interval}.csv".
+//
+// CSV columns: open_time (unix ms or RFC3339), open, high, low, close, volume.
+// A header row is optional. Parquet files use the parquetKline schema.
+type fileProvider struct {
+	path string
+}
+
+type parquetKline struct {
+	OpenTime int64   `parquet:"open_time"`
+	Open     float64 `parquet:"open"`
+	High     float64 `parquet:"high"`
+	Low      float64 `parquet:"low"`
+	Close    float64 `parquet:"close"`
+	Volume   float64 `parquet:"volume"`
+}
+
+func (f *fileProvider) Name() string { return "file" }
+
+func (f *fileProvider) Candles(symbol, interval string, start, end time.Time, limit int) ([]Candle, error) {
+	iv, err := ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	path := strings.NewReplacer("{symbol}", symbol, "{interval}", interval).Replace(f.path)
+
+	var candles []Candle
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".parquet":
+		candles, err = readParquetCandles(path)
+	default:
+		candles, err = readCSVCandles(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sortCandles(candles)
+	fillCloseTimes(candles, iv)
+	return filterRange(candles, start, end, limit), nil
+}
+
+func readCSVCandles(path string) ([]Candle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("baca CSV %s: %w", path, err)
+	}
+
+	var candles []Candle
+	for i, row := range rows {
+		c, err := parseOHLCV(row)
+		if err != nil {
+			if i == 0 {
+				continue // header
+			}
+			return nil, fmt.Errorf("%s baris %d: %w", path, i+1, err)
+		}
+		candles = append(candles, c)
+	}
+	return candles, nil
+}
+
+// writeCSVCandles saves candles in the layout readCSVCandles expects, so a
+// backfill can be replayed later with --provider file.
+func writeCSVCandles(path string, candles []Candle) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"open_time", "open", "high", "low", "close", "volume"})
+	for _, c := range candles {
+		w.Write([]string{
+			strconv.FormatInt(c.Time.UnixMilli(), 10),
+			c.Open.String(), c.High.String(), c.Low.String(), c.Close.String(), c.Volume.String(),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func readParquetCandles(path string) ([]Candle, error) {
+	rows, err := parquet.ReadFile[parquetKline](path)
+	if err != nil {
+		return nil, fmt.Errorf("baca Parquet %s: %w", path, err)
+	}
+
+	candles := make([]Candle, 0, len(rows))
+	for _, r := range rows {
+		candles = append(candles, Candle{
+			Time:   time.UnixMilli(r.OpenTime),
+			Open:   decimal.NewFromFloat(r.Open),
+			High:   decimal.NewFromFloat(r.High),
+			Low:    decimal.NewFromFloat(r.Low),
+			Close:  decimal.NewFromFloat(r.Close),
+			Volume: decimal.NewFromFloat(r.Volume),
+		})
+	}
+	return candles, nil
+}
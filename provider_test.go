@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Dua candle 1h yang sama, dalam format masing-masing venue. Bybit dan OKX
+// mengirim yang terbaru dulu.
+var providerPayloads = []struct {
+	provider, path string
+	query          url.Values
+	body           string
+}{
+	{
+		provider: "binance", path: "/api/v3/klines",
+		query: url.Values{"symbol": {"SOLUSDT"}, "interval": {"1h"}, "limit": {"2"}},
+		body: `[[1709251200000,"140.00","141.50","139.20","141.00","1200.5",1709254799999,"168000",310,"600","84000","0"],
+			[1709254800000,"141.00","142.00","140.50","141.80","900",1709258399999,"127000",250,"450","63500","0"]]`,
+	},
+	{
+		provider: "binance-futures", path: "/fapi/v1/klines",
+		query: url.Values{"symbol": {"SOLUSDT"}, "interval": {"1h"}, "limit": {"2"}},
+		body: `[[1709251200000,"140.00","141.50","139.20","141.00","1200.5",1709254799999,"168000",310,"600","84000","0"],
+			[1709254800000,"141.00","142.00","140.50","141.80","900",1709258399999,"127000",250,"450","63500","0"]]`,
+	},
+	{
+		provider: "bybit", path: "/v5/market/kline",
+		query: url.Values{"category": {"spot"}, "symbol": {"SOLUSDT"}, "interval": {"60"}, "limit": {"2"}},
+		body: `{"retCode":0,"retMsg":"OK","result":{"symbol":"SOLUSDT","category":"spot","list":[
+			["1709254800000","141.00","142.00","140.50","141.80","900","127000"],
+			["1709251200000","140.00","141.50","139.20","141.00","1200.5","168000"]]},"time":1709258000000}`,
+	},
+	{
+		provider: "bybit-linear", path: "/v5/market/kline",
+		query: url.Values{"category": {"linear"}, "symbol": {"SOLUSDT"}, "interval": {"60"}, "limit": {"2"}},
+		body: `{"retCode":0,"retMsg":"OK","result":{"symbol":"SOLUSDT","category":"linear","list":[
+			["1709254800000","141.00","142.00","140.50","141.80","900","127000"],
+			["1709251200000","140.00","141.50","139.20","141.00","1200.5","168000"]]},"time":1709258000000}`,
+	},
+	{
+		provider: "okx", path: "/api/v5/market/candles",
+		query: url.Values{"instId": {"SOL-USDT"}, "bar": {"1H"}, "limit": {"2"}},
+		body: `{"code":"0","msg":"","data":[
+			["1709254800000","141.00","142.00","140.50","141.80","900","127000","127000","1"],
+			["1709251200000","140.00","141.50","139.20","141.00","1200.5","168000","168000","1"]]}`,
+	},
+	{
+		provider: "okx-swap", path: "/api/v5/market/candles",
+		query: url.Values{"instId": {"SOL-USDT-SWAP"}, "bar": {"1H"}, "limit": {"2"}},
+		body: `{"code":"0","msg":"","data":[
+			["1709254800000","141.00","142.00","140.50","141.80","900","127000","127000","1"],
+			["1709251200000","140.00","141.50","139.20","141.00","1200.5","168000","168000","1"]]}`,
+	},
+}
+
+func TestProviderDecode(t *testing.T) {
+	d := decimal.RequireFromString
+	want := []Candle{
+		{Time: time.UnixMilli(1709251200000), CloseTime: time.UnixMilli(1709254799999), Open: d("140"), High: d("141.5"), Low: d("139.2"), Close: d("141"), Volume: d("1200.5")},
+		{Time: time.UnixMilli(1709254800000), CloseTime: time.UnixMilli(1709258399999), Open: d("141"), High: d("142"), Low: d("140.5"), Close: d("141.8"), Volume: d("900")},
+	}
+
+	for _, tt := range providerPayloads {
+		t.Run(tt.provider, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					http.NotFound(w, r)
+					return
+				}
+				for k := range tt.query {
+					if got := r.URL.Query().Get(k); got != tt.query.Get(k) {
+						t.Errorf("param %s = %q, mau %q", k, got, tt.query.Get(k))
+					}
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			// Slash di akhir --provider-url tidak boleh jadi "//" di path
+			provider, err := newProvider(tt.provider, srv.URL+"/", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			candles, err := provider.Candles("SOLUSDT", "1h", time.Time{}, time.Time{}, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(candles) != len(want) {
+				t.Fatalf("dapat %d candle, mau %d", len(candles), len(want))
+			}
+			for i, c := range candles {
+				w := want[i]
+				if !c.Time.Equal(w.Time) || !c.CloseTime.Equal(w.CloseTime) ||
+					!c.Open.Equal(w.Open) || !c.High.Equal(w.High) || !c.Low.Equal(w.Low) ||
+					!c.Close.Equal(w.Close) || !c.Volume.Equal(w.Volume) {
+					t.Errorf("candle %d:\n got %+v\nwant %+v", i, c, w)
+				}
+			}
+		})
+	}
+}
+
+func TestProviderVenueErrors(t *testing.T) {
+	tests := []struct {
+		provider string
+		status   int
+		body     string
+		kind     error
+	}{
+		{"binance", http.StatusBadRequest, `{"code":-1121,"msg":"Invalid symbol."}`, ErrInvalidSymbol},
+		{"bybit", http.StatusOK, `{"retCode":10001,"retMsg":"params error: symbol invalid","result":{}}`, ErrInvalidSymbol},
+		{"okx", http.StatusOK, `{"code":"51001","msg":"Instrument ID does not exist","data":[]}`, ErrInvalidSymbol},
+		{"binance", http.StatusOK, `[[1709251200000,"140.00"]]`, ErrBadPayload},
+		{"okx", http.StatusOK, `{"code":"0","msg":"","data":[["1709251200000","abc","1","1","1","1"]]}`, ErrBadPayload},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider+"/"+tt.kind.Error(), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			provider, err := newProvider(tt.provider, srv.URL, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = provider.Candles("XXXUSDT", "1h", time.Time{}, time.Time{}, 2)
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("err = %v, mau %v", err, tt.kind)
+			}
+			var me *MarketError
+			if !errors.As(err, &me) || me.Provider != tt.provider {
+				t.Errorf("bukan MarketError dari %s: %#v", tt.provider, err)
+			}
+		})
+	}
+}
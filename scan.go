@@ -0,0 +1,501 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/sdcoffey/techan"
+)
+
+// ================================
+// MARKET SCANNER
+// ================================
+
+const defaultScanRank = "squeeze,macd-bull-cross,near-support"
+
+type scanOptions struct {
+	cliOptions
+	Symbols  []string
+	Top      int
+	Quote    string
+	Workers  int
+	Rank     []rankTerm
+	MinScore float64
+	Near     float64
+	AITop    int
+}
+
+// rankTerm is one "name[:weight]" entry of --rank.
+type rankTerm struct {
+	Name   string
+	Weight float64
+}
+
+// scanResult is one row of the scan table. Candles and series are kept for
+// the optional --ai pass over the top rows.
+type scanResult struct {
+	Symbol     string              `json:"symbol"`
+	Summary    IndicatorSummary    `json:"indicators"`
+	Levels     []SupportResistance `json:"support_resistance"`
+	Patterns   []Pattern           `json:"patterns"`
+	Support    *SupportResistance  `json:"nearest_support,omitempty"`
+	Resistance *SupportResistance  `json:"nearest_resistance,omitempty"`
+	Score      float64             `json:"score"`
+	Matched    []string            `json:"matched,omitempty"`
+	Error      string              `json:"error,omitempty"`
+
+	candles []Candle
+	series  *techan.TimeSeries
+}
+
+// supportDist and resistanceDist are the distance to the nearest level in
+// percent of price, or -1 when there is none.
+func (r *scanResult) supportDist() float64 {
+	if r.Support == nil || r.Summary.Price == 0 {
+		return -1
+	}
+	return (r.Summary.Price - r.Support.Price) / r.Summary.Price * 100
+}
+
+func (r *scanResult) resistanceDist() float64 {
+	if r.Resistance == nil || r.Summary.Price == 0 {
+		return -1
+	}
+	return (r.Resistance.Price - r.Summary.Price) / r.Summary.Price * 100
+}
+
+// scanCriterion scores a result between 0 (no match) and 1 (full match).
+type scanCriterion struct {
+	desc  string
+	score func(r *scanResult, opts scanOptions) float64
+}
+
+var scanCriteria = map[string]scanCriterion{
+	"squeeze": {"Bollinger squeeze (high = 1, medium = 0.5)", func(r *scanResult, _ scanOptions) float64 {
+		switch r.Summary.BBSqueeze {
+		case "High Squeeze":
+			return 1
+		case "Medium Squeeze":
+			return 0.5
+		}
+		return 0
+	}},
+	"macd-bull-cross": {"MACD bullish crossover di candle terakhir", func(r *scanResult, _ scanOptions) float64 {
+		return boolScore(r.Summary.MACDTrend == "Bullish Crossover")
+	}},
+	"macd-bear-cross": {"MACD bearish crossover di candle terakhir", func(r *scanResult, _ scanOptions) float64 {
+		return boolScore(r.Summary.MACDTrend == "Bearish Crossover")
+	}},
+	"macd-bullish": {"MACD di atas signal (crossover dihitung penuh)", func(r *scanResult, _ scanOptions) float64 {
+		return boolScore(strings.HasPrefix(r.Summary.MACDTrend, "Bullish"))
+	}},
+	"macd-bearish": {"MACD di bawah signal (crossover dihitung penuh)", func(r *scanResult, _ scanOptions) float64 {
+		return boolScore(strings.HasPrefix(r.Summary.MACDTrend, "Bearish"))
+	}},
+	"near-support": {"harga dalam --near % di atas support terdekat", func(r *scanResult, opts scanOptions) float64 {
+		return nearScore(r.supportDist(), opts.Near)
+	}},
+	"near-resistance": {"harga dalam --near % di bawah resistance terdekat", func(r *scanResult, opts scanOptions) float64 {
+		return nearScore(r.resistanceDist(), opts.Near)
+	}},
+	"trend-up": {"trend strength bullish (strong = 1, biasa = 0.6)", func(r *scanResult, _ scanOptions) float64 {
+		switch r.Summary.TrendStrength {
+		case "Strong Bullish":
+			return 1
+		case "Bullish":
+			return 0.6
+		}
+		return 0
+	}},
+	"trend-down": {"trend strength bearish (strong = 1, biasa = 0.6)", func(r *scanResult, _ scanOptions) float64 {
+		switch r.Summary.TrendStrength {
+		case "Strong Bearish":
+			return 1
+		case "Bearish":
+			return 0.6
+		}
+		return 0
+	}},
+	"oversold": {"RSI oversold", func(r *scanResult, _ scanOptions) float64 {
+		return boolScore(r.Summary.RSITrend == "Oversold")
+	}},
+	"overbought": {"RSI overbought", func(r *scanResult, _ scanOptions) float64 {
+		return boolScore(r.Summary.RSITrend == "Overbought")
+	}},
+	"rsi-bull": {"RSI bullish momentum", func(r *scanResult, _ scanOptions) float64 {
+		return boolScore(r.Summary.RSITrend == "Bullish Momentum")
+	}},
+	"bullish-pattern": {"confidence pattern bullish tertinggi", func(r *scanResult, _ scanOptions) float64 {
+		return patternScore(r.Patterns, "bullish")
+	}},
+	"bearish-pattern": {"confidence pattern bearish tertinggi", func(r *scanResult, _ scanOptions) float64 {
+		return patternScore(r.Patterns, "bearish")
+	}},
+	"volume-spike": {"volume candle terakhir spike", func(r *scanResult, _ scanOptions) float64 {
+		return boolScore(r.Summary.VolumeTrend == "High Volume Spike")
+	}},
+}
+
+func boolScore(ok bool) float64 {
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+// nearScore is 1 right at the level and falls to 0 at near percent away.
+func nearScore(dist, near float64) float64 {
+	if dist < 0 || dist > near {
+		return 0
+	}
+	return 1 - dist/near
+}
+
+func patternScore(patterns []Pattern, typ string) float64 {
+	best := 0.0
+	for _, p := range patterns {
+		if p.Type == typ {
+			best = math.Max(best, p.Confidence)
+		}
+	}
+	return best
+}
+
+func criteriaHelp() string {
+	names := make([]string, 0, len(scanCriteria))
+	for name := range scanCriteria {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("Kriteria --rank (pisah koma atau +, bobot opsional name:2):\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-16s %s\n", name, scanCriteria[name].desc)
+	}
+	return b.String()
+}
+
+// parseRank reads "squeeze+macd-bull-cross:2,near-support".
+func parseRank(s string) ([]rankTerm, error) {
+	var terms []rankTerm
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '+' }) {
+		name, weight, hasWeight := strings.Cut(strings.TrimSpace(part), ":")
+		term := rankTerm{Name: strings.ToLower(name), Weight: 1}
+		if _, ok := scanCriteria[term.Name]; !ok {
+			return nil, fmt.Errorf("kriteria %q tidak dikenal\n\n%s", name, criteriaHelp())
+		}
+		if hasWeight {
+			w, err := strconv.ParseFloat(weight, 64)
+			if err != nil || w <= 0 {
+				return nil, fmt.Errorf("bobot %q untuk %s tidak valid", weight, term.Name)
+			}
+			term.Weight = w
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 0 {
+		return nil, errors.New("--rank kosong")
+	}
+	return terms, nil
+}
+
+func parseScanFlags(args []string) (scanOptions, error) {
+	var opts scanOptions
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	symbols := fs.String("symbols", "", "watchlist dipisah koma, contoh: SOL,BTC,ETH")
+	fs.IntVar(&opts.Top, "top", 0, "scan N pair dengan volume 24h terbesar dari provider (kalau --symbols kosong)")
+	fs.StringVar(&opts.Quote, "quote", "USDT", "quote currency untuk --top")
+	fs.IntVar(&opts.Workers, "workers", 4, "jumlah request candle paralel")
+	rank := fs.String("rank", defaultScanRank, "kriteria ranking, mis. squeeze+macd-bull-cross+near-support")
+	fs.Float64Var(&opts.MinScore, "min-score", 0, "sembunyikan symbol dengan score di bawah ini")
+	fs.Float64Var(&opts.Near, "near", 2, "jarak maksimal (%) ke level untuk near-support/near-resistance")
+	fs.StringVar(&opts.Out, "out", "", "tulis hasil scan JSON ke file ini")
+	finish := dataFlags(fs, &opts.cliOptions)
+	fs.StringVar(&opts.AI, "ai", "", "analisa AI ("+strings.Join(llmNames(), " / ")+") untuk symbol teratas; kosong = tanpa AI")
+	aiFlags(fs, &opts.cliOptions)
+	fs.IntVar(&opts.AITop, "ai-top", 3, "jumlah symbol teratas yang dianalisa AI kalau --ai diisi")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of scan:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\n%s", criteriaHelp())
+	}
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("argumen tidak dikenal: %s", strings.Join(fs.Args(), " "))
+	}
+	if err := finish(); err != nil {
+		return opts, err
+	}
+
+	var err error
+	if opts.Rank, err = parseRank(*rank); err != nil {
+		return opts, err
+	}
+	for _, s := range strings.Split(*symbols, ",") {
+		if strings.TrimSpace(s) != "" {
+			opts.Symbols = append(opts.Symbols, normalizeSymbol(s))
+		}
+	}
+	opts.Quote = strings.ToUpper(strings.TrimSpace(opts.Quote))
+	if len(opts.Symbols) == 0 && opts.Top <= 0 {
+		return opts, errors.New("scan butuh --symbols atau --top")
+	}
+	if opts.Workers <= 0 {
+		return opts, errors.New("--workers harus lebih dari 0")
+	}
+	if opts.Near <= 0 {
+		return opts, errors.New("--near harus lebih dari 0")
+	}
+	if opts.AI != "" {
+		if err := validateAI(&opts.cliOptions); err != nil {
+			return opts, err
+		}
+		if err := checkAIKeys(opts.cliOptions); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+func runScan(args []string) error {
+	opts, err := parseScanFlags(args)
+	if err != nil {
+		return err
+	}
+	provider, err := openProvider(opts.cliOptions)
+	if err != nil {
+		return err
+	}
+
+	symbols := opts.Symbols
+	if len(symbols) == 0 {
+		lister, ok := provider.(symbolLister)
+		if !ok {
+			return fmt.Errorf("provider %s tidak bisa --top, pakai --symbols", provider.Name())
+		}
+		if symbols, err = lister.TopSymbols(opts.Quote, opts.Top); err != nil {
+			return fmt.Errorf("ambil top symbol: %w", err)
+		}
+		if len(symbols) == 0 {
+			return fmt.Errorf("tidak ada pair %s di %s", opts.Quote, provider.Name())
+		}
+	}
+
+	fmt.Printf("\n🔎 Scan %d symbol %s dari %s (%d worker)...\n\n", len(symbols), opts.TF, provider.Name(), opts.Workers)
+	results := scanSymbols(provider, symbols, opts)
+	rankResults(results, opts)
+	printScanTable(results, opts)
+
+	if opts.Out != "" {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode hasil scan: %w", err)
+		}
+		if err := os.WriteFile(opts.Out, data, 0o644); err != nil {
+			return fmt.Errorf("tulis hasil scan %s: %w", opts.Out, err)
+		}
+		fmt.Printf("✅ Hasil scan disimpan → %s\n", opts.Out)
+	}
+
+	if opts.AI != "" {
+		analyzeTopResults(results, opts)
+	}
+	return nil
+}
+
+// scanSymbols loads and evaluates every symbol with at most opts.Workers
+// requests in flight. Results keep the input order; failures carry Error.
+func scanSymbols(provider CandleProvider, symbols []string, opts scanOptions) []*scanResult {
+	results := make([]*scanResult, len(symbols))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(opts.Workers, len(symbols)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = scanSymbol(provider, symbols[i], opts)
+			}
+		}()
+	}
+	for i := range symbols {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func scanSymbol(provider CandleProvider, symbol string, opts scanOptions) *scanResult {
+	r := &scanResult{Symbol: symbol}
+
+	var err error
+	if opts.From.IsZero() && opts.To.IsZero() {
+		r.candles, r.series, err = fetchData(provider, symbol, opts.TF, opts.Candles)
+	} else {
+		r.candles, err = backfillCandles(provider, symbol, opts.TF, opts.From, opts.To)
+		if !opts.To.IsZero() {
+			r.candles = closedOnly(r.candles, opts.To)
+		}
+		r.series = buildSeries(r.candles, mustInterval(opts.TF))
+	}
+	switch {
+	case err != nil:
+		r.Error = err.Error()
+		return r
+	case len(r.candles) < 30:
+		r.Error = fmt.Sprintf("cuma %d candle", len(r.candles))
+		return r
+	}
+
+	detectors := opts.params.config(symbol, opts.TF)
+	r.evaluate(opts.TF, detectors)
+	opts.calibration.apply(r.Patterns, opts.TF, detectors.Patterns)
+	return r
+}
+
+// evaluate fills the indicator, level and pattern fields from r.candles and
+// r.series. Backtest calls it on every bar with only the candles up to it.
+func (r *scanResult) evaluate(tf string, cfg detectorConfig) {
+	r.Summary = summarizeIndicators(r.series, r.Symbol, tf)
+	r.detect(cfg)
+}
+
+// detect fills only the level and pattern fields; r.Summary must already
+// be set. The optimizer reuses one summary for every parameter set.
+func (r *scanResult) detect(cfg detectorConfig) {
+	r.Levels = detectSupportResistance(r.candles, cfg.SR)
+	r.Patterns = detectPatterns(r.candles, cfg.Patterns)
+	r.Support, r.Resistance = nearestLevels(r.Levels, r.Summary.Price)
+}
+
+// score sums the weighted criteria in terms and lists the ones that matched.
+func (r *scanResult) score(terms []rankTerm, opts scanOptions) (float64, []string) {
+	total := 0.0
+	var matched []string
+	for _, term := range terms {
+		s := scanCriteria[term.Name].score(r, opts)
+		if s > 0 {
+			total += s * term.Weight
+			matched = append(matched, term.Name)
+		}
+	}
+	return total, matched
+}
+
+// rankResults scores every result against --rank and sorts best first.
+// Failed symbols go to the bottom.
+func rankResults(results []*scanResult, opts scanOptions) {
+	for _, r := range results {
+		if r.Error != "" {
+			continue
+		}
+		r.Score, r.Matched = r.score(opts.Rank, opts)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if (a.Error == "") != (b.Error == "") {
+			return a.Error == ""
+		}
+		return a.Score > b.Score
+	})
+}
+
+func printScanTable(results []*scanResult, opts scanOptions) {
+	var terms []string
+	for _, t := range opts.Rank {
+		terms = append(terms, t.Name)
+	}
+	fmt.Printf("🏆 RANKING: %s\n\n", strings.Join(terms, " + "))
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tSYMBOL\tPRICE\tTREND\tRSI\tMACD\tSQUEEZE\tSUPPORT\tRESIST\tPATTERNS\tSCORE")
+	rank, hidden := 0, 0
+	var failed []*scanResult
+	for _, r := range results {
+		if r.Error != "" {
+			failed = append(failed, r)
+			continue
+		}
+		if r.Score < opts.MinScore {
+			hidden++
+			continue
+		}
+		rank++
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%.1f %s\t%s\t%s\t%s\t%s\t%s\t%.2f\n",
+			rank, r.Symbol, r.Summary.CurrentPrice, r.Summary.TrendStrength,
+			r.Summary.RSIValue, r.Summary.RSITrend, r.Summary.MACDTrend, r.Summary.BBSqueeze,
+			levelCell(r.Support, r.supportDist()), levelCell(r.Resistance, r.resistanceDist()),
+			patternCell(r.Patterns), r.Score)
+	}
+	tw.Flush()
+
+	if hidden > 0 {
+		fmt.Printf("\n(%d symbol di bawah --min-score %.2f disembunyikan)\n", hidden, opts.MinScore)
+	}
+	for _, r := range failed {
+		fmt.Fprintf(os.Stderr, "⚠️  %s: %s\n", r.Symbol, r.Error)
+	}
+	fmt.Println()
+}
+
+func levelCell(level *SupportResistance, dist float64) string {
+	if level == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.4g (%.1f%%)", level.Price, dist)
+}
+
+func patternCell(patterns []Pattern) string {
+	if len(patterns) == 0 {
+		return "-"
+	}
+	var names []string
+	for _, p := range patterns {
+		names = append(names, fmt.Sprintf("%s %.0f%%", p.Name, p.Confidence*100))
+	}
+	return strings.Join(names, ", ")
+}
+
+// analyzeTopResults runs the selected AI over the best --ai-top rows only,
+// so a wide scan costs a handful of LLM calls at most.
+func analyzeTopResults(results []*scanResult, opts scanOptions) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	n := 0
+	for _, r := range results {
+		if ctx.Err() != nil {
+			fmt.Println("🛑 Analisa AI dibatalkan.")
+			return
+		}
+		if n >= opts.AITop || r.Error != "" || r.Score < opts.MinScore || r.Score == 0 {
+			continue
+		}
+		n++
+		fmt.Printf("\n🤖 Analisa %s #%d: %s %s (score %.2f)\n\n", strings.ToUpper(opts.AI), n, r.Symbol, opts.TF, r.Score)
+		analysis := analyzeAI(ctx, opts.cliOptions, r.candles, r.series, r.Symbol, opts.TF, r.Levels, r.Patterns, nil)
+		if ctx.Err() != nil {
+			continue
+		}
+		printBeautifulAnalysis(analysis, r.Symbol, opts.TF, "", r.Levels, r.Patterns, nil)
+	}
+	if n == 0 {
+		fmt.Println("🤖 Tidak ada symbol yang match kriteria, AI tidak dipanggil.")
+	}
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ================================
+// LOCAL CANDLE STORE
+// ================================
+
+// candleStore keeps closed candles on disk as one append-only CSV per
+// (provider, symbol, interval), in the same layout as writeCSVCandles.
+type candleStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func defaultStoreDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ai-trade", "candles")
+}
+
+func (s *candleStore) path(provider, symbol, interval string) string {
+	// 1m dan 1M beda interval, jadi nama file pakai "1mo" untuk bulanan
+	if interval == "1M" {
+		interval = "1mo"
+	}
+	return filepath.Join(s.dir, provider, fmt.Sprintf("%s_%s.csv", strings.ToUpper(symbol), interval))
+}
+
+// Load returns the stored candles oldest first with duplicates removed.
+// A missing file is not an error.
+func (s *candleStore) Load(provider, symbol, interval string) ([]Candle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	candles, err := readCSVCandles(s.path(provider, symbol, interval))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mergeCandles(candles, nil), nil
+}
+
+// Append adds candles to the end of the file without rewriting it.
+func (s *candleStore) Append(provider, symbol, interval string, candles []Candle) error {
+	if len(candles) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(provider, symbol, interval)
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return s.writeLocked(path, candles)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var b strings.Builder
+	for _, c := range candles {
+		fmt.Fprintf(&b, "%s,%s,%s,%s,%s,%s\n", strconv.FormatInt(c.Time.UnixMilli(), 10),
+			c.Open.String(), c.High.String(), c.Low.String(), c.Close.String(), c.Volume.String())
+	}
+	_, err = f.WriteString(b.String())
+	return err
+}
+
+// Rewrite replaces the whole file, used after head backfill or gap repair
+// so the file stays sorted.
+func (s *candleStore) Rewrite(provider, symbol, interval string, candles []Candle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeLocked(s.path(provider, symbol, interval), candles)
+}
+
+func (s *candleStore) writeLocked(path string, candles []Candle) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := writeCSVCandles(tmp, candles); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// timeRange is an inclusive range of open times.
+type timeRange struct {
+	From, To time.Time
+}
+
+func (r timeRange) covers(from, to time.Time) bool {
+	return !from.Before(r.From) && !to.After(r.To)
+}
+
+// emptyPath lists the ranges already fetched from the venue, next to the
+// candle file. Whatever is missing from the store inside them does not
+// exist (exchange downtime, history before the listing), so those gaps are
+// not fetched again on every run.
+func (s *candleStore) emptyPath(provider, symbol, interval string) string {
+	return strings.TrimSuffix(s.path(provider, symbol, interval), ".csv") + ".empty"
+}
+
+// Empty returns the ranges recorded by MarkEmpty. A missing file is not an
+// error.
+func (s *candleStore) Empty(provider, symbol, interval string) ([]timeRange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.emptyLocked(s.emptyPath(provider, symbol, interval))
+}
+
+func (s *candleStore) emptyLocked(path string) ([]timeRange, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ranges []timeRange
+	for i, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		from, to, _ := strings.Cut(line, ",")
+		f, err1 := strconv.ParseInt(from, 10, 64)
+		t, err2 := strconv.ParseInt(to, 10, 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("%s baris %d: %q", path, i+1, line)
+		}
+		ranges = append(ranges, timeRange{From: time.UnixMilli(f), To: time.UnixMilli(t)})
+	}
+	return ranges, nil
+}
+
+// MarkEmpty records ranges that were fetched completely, merged with what
+// is already recorded.
+func (s *candleStore) MarkEmpty(provider, symbol, interval string, ranges []timeRange) error {
+	if len(ranges) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.emptyPath(provider, symbol, interval)
+	known, err := s.emptyLocked(path)
+	if err != nil {
+		known = nil // file rusak: tulis ulang dari yang baru saja dicek
+	}
+	var b strings.Builder
+	for _, r := range mergeRanges(append(known, ranges...)) {
+		fmt.Fprintf(&b, "%d,%d\n", r.From.UnixMilli(), r.To.UnixMilli())
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// mergeRanges sorts ranges and joins the ones that overlap or touch.
+func mergeRanges(ranges []timeRange) []timeRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].From.Before(ranges[j].From) })
+	var out []timeRange
+	for _, r := range ranges {
+		if n := len(out); n > 0 && !r.From.After(out[n-1].To.Add(time.Millisecond)) {
+			out[n-1].To = laterOf(out[n-1].To, r.To)
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func coveredBy(ranges []timeRange, from, to time.Time) bool {
+	for _, r := range ranges {
+		if r.covers(from, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeCandles combines two candle sets by open time; on conflicts the
+// candle from fresh wins. The result is sorted oldest first.
+func mergeCandles(stored, fresh []Candle) []Candle {
+	byTime := make(map[int64]Candle, len(stored)+len(fresh))
+	for _, c := range stored {
+		byTime[c.Time.UnixMilli()] = c
+	}
+	for _, c := range fresh {
+		byTime[c.Time.UnixMilli()] = c
+	}
+	out := make([]Candle, 0, len(byTime))
+	for _, c := range byTime {
+		out = append(out, c)
+	}
+	sortCandles(out)
+	return out
+}
+
+type candleGap struct {
+	From, To time.Time // open time of the candles on either side of the hole
+}
+
+// findGaps reports holes between consecutive candles. Candles are expected
+// to be sorted; a gap is anything longer than one interval.
+func findGaps(candles []Candle, iv Interval) []candleGap {
+	var gaps []candleGap
+	for i := 1; i < len(candles); i++ {
+		if candles[i].Time.After(iv.Next(candles[i-1].Time)) {
+			gaps = append(gaps, candleGap{From: candles[i-1].Time, To: candles[i].Time})
+		}
+	}
+	return gaps
+}
+
+// cachedProvider serves candles from a candleStore and only asks the
+// wrapped provider for what is missing: older history before the first
+// stored candle, the tail since the last one, and any gaps in between.
+// Head and gap ranges are only fetched once, see emptyPath. Only closed
+// candles are persisted. With offline set the network is never
+// touched, and network failures fall back to whatever is stored.
+type cachedProvider struct {
+	inner   CandleProvider
+	store   *candleStore
+	offline bool
+}
+
+func (p *cachedProvider) Name() string { return p.inner.Name() }
+
+// TopSymbols is not cached; volume rankings go stale within minutes.
+func (p *cachedProvider) TopSymbols(quote string, n int) ([]string, error) {
+	lister, ok := p.inner.(symbolLister)
+	if !ok {
+		return nil, fmt.Errorf("provider %s tidak bisa list top symbol", p.inner.Name())
+	}
+	if p.offline {
+		return nil, errors.New("offline: --top butuh koneksi, pakai --symbols")
+	}
+	return lister.TopSymbols(quote, n)
+}
+
+func (p *cachedProvider) Candles(symbol, interval string, start, end time.Time, limit int) ([]Candle, error) {
+	iv, err := ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	latest := start.IsZero()
+	if latest {
+		n := limit
+		if n <= 0 {
+			n = 500
+		}
+		start = now.Add(-iv.Duration() * time.Duration(n))
+	}
+	rangeEnd := end
+	if rangeEnd.IsZero() {
+		rangeEnd = now
+	}
+
+	name := p.inner.Name()
+	stored, err := p.store.Load(name, symbol, interval)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Cache %s rusak, ambil ulang: %v\n", p.store.path(name, symbol, interval), err)
+		stored = nil
+	}
+
+	if p.offline {
+		if len(stored) == 0 {
+			return nil, fmt.Errorf("offline: belum ada cache %s %s dari %s", symbol, interval, name)
+		}
+		fillCloseTimes(stored, iv)
+		return trimCandles(filterRange(stored, start, end, 0), latest, limit), nil
+	}
+
+	known, err := p.store.Empty(name, symbol, interval)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v, gap dicek ulang\n", err)
+	}
+	fresh, checked, rewrite, err := p.fillMissing(symbol, iv, stored, known, start, rangeEnd)
+	if err != nil {
+		// Hanya gangguan jaringan / rate limit yang boleh fallback ke cache
+		transient := errors.Is(err, ErrNetwork) || errors.Is(err, ErrRateLimited)
+		if len(stored) == 0 || !transient {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  Gagal update data %s (%v), pakai cache lokal\n", name, err)
+	}
+
+	merged := mergeCandles(stored, fresh)
+	if err := p.persist(name, symbol, iv, stored, merged, rewrite, now); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Gagal simpan cache: %v\n", err)
+	} else if err := p.store.MarkEmpty(name, symbol, interval, checked); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Gagal simpan range kosong: %v\n", err)
+	}
+	fillCloseTimes(merged, iv)
+	return trimCandles(filterRange(merged, start, end, 0), latest, limit), nil
+}
+
+// fillMissing fetches the head, the gaps and the tail that the store does
+// not cover for [start, end], skipping head and gap ranges inside known.
+// checked are the head and gap ranges fetched completely. rewrite is true
+// when anything landed before the last stored candle, meaning the file can
+// no longer just be appended to.
+func (p *cachedProvider) fillMissing(symbol string, iv Interval, stored []Candle, known []timeRange, start, end time.Time) (fresh []Candle, checked []timeRange, rewrite bool, err error) {
+	interval := iv.Code
+	if len(stored) == 0 {
+		candles, err := backfillCandles(p.inner, symbol, interval, start, end)
+		return candles, nil, true, err
+	}
+
+	first, last := stored[0].Time, stored[len(stored)-1].Time
+
+	// History sebelum candle pertama di cache
+	if to := first.Add(-time.Millisecond); start.Before(first) && !coveredBy(known, start, to) {
+		head, err := backfillCandles(p.inner, symbol, interval, start, to)
+		if err != nil {
+			return fresh, checked, rewrite, err
+		}
+		fresh = append(fresh, head...)
+		checked = append(checked, timeRange{From: start, To: to})
+		rewrite = rewrite || len(head) > 0
+	}
+
+	// Repair bar yang bolong di tengah
+	for _, gap := range findGaps(stored, iv) {
+		from, to := laterOf(iv.Next(gap.From), start), earlierOf(gap.To.Add(-time.Millisecond), end)
+		if !from.Before(to) || coveredBy(known, from, to) {
+			continue
+		}
+		repaired, err := backfillCandles(p.inner, symbol, interval, from, to)
+		if err != nil {
+			return fresh, checked, rewrite, err
+		}
+		fresh = append(fresh, repaired...)
+		checked = append(checked, timeRange{From: from, To: to})
+		rewrite = rewrite || len(repaired) > 0
+	}
+
+	// Tail: candle setelah yang terakhir disimpan, termasuk candle yang masih jalan
+	if tailStart := laterOf(iv.Next(last), start); tailStart.Before(end) {
+		tail, err := backfillCandles(p.inner, symbol, interval, tailStart, end)
+		if err != nil {
+			return fresh, checked, rewrite, err
+		}
+		fresh = append(fresh, tail...)
+	}
+	return fresh, checked, rewrite, nil
+}
+
+func (p *cachedProvider) persist(name, symbol string, iv Interval, stored, merged []Candle, rewrite bool, now time.Time) error {
+	interval := iv.Code
+	var closed []Candle
+	for _, c := range merged {
+		if !iv.Next(c.Time).After(now) {
+			closed = append(closed, c)
+		}
+	}
+	if rewrite {
+		return p.store.Rewrite(name, symbol, interval, closed)
+	}
+
+	var tail []Candle
+	for _, c := range closed {
+		if len(stored) == 0 || c.Time.After(stored[len(stored)-1].Time) {
+			tail = append(tail, c)
+		}
+	}
+	return p.store.Append(name, symbol, interval, tail)
+}
+
+func laterOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlierOf(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// trimCandles applies limit the same way a provider would for an open
+// ended request (newest candles) or a ranged one (oldest first).
+func trimCandles(candles []Candle, latest bool, limit int) []Candle {
+	if limit <= 0 || len(candles) <= limit {
+		return candles
+	}
+	if latest {
+		return candles[len(candles)-limit:]
+	}
+	return candles[:limit]
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// holeProvider serves candles from memory and counts the ranges asked for.
+type holeProvider struct {
+	candles []Candle
+	calls   []timeRange
+}
+
+func (h *holeProvider) Name() string { return "hole" }
+
+func (h *holeProvider) Candles(symbol, interval string, start, end time.Time, limit int) ([]Candle, error) {
+	h.calls = append(h.calls, timeRange{From: start, To: end})
+	return filterRange(h.candles, start, end, limit), nil
+}
+
+func TestCachedProviderSkipsKnownEmptyGaps(t *testing.T) {
+	// 60 candle 1h yang sudah close. Venue tidak punya #20-#24, store juga
+	// bolong di #40-#44 yang masih bisa diisi
+	all := waveCandles(60, time.Now().Truncate(time.Hour).Add(-2*time.Hour))
+	var venue, stored []Candle
+	for i, c := range all {
+		if i < 20 || i > 24 {
+			venue = append(venue, c)
+		}
+		if (i < 20 || i > 24) && (i < 40 || i > 44) {
+			stored = append(stored, c)
+		}
+	}
+	inner := &holeProvider{candles: venue}
+	store := &candleStore{dir: t.TempDir()}
+	if err := store.Rewrite("hole", "SOLUSDT", "1h", stored); err != nil {
+		t.Fatal(err)
+	}
+	p := &cachedProvider{inner: inner, store: store}
+	start, end := all[0].Time, all[len(all)-1].Time
+
+	got, err := p.Candles("SOLUSDT", "1h", start, end, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(venue) {
+		t.Fatalf("dapat %d candle, mau %d", len(got), len(venue))
+	}
+	// Satu request per gap, end sudah di candle terakhir jadi tanpa tail
+	if len(inner.calls) != 2 {
+		t.Fatalf("run pertama %d request, mau 2: %v", len(inner.calls), inner.calls)
+	}
+
+	inner.calls = nil
+	if got, err = p.Candles("SOLUSDT", "1h", start, end, 0); err != nil || len(got) != len(venue) {
+		t.Fatalf("run kedua: %d candle, err %v", len(got), err)
+	}
+	for _, c := range inner.calls {
+		t.Errorf("gap yang sudah dicek diambil lagi: %s → %s", c.From.Format(time.RFC3339), c.To.Format(time.RFC3339))
+	}
+
+	known, err := store.Empty("hole", "SOLUSDT", "1h")
+	if err != nil || len(known) != 2 {
+		t.Fatalf("range kosong = %v, err %v; mau 2 range", known, err)
+	}
+}
+
+func TestMergeRanges(t *testing.T) {
+	at := func(h int) time.Time { return barStart.Add(time.Duration(h) * time.Hour) }
+	got := mergeRanges([]timeRange{
+		{at(5), at(6)}, {at(0), at(2)}, {at(1), at(3)}, {at(3).Add(time.Millisecond), at(4)}, {at(8), at(9)},
+	})
+	want := []timeRange{{at(0), at(4)}, {at(5), at(6)}, {at(8), at(9)}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].From.Equal(want[i].From) || !got[i].To.Equal(want[i].To) {
+			t.Errorf("range %d = %v, mau %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ================================
+// STREAMING (SERVER-SENT EVENTS)
+// ================================
+
+// LLMDelta is one streamed piece of an answer.
+type LLMDelta struct {
+	Reasoning bool // chain of thought rather than the answer itself
+	Text      string
+}
+
+// streamingClient is implemented by backends that can stream their answer.
+// onDelta is called in order from the calling goroutine; the returned
+// response holds the assembled content and reasoning.
+type streamingClient interface {
+	Stream(ctx context.Context, prompt string, onDelta func(LLMDelta)) (*LLMResponse, error)
+}
+
+// streamAI posts payload like callAI and hands every SSE event to onEvent
+// until the body ends, the server sends [DONE] or ctx is cancelled. Only
+// the initial request is retried; a stream cut off halfway is an error.
+func streamAI(ctx context.Context, client *http.Client, cfg LLMConfig, url string, header http.Header, payload any, onEvent func(event string, data []byte) error) error {
+	header = header.Clone()
+	header.Set("Accept", "text/event-stream")
+	resp, err := postAI(ctx, client, cfg, url, header, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var event string
+	var data []byte
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// Baris kosong = akhir satu event
+			if len(data) > 0 {
+				if string(data) == "[DONE]" {
+					return nil
+				}
+				if err := onEvent(event, data); err != nil {
+					return err
+				}
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// komentar / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(data) > 0 && string(data) != "[DONE]" {
+		return onEvent(event, data)
+	}
+	return nil
+}
+
+type chatStreamChunk struct {
+	Model   string     `json:"model"`
+	Usage   *chatUsage `json:"usage"`
+	Choices []struct {
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+func (c *openAIClient) Stream(ctx context.Context, prompt string, onDelta func(LLMDelta)) (*LLMResponse, error) {
+	req, header := c.request(prompt)
+	req.Stream = true
+	req.StreamOptions = &streamOptions{IncludeUsage: true}
+
+	var content, reasoning strings.Builder
+	out := &LLMResponse{Model: c.cfg.Model, Usage: LLMUsage{Calls: 1}}
+	err := streamAI(ctx, c.client, c.cfg, c.url(), header, req, func(_ string, data []byte) error {
+		if code, msg := parseLLMError(data); msg != "" {
+			return &LLMError{Backend: c.cfg.Name, Status: http.StatusOK, Code: code, Msg: msg}
+		}
+		var chunk chatStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("decode chunk: %w", err)
+		}
+		out.Model = orDefault(chunk.Model, out.Model)
+		if chunk.Usage != nil {
+			// Chunk terakhir (stream_options.include_usage)
+			out.Usage = chunk.Usage.usage()
+		}
+		for _, choice := range chunk.Choices {
+			if t := choice.Delta.ReasoningContent; t != "" {
+				reasoning.WriteString(t)
+				onDelta(LLMDelta{Reasoning: true, Text: t})
+			}
+			if t := choice.Delta.Content; t != "" {
+				content.WriteString(t)
+				onDelta(LLMDelta{Text: t})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.cfg.Name, err)
+	}
+	out.Content, out.Reasoning = content.String(), reasoning.String()
+	return out, nil
+}
+
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Model string         `json:"model"`
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Usage anthropicUsage `json:"usage"`
+	Delta struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		Thinking string `json:"thinking"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *anthropicClient) Stream(ctx context.Context, prompt string, onDelta func(LLMDelta)) (*LLMResponse, error) {
+	req, header := c.request(prompt)
+	req.Stream = true
+
+	var content, reasoning strings.Builder
+	out := &LLMResponse{Model: c.cfg.Model, Usage: LLMUsage{Calls: 1}}
+	err := streamAI(ctx, c.client, c.cfg, c.url(), header, req, func(_ string, data []byte) error {
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return fmt.Errorf("decode event: %w", err)
+		}
+		switch ev.Type {
+		case "message_start":
+			out.Model = orDefault(ev.Message.Model, out.Model)
+			out.Usage.InputTokens = ev.Message.Usage.InputTokens
+		case "message_delta":
+			// output_tokens di sini kumulatif
+			out.Usage.OutputTokens = ev.Usage.OutputTokens
+		case "content_block_delta":
+			switch ev.Delta.Type {
+			case "text_delta":
+				content.WriteString(ev.Delta.Text)
+				onDelta(LLMDelta{Text: ev.Delta.Text})
+			case "thinking_delta":
+				reasoning.WriteString(ev.Delta.Thinking)
+				onDelta(LLMDelta{Reasoning: true, Text: ev.Delta.Thinking})
+			}
+		case "error":
+			return &LLMError{Backend: c.cfg.Name, Status: http.StatusOK, Code: ev.Error.Type, Msg: ev.Error.Message}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.cfg.Name, err)
+	}
+	out.Content, out.Reasoning = content.String(), reasoning.String()
+	if out.Content == "" {
+		return nil, fmt.Errorf("%s: response tanpa text", c.cfg.Name)
+	}
+	return out, nil
+}
+
+// ================================
+// TERMINAL RENDERING
+// ================================
+
+const (
+	ansiDim   = "\x1b[2m"
+	ansiReset = "\x1b[0m"
+)
+
+// streamPrinter renders deltas as they arrive. Reasoning is printed dimmed
+// with showReasoning, otherwise it collapses into a single progress line;
+// the same goes for the answer when showContent is off (JSON answers are
+// rendered properly once complete).
+type streamPrinter struct {
+	out           io.Writer
+	tty           bool
+	showReasoning bool
+	showContent   bool
+
+	section         string // "", "reasoning" or "content"
+	reasoningTokens int
+	contentTokens   int
+}
+
+func newStreamPrinter(showReasoning, showContent bool) *streamPrinter {
+	return &streamPrinter{out: os.Stdout, tty: isTerminal(os.Stdout), showReasoning: showReasoning, showContent: showContent}
+}
+
+func (p *streamPrinter) Delta(d LLMDelta) {
+	if d.Reasoning {
+		p.reasoningTokens++
+		p.enter("reasoning")
+		switch {
+		case p.showReasoning:
+			fmt.Fprint(p.out, d.Text)
+		case p.tty:
+			fmt.Fprintf(p.out, "\r🧠 Berpikir... %d token", p.reasoningTokens)
+		}
+		return
+	}
+
+	p.contentTokens++
+	p.enter("content")
+	switch {
+	case p.showContent:
+		fmt.Fprint(p.out, d.Text)
+	case p.tty:
+		fmt.Fprintf(p.out, "\r📝 Menulis jawaban... %d token", p.contentTokens)
+	}
+}
+
+// enter closes the previous section and opens the next one.
+func (p *streamPrinter) enter(section string) {
+	if p.section == section {
+		return
+	}
+	p.close()
+	p.section = section
+	if section == "reasoning" && p.showReasoning {
+		fmt.Fprintln(p.out, "🧠 REASONING:")
+		p.style(ansiDim)
+	}
+	if section == "content" && p.showContent {
+		fmt.Fprintln(p.out, "💬 JAWABAN:")
+	}
+}
+
+func (p *streamPrinter) close() {
+	switch {
+	case p.section == "reasoning" && p.showReasoning:
+		p.style(ansiReset)
+		fmt.Fprint(p.out, "\n\n")
+	case p.section == "reasoning" && p.tty, p.section == "content" && !p.showContent && p.tty:
+		fmt.Fprintln(p.out)
+	case p.section == "content" && p.showContent:
+		fmt.Fprint(p.out, "\n\n")
+	}
+}
+
+// Done finishes the last section and resets the terminal style, also when
+// the stream was cut off by Ctrl-C.
+func (p *streamPrinter) Done() {
+	p.close()
+	p.section = ""
+}
+
+// Printed reports whether the answer itself went to the terminal, so it
+// does not need to be printed again.
+func (p *streamPrinter) Printed() bool {
+	return p.showContent && p.contentTokens > 0
+}
+
+func (p *streamPrinter) style(code string) {
+	if p.tty {
+		fmt.Fprint(p.out, code)
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
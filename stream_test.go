@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sseServer writes body as an event stream.
+func sseServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accept := r.Header.Get("Accept"); accept != "text/event-stream" {
+			t.Errorf("Accept %q", accept)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestStreamAIParser(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string // "event|data"
+	}{
+		{
+			name: "berhenti di [DONE]",
+			body: "data: {\"a\":1}\n\ndata: {\"a\":2}\n\ndata: [DONE]\n\ndata: {\"a\":3}\n\n",
+			want: []string{`|{"a":1}`, `|{"a":2}`},
+		},
+		{
+			name: "data multi-baris digabung dengan newline",
+			body: "data: baris satu\ndata:baris dua\ndata: baris tiga\n\n",
+			want: []string{"|baris satu\nbaris dua\nbaris tiga"},
+		},
+		{
+			name: "komentar keep-alive dilewati",
+			body: ": ping\n\n:\ndata: x\n: masih jalan\n\n",
+			want: []string{"|x"},
+		},
+		{
+			name: "event bernama",
+			body: "event: message_start\ndata: {}\n\nevent: error\ndata: {\"type\":\"error\"}\n\ndata: tanpa nama\n\n",
+			want: []string{"message_start|{}", `error|{"type":"error"}`, "|tanpa nama"},
+		},
+		{
+			name: "CRLF",
+			body: "event: ping\r\ndata: satu\r\n\r\ndata: dua\r\ndata: tiga\r\n\r\n",
+			want: []string{"ping|satu", "|dua\ntiga"},
+		},
+		{
+			name: "event terakhir tanpa baris kosong",
+			body: "data: a\n\ndata: b",
+			want: []string{"|a", "|b"},
+		},
+		{
+			name: "baris kosong berturut-turut dan field lain",
+			body: "\n\nid: 7\nretry: 1000\ndata: a\n\n\n",
+			want: []string{"|a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := sseServer(t, tt.body)
+			var got []string
+			err := streamAI(context.Background(), srv.Client(), LLMConfig{Name: "openai"}, srv.URL, http.Header{}, struct{}{},
+				func(event string, data []byte) error {
+					got = append(got, event+"|"+string(data))
+					return nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("event:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamAIStopsOnHandlerError(t *testing.T) {
+	srv := sseServer(t, "data: 1\n\ndata: 2\n\ndata: 3\n\n")
+	stop := errors.New("stop")
+	calls := 0
+	err := streamAI(context.Background(), srv.Client(), LLMConfig{}, srv.URL, http.Header{}, struct{}{}, func(string, []byte) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("err %v setelah %d event, mau stop setelah 1", err, calls)
+	}
+}
+
+func TestStreamAICancelledMidStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: pertama\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done() // stream menggantung sampai client putus
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var got []string
+	err := streamAI(ctx, srv.Client(), LLMConfig{}, srv.URL, http.Header{}, struct{}{}, func(_ string, data []byte) error {
+		got = append(got, string(data))
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err %v, mau context.Canceled", err)
+	}
+	if !reflect.DeepEqual(got, []string{"pertama"}) {
+		t.Errorf("event %q", got)
+	}
+}
+
+func TestAnthropicStreamErrorEvent(t *testing.T) {
+	srv := sseServer(t, strings.Join([]string{
+		"event: message_start",
+		`data: {"type":"message_start","message":{"model":"claude-test","usage":{"input_tokens":12}}}`,
+		"",
+		"event: content_block_delta",
+		`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"bias"}}`,
+		"",
+		"event: error",
+		`data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+		"", "",
+	}, "\n"))
+
+	client := &anthropicClient{cfg: LLMConfig{Name: "anthropic", BaseURL: srv.URL, Model: "m"}, client: srv.Client()}
+	var deltas []LLMDelta
+	_, err := client.Stream(context.Background(), "x", func(d LLMDelta) { deltas = append(deltas, d) })
+	var le *LLMError
+	if !errors.As(err, &le) || le.Code != "overloaded_error" || le.Msg != "Overloaded" {
+		t.Fatalf("err %v, mau LLMError overloaded_error", err)
+	}
+	if len(deltas) != 1 || deltas[0].Text != "bias" {
+		t.Errorf("delta sebelum error %+v", deltas)
+	}
+}
+
+func TestOpenAIStreamAssemblesAnswer(t *testing.T) {
+	srv := sseServer(t, strings.Join([]string{
+		`data: {"model":"deepseek-reasoner","choices":[{"delta":{"reasoning_content":"cek "}}]}`,
+		"",
+		`data: {"choices":[{"delta":{"reasoning_content":"EMA"}}]}`,
+		"",
+		": keep-alive",
+		`data: {"choices":[{"delta":{"content":"{\"bias\":"}}]}`,
+		"",
+		`data: {"choices":[{"delta":{"content":"\"long\"}"}}]}`,
+		"",
+		`data: {"choices":[],"usage":{"prompt_tokens":50,"completion_tokens":20,"completion_tokens_details":{"reasoning_tokens":8}}}`,
+		"",
+		"data: [DONE]",
+		"", "",
+	}, "\r\n"))
+
+	client := &openAIClient{cfg: LLMConfig{Name: "deepseek", BaseURL: srv.URL, Model: "m"}, client: srv.Client()}
+	var deltas int
+	resp, err := client.Stream(context.Background(), "x", func(LLMDelta) { deltas++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != `{"bias":"long"}` || resp.Reasoning != "cek EMA" || resp.Model != "deepseek-reasoner" || deltas != 4 {
+		t.Errorf("response %+v, %d delta", resp, deltas)
+	}
+	if resp.Usage != (LLMUsage{InputTokens: 50, OutputTokens: 20, ReasoningTokens: 8, Calls: 1}) {
+		t.Errorf("usage %+v", resp.Usage)
+	}
+}
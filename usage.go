@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// ================================
+// TOKEN & COST ACCOUNTING
+// ================================
+
+// modelPrice is USD per 1M tokens. Reasoning tokens are billed as output.
+type modelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// defaultPrices are list prices at the time of writing; override them with
+// a prices.json ({"model-prefix": {"input": 0.27, "output": 1.1}}).
+// Keys match the model name by prefix, the longest key wins.
+var defaultPrices = map[string]modelPrice{
+	"deepseek-chat":     {Input: 0.27, Output: 1.10},
+	"deepseek-reasoner": {Input: 0.55, Output: 2.19},
+	"grok-beta":         {Input: 5, Output: 15},
+	"grok-2":            {Input: 2, Output: 10},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60},
+	"gpt-4o":            {Input: 2.50, Output: 10},
+	"claude-3-5-sonnet": {Input: 3, Output: 15},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4},
+}
+
+// usageEntry is one line of the JSONL ledger.
+type usageEntry struct {
+	Time   time.Time `json:"time"`
+	AI     string    `json:"ai"`
+	Model  string    `json:"model"`
+	Symbol string    `json:"symbol,omitempty"`
+	TF     string    `json:"timeframe,omitempty"`
+	LLMUsage
+}
+
+func defaultUsageLedger() string {
+	if v := os.Getenv("USAGE_LEDGER"); v != "" {
+		return v
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ai-trade", "usage.jsonl")
+}
+
+func defaultPricesFile() string {
+	if v := os.Getenv("LLM_PRICES"); v != "" {
+		return v
+	}
+	return filepath.Join(filepath.Dir(defaultUsageLedger()), "prices.json")
+}
+
+// loadPrices merges path over defaultPrices. A missing file is fine.
+func loadPrices(path string) (map[string]modelPrice, error) {
+	prices := make(map[string]modelPrice, len(defaultPrices))
+	for k, v := range defaultPrices {
+		prices[k] = v
+	}
+	if path == "" {
+		return prices, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return prices, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var custom map[string]modelPrice
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for k, v := range custom {
+		prices[k] = v
+	}
+	return prices, nil
+}
+
+// priceFor finds the longest price key that prefixes model.
+func priceFor(prices map[string]modelPrice, model string) (modelPrice, bool) {
+	best, found := "", false
+	for k := range prices {
+		if strings.HasPrefix(model, k) && len(k) >= len(best) {
+			best, found = k, true
+		}
+	}
+	return prices[best], found
+}
+
+// usageMeter prices every call, appends it to the ledger and refuses new
+// calls once today's spend reaches the budget. It is shared by all calls
+// of a run, including concurrent ensemble members. With a budget the calls
+// run one at a time, so each check sees what the previous call cost.
+type usageMeter struct {
+	ledger string
+	prices map[string]modelPrice
+	budget float64 // USD per day, 0 = no cap
+
+	mu    sync.Mutex // ledger writes
+	calls sync.Mutex // check, call and record as one step under --budget
+}
+
+func newUsageMeter(ledger, pricesFile string, budget float64) (*usageMeter, error) {
+	prices, err := loadPrices(pricesFile)
+	if err != nil {
+		return nil, err
+	}
+	if budget > 0 && ledger == "" {
+		return nil, errors.New("--budget butuh --ledger")
+	}
+	return &usageMeter{ledger: ledger, prices: prices, budget: budget}, nil
+}
+
+// cost fills u.Cost for model; unknown models (local ones) cost nothing.
+func (m *usageMeter) cost(model string, u *LLMUsage) {
+	p, _ := priceFor(m.prices, model)
+	u.Cost = (float64(u.InputTokens)*p.Input + float64(u.OutputTokens)*p.Output) / 1e6
+}
+
+// spentToday sums today's (local time) cost in the ledger.
+func (m *usageMeter) spentToday() (float64, error) {
+	entries, err := readUsageLedger(m.ledger)
+	if err != nil {
+		return 0, err
+	}
+	today := time.Now().Format("2006-01-02")
+	total := 0.0
+	for _, e := range entries {
+		if e.Time.Local().Format("2006-01-02") == today {
+			total += e.Cost
+		}
+	}
+	return total, nil
+}
+
+// reserve checks the budget and, when there is one, holds it until the
+// returned release is called after the call has been recorded. Without
+// that, parallel ensemble members or backtest points would all see the
+// same spend and overshoot the cap together.
+func (m *usageMeter) reserve() (release func(), err error) {
+	if m.budget <= 0 {
+		return func() {}, nil
+	}
+	m.calls.Lock()
+	if err := m.checkBudget(); err != nil {
+		m.calls.Unlock()
+		return nil, err
+	}
+	return m.calls.Unlock, nil
+}
+
+func (m *usageMeter) checkBudget() error {
+	if m.budget <= 0 {
+		return nil
+	}
+	spent, err := m.spentToday()
+	if err != nil {
+		return err
+	}
+	if spent >= m.budget {
+		return fmt.Errorf("budget harian $%.2f sudah habis (terpakai $%.4f), lihat: ai-trade usage", m.budget, spent)
+	}
+	return nil
+}
+
+func (m *usageMeter) record(e usageEntry) error {
+	if m.ledger == "" {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(m.ledger), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(m.ledger, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(e)
+}
+
+// meteredClient wraps an LLMClient with the meter. Symbol and TF label the
+// ledger entries.
+type meteredClient struct {
+	LLMClient
+	meter      *usageMeter
+	model      string
+	symbol, tf string
+}
+
+func (m *usageMeter) wrap(client LLMClient, cfg LLMConfig) LLMClient {
+	return &meteredClient{LLMClient: client, meter: m, model: cfg.Model, symbol: cfg.Symbol, tf: cfg.TF}
+}
+
+func (c *meteredClient) Complete(ctx context.Context, prompt string) (*LLMResponse, error) {
+	release, err := c.meter.reserve()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	resp, err := c.LLMClient.Complete(ctx, prompt)
+	return c.account(resp, err)
+}
+
+// Stream keeps streaming available through the wrapper; backends without
+// it fall back to Complete.
+func (c *meteredClient) Stream(ctx context.Context, prompt string, onDelta func(LLMDelta)) (*LLMResponse, error) {
+	sc, ok := c.LLMClient.(streamingClient)
+	if !ok {
+		return c.Complete(ctx, prompt)
+	}
+	release, err := c.meter.reserve()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	resp, err := sc.Stream(ctx, prompt, onDelta)
+	return c.account(resp, err)
+}
+
+func (c *meteredClient) account(resp *LLMResponse, err error) (*LLMResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+	model := orDefault(resp.Model, c.model)
+	c.meter.cost(model, &resp.Usage)
+	entry := usageEntry{Time: time.Now(), AI: c.Name(), Model: model, Symbol: c.symbol, TF: c.tf, LLMUsage: resp.Usage}
+	if err := c.meter.record(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Gagal tulis usage ledger: %v\n", err)
+	}
+	return resp, nil
+}
+
+func readUsageLedger(path string) ([]usageEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []usageEntry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var e usageEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s baris %d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// printUsageTotals is the footer of an analysis: tokens, cost and, with a
+// budget, what is left of today's.
+func printUsageTotals(u LLMUsage, meter *usageMeter) {
+	if u.Calls == 0 {
+		return
+	}
+	fmt.Printf("🔢 Token: %s | 💰 $%.4f", u, u.Cost)
+	if meter != nil && meter.budget > 0 {
+		if spent, err := meter.spentToday(); err == nil {
+			fmt.Printf(" | hari ini $%.4f / budget $%.2f", spent, meter.budget)
+		}
+	}
+	fmt.Println()
+}
+
+// runUsage summarizes the ledger by day, model and symbol.
+func runUsage(args []string) error {
+	flags := flag.NewFlagSet("usage", flag.ContinueOnError)
+	ledger := flags.String("ledger", defaultUsageLedger(), "file usage ledger (JSONL)")
+	by := flags.String("by", "day,model,symbol", "ringkas per: day, model, symbol (pisah koma)")
+	since := flags.String("since", "", "hanya pemakaian sejak tanggal ini, mis. 2024-06-01")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	from, err := parseDateFlag(*since)
+	if err != nil {
+		return err
+	}
+
+	entries, err := readUsageLedger(*ledger)
+	if err != nil {
+		return err
+	}
+	var kept []usageEntry
+	for _, e := range entries {
+		if from.IsZero() || !e.Time.Before(from) {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == 0 {
+		fmt.Printf("Belum ada pemakaian tercatat di %s\n", *ledger)
+		return nil
+	}
+
+	keys := map[string]func(usageEntry) string{
+		"day":    func(e usageEntry) string { return e.Time.Local().Format("2006-01-02") },
+		"model":  func(e usageEntry) string { return e.AI + "/" + e.Model },
+		"symbol": func(e usageEntry) string { return orDefault(strings.TrimSpace(e.Symbol+" "+e.TF), "-") },
+	}
+	for _, name := range strings.Split(*by, ",") {
+		name = strings.TrimSpace(name)
+		key, ok := keys[name]
+		if !ok {
+			return fmt.Errorf("--by %q tidak dikenal, pilih day, model atau symbol", name)
+		}
+		printUsageTable(strings.ToUpper(name), kept, key)
+	}
+	return nil
+}
+
+func printUsageTable(title string, entries []usageEntry, key func(usageEntry) string) {
+	groups := map[string]*LLMUsage{}
+	var total LLMUsage
+	for _, e := range entries {
+		k := key(e)
+		if groups[k] == nil {
+			groups[k] = &LLMUsage{}
+		}
+		groups[k].Add(e.LLMUsage)
+		total.Add(e.LLMUsage)
+	}
+	names := make([]string, 0, len(groups))
+	for k := range groups {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\tCALLS\tINPUT\tOUTPUT\tREASONING\tCOST USD\t\n", title)
+	row := func(name string, u LLMUsage) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%.4f\t\n", name, u.Calls, u.InputTokens, u.OutputTokens, u.ReasoningTokens, u.Cost)
+	}
+	for _, k := range names {
+		row(k, *groups[k])
+	}
+	row("TOTAL", total)
+	tw.Flush()
+	fmt.Println()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPriceFor(t *testing.T) {
+	prices := map[string]modelPrice{
+		"gpt-4o":        {Input: 2.5, Output: 10},
+		"gpt-4o-mini":   {Input: 0.15, Output: 0.6},
+		"deepseek-chat": {Input: 0.27, Output: 1.1},
+		"deepseek":      {Input: 1, Output: 1},
+	}
+	tests := []struct {
+		model string
+		want  modelPrice
+		found bool
+	}{
+		{"gpt-4o-mini-2024-07-18", prices["gpt-4o-mini"], true},
+		{"gpt-4o-2024-08-06", prices["gpt-4o"], true},
+		{"gpt-4o", prices["gpt-4o"], true},
+		{"deepseek-chat", prices["deepseek-chat"], true},
+		{"deepseek-reasoner", prices["deepseek"], true},
+		{"llama3.1", modelPrice{}, false},
+		{"", modelPrice{}, false},
+	}
+	for _, tt := range tests {
+		got, found := priceFor(prices, tt.model)
+		if got != tt.want || found != tt.found {
+			t.Errorf("priceFor(%q) = %+v, %v; mau %+v, %v", tt.model, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestUsageCost(t *testing.T) {
+	m := &usageMeter{prices: defaultPrices}
+	tests := []struct {
+		model string
+		usage LLMUsage
+		want  float64
+	}{
+		{"deepseek-chat", LLMUsage{InputTokens: 1_000_000, OutputTokens: 500_000}, 0.27 + 0.55},
+		// Reasoning sudah termasuk output, tidak dihitung dua kali
+		{"deepseek-reasoner", LLMUsage{InputTokens: 2000, OutputTokens: 3000, ReasoningTokens: 2500}, (2000*0.55 + 3000*2.19) / 1e6},
+		{"claude-3-5-sonnet-latest", LLMUsage{InputTokens: 10_000, OutputTokens: 1000}, 0.03 + 0.015},
+		{"llama3.1", LLMUsage{InputTokens: 50_000, OutputTokens: 9000}, 0},
+	}
+	for _, tt := range tests {
+		u := tt.usage
+		m.cost(tt.model, &u)
+		if math.Abs(u.Cost-tt.want) > 1e-12 {
+			t.Errorf("cost %s %+v = %.8f, mau %.8f", tt.model, tt.usage, u.Cost, tt.want)
+		}
+	}
+}
+
+func TestLoadPricesOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	if err := os.WriteFile(path, []byte(`{"deepseek-chat":{"input":0.07,"output":1.1},"qwen":{"input":0.4,"output":1.2}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	prices, err := loadPrices(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prices["deepseek-chat"].Input != 0.07 || prices["qwen"].Output != 1.2 || prices["gpt-4o"] != defaultPrices["gpt-4o"] {
+		t.Errorf("prices %+v", prices)
+	}
+	if defaultPrices["deepseek-chat"].Input != 0.27 {
+		t.Error("override mengubah defaultPrices")
+	}
+	if _, err := loadPrices(filepath.Join(t.TempDir(), "tidak-ada.json")); err != nil {
+		t.Errorf("file tidak ada harus pakai default: %v", err)
+	}
+}
+
+// pricedClient answers after a short delay with usage that costs $0.60 at
+// the test price.
+type pricedClient struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *pricedClient) Name() string { return "priced" }
+
+func (c *pricedClient) Complete(ctx context.Context, prompt string) (*LLMResponse, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	return &LLMResponse{Content: "ok", Model: "test-model", Usage: LLMUsage{InputTokens: 1_000_000, Calls: 1}}, nil
+}
+
+func TestBudgetHoldsUnderParallelCalls(t *testing.T) {
+	ledger := filepath.Join(t.TempDir(), "usage.jsonl")
+	meter, err := newUsageMeter(ledger, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meter.prices["test-model"] = modelPrice{Input: 0.6}
+
+	inner := &pricedClient{}
+	client := meter.wrap(inner, LLMConfig{Model: "test-model", Symbol: "SOLUSDT", TF: "1h"})
+
+	// Lima member ensemble sekaligus dengan budget $1: cuma dua yang boleh jalan
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = client.Complete(context.Background(), "x")
+		}(i)
+	}
+	wg.Wait()
+
+	refused := 0
+	for _, err := range errs {
+		if err != nil {
+			if !strings.Contains(err.Error(), "budget harian $1.00 sudah habis") {
+				t.Errorf("err %v", err)
+			}
+			refused++
+		}
+	}
+	if inner.calls != 2 || refused != 3 {
+		t.Errorf("%d call lolos, %d ditolak; mau 2 dan 3", inner.calls, refused)
+	}
+	spent, err := meter.spentToday()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(spent-1.2) > 1e-9 {
+		t.Errorf("terpakai $%.4f, mau $1.20 (lewat paling banyak satu call)", spent)
+	}
+
+	entries, _ := readUsageLedger(ledger)
+	if len(entries) != 2 || entries[0].Symbol != "SOLUSDT" || entries[0].Model != "test-model" || entries[0].AI != "priced" {
+		t.Errorf("ledger %+v", entries)
+	}
+}
+
+func TestNoBudgetRunsInParallel(t *testing.T) {
+	meter, err := newUsageMeter("", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := meter.wrap(&pricedClient{}, LLMConfig{Model: "test-model"})
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Complete(context.Background(), "x")
+		}()
+	}
+	wg.Wait()
+	if d := time.Since(start); d > 80*time.Millisecond {
+		t.Errorf("tanpa budget call tetap antri (%s)", d)
+	}
+}
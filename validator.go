@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ================================
+// SETUP VALIDATOR
+// ================================
+
+// validatorConfig holds the limits a trade setup is checked against.
+type validatorConfig struct {
+	MinStopATR   float64 // stops closer than this many ATR get hunted
+	MaxEntryATR  float64 // entries further than this many ATR from price
+	RangeCandles int     // candles that make up the "recent range"
+	MaxDeviation float64 // any price this far (fraction) from close is invented
+	LevelATR     float64 // how close SL may sit to the wrong side of a level
+}
+
+var defaultValidatorConfig = validatorConfig{
+	MinStopATR:   0.5,
+	MaxEntryATR:  3,
+	RangeCandles: 100,
+	MaxDeviation: 0.25,
+	LevelATR:     0.25,
+}
+
+const (
+	verdictPass = "PASS"
+	verdictWarn = "WARN"
+	verdictFail = "FAIL"
+)
+
+type Finding struct {
+	Level   string `json:"level"` // WARN or FAIL
+	Message string `json:"message"`
+}
+
+// SetupVerdict is the validator's deterministic view of an LLM setup.
+type SetupVerdict struct {
+	Verdict     string    `json:"verdict"`
+	Findings    []Finding `json:"findings"`
+	Price       float64   `json:"price"`
+	ATR         float64   `json:"atr"`
+	RangeHigh   float64   `json:"range_high"`
+	RangeLow    float64   `json:"range_low"`
+	StopATR     float64   `json:"stop_atr"`
+	RR          float64   `json:"rr"`
+	RRPerTP     []float64 `json:"rr_per_tp"`
+	RRFromPrice float64   `json:"rr_from_price"`
+}
+
+// validateSetup checks entry, TPs and SL against the last close, ATR, the
+// recent high/low range and the detected S/R levels, and recomputes RR.
+func validateSetup(setup TradeSetup, candles []Candle, summary IndicatorSummary, levels []SupportResistance, cfg validatorConfig) *SetupVerdict {
+	if setup.Direction != "long" && setup.Direction != "short" {
+		return nil
+	}
+	v := &SetupVerdict{Verdict: verdictPass, Price: summary.Price, ATR: summary.ATRValue}
+	warn := func(format string, args ...any) {
+		v.Findings = append(v.Findings, Finding{Level: verdictWarn, Message: fmt.Sprintf(format, args...)})
+	}
+	fail := func(format string, args ...any) {
+		v.Findings = append(v.Findings, Finding{Level: verdictFail, Message: fmt.Sprintf(format, args...)})
+	}
+
+	recent := candles
+	if len(recent) > cfg.RangeCandles {
+		recent = recent[len(recent)-cfg.RangeCandles:]
+	}
+	v.RangeHigh, v.RangeLow = candleRange(recent)
+
+	long := setup.Direction == "long"
+	mid := setup.EntryMid()
+	price, atr := v.Price, v.ATR
+
+	// Harga yang jauh dari market = hasil halusinasi
+	for _, p := range []struct {
+		name  string
+		value float64
+	}{{"entry", mid}, {"SL", setup.SL}, {"TP1", setup.TP1}, {"TP2", setup.TP2}, {"TP3", setup.TP3}} {
+		if p.value > 0 && math.Abs(p.value-price)/price > cfg.MaxDeviation {
+			fail("%s %.4f berjarak %.0f%% dari harga %.4f, tidak realistis", p.name, p.value, math.Abs(p.value-price)/price*100, price)
+		}
+	}
+
+	// Sisi entry terhadap harga sekarang
+	switch {
+	case long && setup.EntryLow <= price && setup.SL >= price:
+		fail("SL %.4f di atas harga sekarang %.4f, long langsung kena stop", setup.SL, price)
+	case !long && setup.EntryHigh >= price && setup.SL <= price:
+		fail("SL %.4f di bawah harga sekarang %.4f, short langsung kena stop", setup.SL, price)
+	case long && setup.EntryLow > price:
+		warn("entry long %.4f di atas harga %.4f%s, ini breakout / stop order, bukan limit", setup.EntryLow, price, atrAway(setup.EntryLow-price, atr))
+	case !long && setup.EntryHigh < price:
+		warn("entry short %.4f di bawah harga %.4f%s, ini breakdown / stop order, bukan limit", setup.EntryHigh, price, atrAway(price-setup.EntryHigh, atr))
+	}
+	if atr > 0 {
+		if dist := math.Abs(mid-price) / atr; dist > cfg.MaxEntryATR {
+			warn("entry %.1f ATR dari harga, kemungkinan tidak tersentuh", dist)
+		}
+
+		v.StopATR = math.Abs(mid-setup.SL) / atr
+		if v.StopATR < cfg.MinStopATR {
+			warn("SL cuma %.2f ATR dari entry (minimal %.2f ATR), rawan kena noise", v.StopATR, cfg.MinStopATR)
+		}
+	}
+
+	// Target di luar range terakhir
+	for i, tp := range setup.Targets() {
+		if long && tp > v.RangeHigh {
+			warn("TP%d %.4f di atas high %d candle terakhir (%.4f)", i+1, tp, len(recent), v.RangeHigh)
+		}
+		if !long && tp < v.RangeLow {
+			warn("TP%d %.4f di bawah low %d candle terakhir (%.4f)", i+1, tp, len(recent), v.RangeLow)
+		}
+	}
+
+	// SL sebaiknya di balik level, bukan persis di depannya
+	for _, level := range levels {
+		between := (long && level.Price < mid && level.Price < setup.SL && setup.SL-level.Price < cfg.LevelATR*atr) ||
+			(!long && level.Price > mid && level.Price > setup.SL && level.Price-setup.SL < cfg.LevelATR*atr)
+		if between {
+			warn("SL %.4f persis di depan %s %.4f, taruh di balik level", setup.SL, level.Type, level.Price)
+		}
+	}
+
+	// RR dihitung ulang, tidak percaya angka dari AI
+	v.RR = setup.ComputedRR()
+	if risk := math.Abs(mid - setup.SL); risk > 0 {
+		for _, tp := range setup.Targets() {
+			v.RRPerTP = append(v.RRPerTP, math.Abs(tp-mid)/risk)
+		}
+	}
+	if risk := math.Abs(price - setup.SL); risk > 0 {
+		v.RRFromPrice = math.Abs(setup.Target()-price) / risk
+	}
+	if math.Abs(v.RR-setup.RR) > math.Max(rrTolerance, v.RR*0.1) {
+		warn("RR dari AI 1:%.2f, hitungan validator 1:%.2f", setup.RR, v.RR)
+	}
+
+	for _, f := range v.Findings {
+		if f.Level == verdictFail {
+			v.Verdict = verdictFail
+			break
+		}
+		v.Verdict = verdictWarn
+	}
+	return v
+}
+
+// atrAway formats a distance as " (x ATR)", empty without an ATR.
+func atrAway(dist, atr float64) string {
+	if atr <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%.2f ATR)", dist/atr)
+}
+
+func candleRange(candles []Candle) (high, low float64) {
+	for i, c := range candles {
+		h, l := c.High.InexactFloat64(), c.Low.InexactFloat64()
+		if i == 0 || h > high {
+			high = h
+		}
+		if i == 0 || l < low {
+			low = l
+		}
+	}
+	return high, low
+}
+
+func renderVerdict(v *SetupVerdict) string {
+	if v == nil {
+		return ""
+	}
+	emoji := map[string]string{verdictPass: "✅", verdictWarn: "⚠️ ", verdictFail: "❌"}[v.Verdict]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🧪 VALIDATOR VERDICT: %s %s\n", emoji, v.Verdict)
+	fmt.Fprintf(&b, "├ Harga %.4f | ATR %.4f | range %.4f - %.4f\n", v.Price, v.ATR, v.RangeLow, v.RangeHigh)
+
+	var rrs []string
+	for i, rr := range v.RRPerTP {
+		rrs = append(rrs, fmt.Sprintf("TP%d 1:%.2f", i+1, rr))
+	}
+	fmt.Fprintf(&b, "├ RR (hitung ulang): 1:%.2f [%s] | dari harga sekarang 1:%.2f\n", v.RR, strings.Join(rrs, ", "), v.RRFromPrice)
+	fmt.Fprintf(&b, "└ Jarak SL: %.2f ATR\n", v.StopATR)
+	for _, f := range v.Findings {
+		mark := "⚠️ "
+		if f.Level == verdictFail {
+			mark = "❌"
+		}
+		fmt.Fprintf(&b, "   %s %s\n", mark, f.Message)
+	}
+	return b.String()
+}
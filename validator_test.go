@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestValidateSetup(t *testing.T) {
+	// Harga 100, ATR 2, range 20 candle terakhir 90 - 110
+	rows := make([][4]float64, 20)
+	for i := range rows {
+		rows[i] = [4]float64{100, 101, 99, 100}
+	}
+	rows[3] = [4]float64{100, 110, 90, 100}
+	candles := bars(rows...)
+	summary := IndicatorSummary{Price: 100, ATRValue: 2}
+
+	long := TradeSetup{Direction: "long", EntryLow: 99, EntryHigh: 100, SL: 96, TP1: 104, TP2: 107, RR: 2.14}
+	tests := []struct {
+		name     string
+		setup    func(s *TradeSetup)
+		levels   []SupportResistance
+		verdict  string
+		findings []string // potongan pesan, urut
+	}{
+		{name: "setup bersih", verdict: verdictPass},
+		{
+			name:    "SL di bawah batas ATR",
+			setup:   func(s *TradeSetup) { s.SL, s.RR = 99, 15 },
+			verdict: verdictWarn, findings: []string{"SL cuma 0.25 ATR"},
+		},
+		{
+			name:    "TP di luar range terakhir",
+			setup:   func(s *TradeSetup) { s.TP2, s.RR = 112, 3.57 },
+			verdict: verdictWarn, findings: []string{"TP2 112.0000 di atas high 20 candle terakhir (110.0000)"},
+		},
+		{
+			name: "entry long di atas harga, kurang dari 1 ATR",
+			setup: func(s *TradeSetup) {
+				s.EntryLow, s.EntryHigh, s.SL, s.TP1, s.TP2, s.RR = 101, 101.5, 97.5, 105, 108, 1.8
+			},
+			verdict: verdictWarn, findings: []string{"entry long 101.0000 di atas harga 100.0000 (0.50 ATR), ini breakout / stop order"},
+		},
+		{
+			name: "entry short di bawah harga",
+			setup: func(s *TradeSetup) {
+				*s = TradeSetup{Direction: "short", EntryLow: 98.5, EntryHigh: 99, SL: 102.5, TP1: 95, TP2: 92, RR: 1.8}
+			},
+			verdict: verdictWarn, findings: []string{"entry short 99.0000 di bawah harga 100.0000 (0.50 ATR), ini breakdown / stop order"},
+		},
+		{
+			name:    "SL persis di depan support",
+			setup:   func(s *TradeSetup) { s.SL, s.RR = 96.4, 2.42 },
+			levels:  []SupportResistance{{Type: "support", Price: 96.3}, {Type: "support", Price: 95}},
+			verdict: verdictWarn, findings: []string{"SL 96.4000 persis di depan support 96.3000"},
+		},
+		{
+			name: "SL short persis di bawah resistance",
+			setup: func(s *TradeSetup) {
+				*s = TradeSetup{Direction: "short", EntryLow: 100, EntryHigh: 101, SL: 103.8, TP1: 96, TP2: 93, RR: 2.33}
+			},
+			levels:  []SupportResistance{{Type: "resistance", Price: 104.1}},
+			verdict: verdictWarn, findings: []string{"SL 103.8000 persis di depan resistance 104.1000"},
+		},
+		{
+			name:    "SL long di atas harga",
+			setup:   func(s *TradeSetup) { s.SL, s.TP1, s.TP2, s.RR = 100.5, 102, 103, 3.5 },
+			verdict: verdictFail, findings: []string{"long langsung kena stop"},
+		},
+		{
+			name:    "RR dari AI tidak cocok",
+			setup:   func(s *TradeSetup) { s.RR = 3 },
+			verdict: verdictWarn, findings: []string{"RR dari AI 1:3.00, hitungan validator 1:2.14"},
+		},
+		{
+			name:    "harga halusinasi",
+			setup:   func(s *TradeSetup) { s.TP2, s.RR = 130, 8.71 },
+			verdict: verdictFail, findings: []string{"TP2 130.0000 berjarak 30%", "TP2 130.0000 di atas high"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setup := long
+			if tt.setup != nil {
+				tt.setup(&setup)
+			}
+			v := validateSetup(setup, candles, summary, tt.levels, defaultValidatorConfig)
+			if v.Verdict != tt.verdict || len(v.Findings) != len(tt.findings) {
+				t.Fatalf("verdict %s dengan %+v, mau %s dengan %d temuan", v.Verdict, v.Findings, tt.verdict, len(tt.findings))
+			}
+			for i, want := range tt.findings {
+				if !strings.Contains(v.Findings[i].Message, want) {
+					t.Errorf("temuan %d %q, mau berisi %q", i, v.Findings[i].Message, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSetupRecomputesRR(t *testing.T) {
+	candles := bars([4]float64{100, 110, 90, 100})
+	setup := TradeSetup{Direction: "long", EntryLow: 99, EntryHigh: 100, SL: 96, TP1: 104, TP2: 107, TP3: 109, RR: 9}
+	v := validateSetup(setup, candles, IndicatorSummary{Price: 100, ATRValue: 2}, nil, defaultValidatorConfig)
+
+	// risk 3.5 dari entry tengah 99.5; dari harga sekarang risk 4 ke TP2
+	want := []float64{4.5 / 3.5, 7.5 / 3.5, 9.5 / 3.5}
+	if math.Abs(v.RR-7.5/3.5) > 1e-9 || math.Abs(v.RRFromPrice-7.0/4) > 1e-9 || math.Abs(v.StopATR-1.75) > 1e-9 {
+		t.Errorf("RR %.4f dari harga %.4f stop %.2f ATR", v.RR, v.RRFromPrice, v.StopATR)
+	}
+	if len(v.RRPerTP) != len(want) {
+		t.Fatalf("RR per TP %v", v.RRPerTP)
+	}
+	for i := range want {
+		if math.Abs(v.RRPerTP[i]-want[i]) > 1e-9 {
+			t.Errorf("RR TP%d %.4f, mau %.4f", i+1, v.RRPerTP[i], want[i])
+		}
+	}
+
+	if v := validateSetup(TradeSetup{Direction: "none"}, candles, IndicatorSummary{Price: 100}, nil, defaultValidatorConfig); v != nil {
+		t.Errorf("tanpa arah tetap divalidasi: %+v", v)
+	}
+}
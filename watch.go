@@ -0,0 +1,422 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ================================
+// LIVE WATCH MODE
+// ================================
+
+const (
+	defaultBinanceWS        = "wss://stream.binance.com:9443"
+	defaultBinanceFuturesWS = "wss://fstream.binance.com"
+)
+
+type watchOptions struct {
+	cliOptions
+	Symbols   []string
+	WSURL     string
+	Cooldown  time.Duration
+	AIOnStart bool
+}
+
+// wsKlineEvent is one message of a Binance combined kline stream.
+type wsKlineEvent struct {
+	Stream string `json:"stream"`
+	Data   struct {
+		Event  string  `json:"e"`
+		Symbol string  `json:"s"`
+		Kline  wsKline `json:"k"`
+	} `json:"data"`
+}
+
+type wsKline struct {
+	Start    int64  `json:"t"`
+	End      int64  `json:"T"`
+	Symbol   string `json:"s"`
+	Interval string `json:"i"`
+	Open     string `json:"o"`
+	Close    string `json:"c"`
+	High     string `json:"h"`
+	Low      string `json:"l"`
+	Volume   string `json:"v"`
+	Closed   bool   `json:"x"`
+}
+
+func (k wsKline) candle() (Candle, error) {
+	c, err := parseOHLCV([]string{fmt.Sprint(k.Start), k.Open, k.High, k.Low, k.Close, k.Volume})
+	if err != nil {
+		return Candle{}, err
+	}
+	c.CloseTime = time.UnixMilli(k.End)
+	return c, nil
+}
+
+// watchState is what watch compares from one closed candle to the next.
+type watchState struct {
+	Summary  IndicatorSummary
+	Levels   []SupportResistance
+	Patterns []Pattern
+}
+
+type symbolWatch struct {
+	symbol  string
+	candles []Candle
+	state   *watchState
+	lastAI  time.Time
+	aiBusy  bool
+}
+
+func parseWatchFlags(args []string) (watchOptions, error) {
+	var opts watchOptions
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	symbols := fs.String("symbols", "", "daftar coin dipisah koma, contoh: SOL,BTC,ETH")
+	finish := dataFlags(fs, &opts.cliOptions)
+	fs.StringVar(&opts.AI, "ai", "", "panggil AI ("+strings.Join(llmNames(), " / ")+") saat ada perubahan penting; kosong = tanpa AI")
+	aiFlags(fs, &opts.cliOptions)
+	fs.StringVar(&opts.WSURL, "ws-url", "", "base URL WebSocket kline format Binance (default dari --provider; ws://127.0.0.1:9443 untuk ws-stub)")
+	fs.DurationVar(&opts.Cooldown, "ai-cooldown", time.Hour, "jeda minimal antar panggilan AI per symbol")
+	fs.BoolVar(&opts.AIOnStart, "ai-on-start", false, "langsung panggil AI sekali setelah data awal siap")
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if err := finish(); err != nil {
+		return opts, err
+	}
+
+	for _, s := range strings.Split(*symbols, ",") {
+		if strings.TrimSpace(s) != "" {
+			opts.Symbols = append(opts.Symbols, normalizeSymbol(s))
+		}
+	}
+	if len(opts.Symbols) == 0 {
+		return opts, errors.New("--symbols wajib diisi")
+	}
+	wsURL, err := klineWSURL(opts.Provider, opts.WSURL)
+	if err != nil {
+		return opts, err
+	}
+	opts.WSURL = wsURL
+	if opts.AI != "" {
+		// Beberapa symbol bisa analisa bersamaan, stream mereka akan campur aduk
+		opts.LLM.Stream = false
+		if err := validateAI(&opts.cliOptions); err != nil {
+			return opts, err
+		}
+		if err := checkAIKeys(opts.cliOptions); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+func runWatch(args []string) error {
+	opts, err := parseWatchFlags(args)
+	if err != nil {
+		return err
+	}
+	provider, err := openProvider(opts.cliOptions)
+	if err != nil {
+		return err
+	}
+	iv := mustInterval(opts.TF)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	watches := make(map[string]*symbolWatch)
+	for _, symbol := range opts.Symbols {
+		candles, err := seedCandles(provider, symbol, opts)
+		if err != nil {
+			return err
+		}
+		sw := &symbolWatch{symbol: symbol, candles: candles}
+		sw.state = evaluateWatch(sw, opts.cliOptions)
+		watches[symbol] = sw
+		fmt.Printf("👀 %s %s: %d candle awal, close %s\n", symbol, opts.TF, len(candles), sw.state.Summary.CurrentPrice)
+	}
+
+	var aiWG sync.WaitGroup
+	defer aiWG.Wait()
+	var mu sync.Mutex
+
+	if opts.AI != "" && opts.AIOnStart {
+		for _, sw := range watches {
+			triggerWatchAI(ctx, &aiWG, &mu, sw, opts, []string{"analisa awal"})
+		}
+	}
+
+	onClosed := func(c Candle, symbol string) {
+		mu.Lock()
+		defer mu.Unlock()
+		sw, ok := watches[symbol]
+		if !ok {
+			return
+		}
+		sw.candles = trimCandles(mergeCandles(sw.candles, []Candle{c}), true, opts.Candles)
+
+		prev := sw.state
+		sw.state = evaluateWatch(sw, opts.cliOptions)
+		changes := materialChanges(prev, sw.state)
+		printWatchUpdate(sw, c, iv, changes)
+
+		if opts.AI != "" && len(changes) > 0 {
+			triggerWatchAI(ctx, &aiWG, &mu, sw, opts, changes)
+		}
+	}
+
+	err = followKlines(ctx, opts.WSURL, opts.Symbols, opts.TF, onClosed, func() {
+		// Isi candle yang terlewat selama putus
+		mu.Lock()
+		defer mu.Unlock()
+		for _, sw := range watches {
+			if fresh, err := fetchLatest(provider, sw.symbol, opts.TF, 50); err == nil {
+				sw.candles = trimCandles(mergeCandles(sw.candles, closedOnly(fresh, time.Now())), true, opts.Candles)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("\n👋 Watch dihentikan.")
+	return nil
+}
+
+// followKlines streams the closed klines of symbols to onClosed until ctx
+// ends, reconnecting with backoff. refill runs after every reconnect to
+// fetch what the stream missed while it was down.
+func followKlines(ctx context.Context, wsURL string, symbols []string, tf string, onClosed func(Candle, string), refill func()) error {
+	streamURL, err := klineStreamURL(wsURL, symbols, tf)
+	if err != nil {
+		return err
+	}
+
+	backoff := time.Second
+	for ctx.Err() == nil {
+		connected := time.Now()
+		err := streamKlines(ctx, streamURL, onClosed)
+		if ctx.Err() != nil {
+			break
+		}
+		if time.Since(connected) > time.Minute {
+			backoff = time.Second
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  WebSocket putus (%v), reconnect dalam %s...\n", err, backoff)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, time.Minute)
+		refill()
+	}
+	return nil
+}
+
+// seedCandles loads the starting window. With --to the window ends there,
+// which lets ws-stub replay the candles that follow.
+func seedCandles(provider CandleProvider, symbol string, opts watchOptions) ([]Candle, error) {
+	candles, err := loadWindow(provider, symbol, opts.TF, opts.Candles, opts.To)
+	if err != nil {
+		return nil, fmt.Errorf("seed %s: %w", symbol, err)
+	}
+	// Candle terakhir dari REST biasanya belum close, stream yang akan melengkapi
+	candles = closedOnly(candles, time.Now())
+	if len(candles) < 30 {
+		return nil, fmt.Errorf("seed %s: cuma dapat %d candle", symbol, len(candles))
+	}
+	return candles, nil
+}
+
+func closedOnly(candles []Candle, now time.Time) []Candle {
+	for len(candles) > 0 && !candles[len(candles)-1].CloseTime.IsZero() && candles[len(candles)-1].CloseTime.After(now) {
+		candles = candles[:len(candles)-1]
+	}
+	return candles
+}
+
+// klineWSURL is the stream that matches --provider unless --ws-url is set.
+// Only the Binance stream format is spoken, so other venues are refused
+// instead of silently streaming Binance spot next to their REST candles.
+func klineWSURL(provider, wsURL string) (string, error) {
+	if wsURL != "" {
+		return wsURL, nil
+	}
+	switch strings.ToLower(provider) {
+	case "", "binance":
+		return defaultBinanceWS, nil
+	case "binance-futures":
+		return defaultBinanceFuturesWS, nil
+	case "file":
+		return "", errors.New("provider file tidak punya stream, jalankan ai-trade ws-stub lalu isi --ws-url")
+	default:
+		return "", fmt.Errorf("streaming kline baru ada untuk binance dan binance-futures, bukan %s", provider)
+	}
+}
+
+func klineStreamURL(base string, symbols []string, tf string) (string, error) {
+	u, err := url.Parse(strings.TrimRight(base, "/") + "/stream")
+	if err != nil {
+		return "", fmt.Errorf("--ws-url tidak valid: %w", err)
+	}
+	var streams []string
+	for _, s := range symbols {
+		streams = append(streams, strings.ToLower(s)+"@kline_"+tf)
+	}
+	q := u.Query()
+	q.Set("streams", strings.Join(streams, "/"))
+	u.RawQuery = q.Encode()
+	// Binance minta "/" apa adanya di query streams
+	u.RawQuery = strings.ReplaceAll(u.RawQuery, "%2F", "/")
+	return u.String(), nil
+}
+
+// streamKlines reads the combined stream until it fails or ctx ends and
+// hands every closed kline to onClosed.
+func streamKlines(ctx context.Context, streamURL string, onClosed func(Candle, string)) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, streamURL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var ev wsKlineEvent
+		if err := json.Unmarshal(data, &ev); err != nil || ev.Data.Event != "kline" {
+			continue
+		}
+		if !ev.Data.Kline.Closed {
+			continue
+		}
+		c, err := ev.Data.Kline.candle()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Kline %s rusak: %v\n", ev.Stream, err)
+			continue
+		}
+		onClosed(c, strings.ToUpper(ev.Data.Symbol))
+	}
+}
+
+func evaluateWatch(sw *symbolWatch, opts cliOptions) *watchState {
+	series := buildSeries(sw.candles, mustInterval(opts.TF))
+	detectors := opts.params.config(sw.symbol, opts.TF)
+	patterns := detectPatterns(sw.candles, detectors.Patterns)
+	opts.calibration.apply(patterns, opts.TF, detectors.Patterns)
+	return &watchState{
+		Summary:  summarizeIndicators(series, sw.symbol, opts.TF),
+		Levels:   detectSupportResistance(sw.candles, detectors.SR),
+		Patterns: patterns,
+	}
+}
+
+// materialChanges lists what changed enough between two closed candles to
+// justify a new LLM analysis.
+func materialChanges(prev, cur *watchState) []string {
+	if prev == nil {
+		return nil
+	}
+	var changes []string
+	p, c := prev.Summary, cur.Summary
+
+	if p.TrendStrength != c.TrendStrength {
+		changes = append(changes, fmt.Sprintf("Trend %s → %s", p.TrendStrength, c.TrendStrength))
+	}
+	if p.EMAAlignment != c.EMAAlignment {
+		changes = append(changes, fmt.Sprintf("EMA %s → %s", p.EMAAlignment, c.EMAAlignment))
+	}
+	if strings.Contains(c.MACDTrend, "Crossover") {
+		changes = append(changes, "MACD "+c.MACDTrend)
+	}
+	if p.BBSqueeze != c.BBSqueeze {
+		changes = append(changes, fmt.Sprintf("BB %s → %s", p.BBSqueeze, c.BBSqueeze))
+	}
+	if (c.RSITrend == "Overbought" || c.RSITrend == "Oversold") && p.RSITrend != c.RSITrend {
+		changes = append(changes, "RSI "+c.RSITrend)
+	}
+
+	known := make(map[string]bool)
+	for _, pt := range prev.Patterns {
+		known[pt.Name] = true
+	}
+	for _, pt := range cur.Patterns {
+		if !known[pt.Name] {
+			changes = append(changes, fmt.Sprintf("Pattern baru: %s (%.0f%%)", pt.Name, pt.Confidence*100))
+		}
+	}
+
+	// Harga menembus level S/R
+	for _, level := range prev.Levels {
+		if (p.Price-level.Price)*(c.Price-level.Price) < 0 {
+			dir := "tembus ke atas"
+			if c.Price < level.Price {
+				dir = "tembus ke bawah"
+			}
+			changes = append(changes, fmt.Sprintf("%s %.4f %s", level.Type, level.Price, dir))
+		}
+	}
+	return changes
+}
+
+func printWatchUpdate(sw *symbolWatch, c Candle, iv Interval, changes []string) {
+	s := sw.state.Summary
+	fmt.Printf("[%s] %s %s close %s | RSI %s | MACD %s | %s\n",
+		c.Time.Format(iv.AxisLayout()), sw.symbol, iv, s.CurrentPrice, s.RSI, s.MACDTrend, s.TrendStrength)
+	for _, ch := range changes {
+		fmt.Printf("   ⚡ %s\n", ch)
+	}
+}
+
+// triggerWatchAI runs the LLM in the background so the stream keeps being
+// read. mu guards sw and must be held by the caller.
+func triggerWatchAI(ctx context.Context, wg *sync.WaitGroup, mu *sync.Mutex, sw *symbolWatch, opts watchOptions, reasons []string) {
+	if sw.aiBusy || time.Since(sw.lastAI) < opts.Cooldown {
+		return
+	}
+	sw.aiBusy, sw.lastAI = true, time.Now()
+
+	candles := append([]Candle(nil), sw.candles...)
+	levels, patterns := sw.state.Levels, sw.state.Patterns
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		series := buildSeries(candles, mustInterval(opts.TF))
+		analysis := analyzeAI(ctx, opts.cliOptions, candles, series, sw.symbol, opts.TF, levels, patterns, nil)
+
+		mu.Lock()
+		defer mu.Unlock()
+		sw.aiBusy = false
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Printf("\n🤖 %s: analisa ulang karena %s\n", sw.symbol, strings.Join(reasons, "; "))
+		printBeautifulAnalysis(analysis, sw.symbol, opts.TF, "", levels, patterns, nil)
+	}()
+}
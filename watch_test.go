@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFollowKlinesFromStub(t *testing.T) {
+	source := &fileProvider{path: "testdata/SOLUSDT_1h.csv"}
+	all, err := source.Candles("SOLUSDT", "1h", time.Time{}, time.Time{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tail := all[len(all)-4:]
+
+	// File yang sama untuk dua symbol, cukup untuk cek routing per stream
+	stub := &wsStub{source: source, from: tail[0].Time, every: time.Millisecond}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stream", stub.serve)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	got := map[string][]Candle{}
+	onClosed := func(c Candle, symbol string) {
+		mu.Lock()
+		defer mu.Unlock()
+		got[symbol] = append(got[symbol], c)
+		if len(got["SOLUSDT"])+len(got["BTCUSDT"]) == 2*len(tail) {
+			cancel()
+		}
+	}
+	refills := 0
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+	if err := followKlines(ctx, wsURL, []string{"SOLUSDT", "btcusdt"}, "1h", onClosed, func() { refills++ }); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		t.Fatalf("timeout, baru dapat %d+%d candle", len(got["SOLUSDT"]), len(got["BTCUSDT"]))
+	}
+	if refills != 0 {
+		t.Errorf("refill %d kali tanpa putus koneksi", refills)
+	}
+
+	for _, symbol := range []string{"SOLUSDT", "BTCUSDT"} {
+		candles := got[symbol]
+		if len(candles) != len(tail) {
+			t.Fatalf("%s: %d candle close, mau %d (update tengah candle tidak boleh ikut)", symbol, len(candles), len(tail))
+		}
+		for i, c := range candles {
+			w := tail[i]
+			if !c.Time.Equal(w.Time) || !c.CloseTime.Equal(w.CloseTime) || !c.Close.Equal(w.Close) || !c.Volume.Equal(w.Volume) {
+				t.Errorf("%s candle %d:\n got %+v\nwant %+v", symbol, i, c, w)
+			}
+		}
+	}
+}
+
+func TestKlineWSURLFollowsProvider(t *testing.T) {
+	tests := []struct {
+		provider, wsURL string
+		want            string
+		ok              bool
+	}{
+		{"", "", defaultBinanceWS, true},
+		{"binance", "", defaultBinanceWS, true},
+		{"binance-futures", "", defaultBinanceFuturesWS, true},
+		{"bybit", "", "", false},
+		{"okx-swap", "", "", false},
+		{"file", "", "", false},
+		{"file", "ws://127.0.0.1:9443", "ws://127.0.0.1:9443", true},
+	}
+	for _, tt := range tests {
+		got, err := klineWSURL(tt.provider, tt.wsURL)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("klineWSURL(%q, %q) = %q, %v; mau %q, ok %v", tt.provider, tt.wsURL, got, err, tt.want, tt.ok)
+		}
+	}
+	noFiles := []string{"--symbols", "SOL", "--calibration", "", "--params", ""}
+	if _, err := parseWatchFlags(append(noFiles, "--provider", "bybit")); err == nil || !strings.Contains(err.Error(), "bybit") {
+		t.Errorf("watch dengan provider bybit harus ditolak, err %v", err)
+	}
+	if _, err := parsePaperFlags(append(noFiles, "--provider", "okx", "--state", filepath.Join(t.TempDir(), "p.json"))); err == nil || !strings.Contains(err.Error(), "okx") {
+		t.Errorf("paper dengan provider okx harus ditolak, err %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+// ================================
+// LOCAL WEBSOCKET STAND-IN
+// ================================
+
+// runWSStub serves a Binance compatible /stream endpoint that replays
+// candles from local files, so watch mode can run without network:
+//
+//	ai-trade ws-stub --file data/{symbol}_{interval}.csv --from 2024-03-01
+//	ai-trade watch --symbols SOL --tf 1h --provider file --file data/{symbol}_{interval}.csv \
+//	    --to 2024-03-01 --ws-url ws://127.0.0.1:9443
+func runWSStub(args []string) error {
+	fs := flag.NewFlagSet("ws-stub", flag.ContinueOnError)
+	listen := fs.String("listen", "127.0.0.1:9443", "alamat listen")
+	file := fs.String("file", "", "file CSV/Parquet candle, boleh pakai {symbol} dan {interval}")
+	from := fs.String("from", "", "replay candle mulai tanggal ini (default: 50 candle terakhir)")
+	every := fs.Duration("every", time.Second, "jeda antar candle yang di-replay")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("ws-stub butuh --file")
+	}
+	start, err := parseDateFlag(*from)
+	if err != nil {
+		return err
+	}
+
+	stub := &wsStub{source: &fileProvider{path: *file}, from: start, every: *every}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stream", stub.serve)
+
+	fmt.Printf("🧪 ws-stub listen di ws://%s/stream (replay %s)\n", *listen, *file)
+	return http.ListenAndServe(*listen, mux)
+}
+
+type wsStub struct {
+	source CandleProvider
+	from   time.Time
+	every  time.Duration
+}
+
+type stubFrame struct {
+	symbol, tf string
+	candle     Candle
+}
+
+var stubUpgrader = websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+func (s *wsStub) serve(w http.ResponseWriter, r *http.Request) {
+	frames, err := s.frames(r.URL.Query().Get("streams"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	conn, err := stubUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Baca frame dari client supaya close/ping terdeteksi
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, f := range frames {
+		// Kirim update tengah candle dulu, lalu candle yang sudah close
+		for _, done := range []bool{false, true} {
+			if err := conn.WriteJSON(stubKline(f.symbol, f.tf, f.candle, done)); err != nil {
+				return
+			}
+		}
+		select {
+		case <-closed:
+			return
+		case <-time.After(s.every):
+		}
+	}
+	<-closed
+}
+
+// frames loads every requested stream ("solusdt@kline_1h/btcusdt@kline_1h")
+// and interleaves them by open time.
+func (s *wsStub) frames(streams string) ([]stubFrame, error) {
+	var frames []stubFrame
+	for _, stream := range strings.Split(streams, "/") {
+		symbol, tf, ok := strings.Cut(stream, "@kline_")
+		if !ok {
+			return nil, fmt.Errorf("stream %q tidak didukung, hanya <symbol>@kline_<interval>", stream)
+		}
+		symbol = strings.ToUpper(symbol)
+
+		limit := 0
+		if s.from.IsZero() {
+			limit = 50
+		}
+		candles, err := s.source.Candles(symbol, tf, time.Time{}, time.Time{}, 0)
+		if err != nil {
+			return nil, err
+		}
+		candles = filterRange(candles, s.from, time.Time{}, 0)
+		if limit > 0 && len(candles) > limit {
+			candles = candles[len(candles)-limit:]
+		}
+		for _, c := range candles {
+			frames = append(frames, stubFrame{symbol: symbol, tf: tf, candle: c})
+		}
+	}
+	sort.SliceStable(frames, func(i, j int) bool {
+		return frames[i].candle.Time.Before(frames[j].candle.Time)
+	})
+	return frames, nil
+}
+
+// stubKline builds the stream message for a candle. The in-progress update
+// carries half the volume and a midpoint close, like a live candle would.
+func stubKline(symbol, tf string, c Candle, closed bool) wsKlineEvent {
+	var ev wsKlineEvent
+	ev.Stream = strings.ToLower(symbol) + "@kline_" + tf
+	ev.Data.Event = "kline"
+	ev.Data.Symbol = symbol
+	ev.Data.Kline = wsKline{
+		Start:    c.Time.UnixMilli(),
+		End:      c.CloseTime.UnixMilli(),
+		Symbol:   symbol,
+		Interval: tf,
+		Open:     c.Open.String(),
+		Close:    c.Close.String(),
+		High:     c.High.String(),
+		Low:      c.Low.String(),
+		Volume:   c.Volume.String(),
+		Closed:   closed,
+	}
+	if !closed {
+		half := decimal.NewFromInt(2)
+		ev.Data.Kline.Volume = c.Volume.Div(half).String()
+		ev.Data.Kline.Close = c.Open.Add(c.Close).Div(half).String()
+	}
+	return ev
+}